@@ -0,0 +1,61 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"go.opencensus.io/metric"
+	"go.opencensus.io/metric/metricdata"
+	"go.opencensus.io/metric/metricproducer"
+
+	"github.com/aws-observability/aws-otel-collector/tools/version"
+)
+
+const buildInfoMetric = "aoc_build_info"
+
+// newBuildInfoRegistry returns the registry of the aoc_build_info gauge, which is always 1 and
+// carries the build of the collector as labels.
+func newBuildInfoRegistry(info version.BuildInfo) (*metric.Registry, error) {
+	registry := metric.NewRegistry()
+	gauge, err := registry.AddInt64Gauge(buildInfoMetric,
+		metric.WithDescription("Build information of the ADOT Collector, the value is always 1"),
+		metric.WithLabelKeys("version", "git_hash", "build_date", "go_version", "os", "arch"))
+	if err != nil {
+		return nil, err
+	}
+	entry, err := gauge.GetEntry(
+		metricdata.NewLabelValue(info.Version),
+		metricdata.NewLabelValue(info.GitHash),
+		metricdata.NewLabelValue(info.Date),
+		metricdata.NewLabelValue(info.GoVersion),
+		metricdata.NewLabelValue(info.OS),
+		metricdata.NewLabelValue(info.Arch))
+	if err != nil {
+		return nil, err
+	}
+	entry.Set(1)
+	return registry, nil
+}
+
+// registerBuildInfoMetric adds the aoc_build_info gauge to the producers read by the internal
+// telemetry of the collector, it is exported with the other otelcol_ metrics.
+func registerBuildInfoMetric(info version.BuildInfo) error {
+	registry, err := newBuildInfoRegistry(info)
+	if err != nil {
+		return err
+	}
+	metricproducer.GlobalManager().AddProducer(registry)
+	return nil
+}
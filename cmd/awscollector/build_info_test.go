@@ -0,0 +1,87 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/metric/metricdata"
+	"go.opentelemetry.io/collector/featuregate"
+
+	"github.com/aws-observability/aws-otel-collector/tools/version"
+)
+
+func TestBuildInfoRegistry(t *testing.T) {
+	registry, err := newBuildInfoRegistry(version.BuildInfo{
+		Version:   "v0.37.0",
+		GitHash:   "abc123",
+		Date:      "2024-02-01T00:00:00Z",
+		GoVersion: "go1.21.6",
+		OS:        "linux",
+		Arch:      "arm64",
+	})
+	require.NoError(t, err)
+
+	metrics := registry.Read()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, buildInfoMetric, metrics[0].Descriptor.Name)
+	assert.Equal(t, metricdata.TypeGaugeInt64, metrics[0].Descriptor.Type)
+	require.Len(t, metrics[0].TimeSeries, 1)
+	series := metrics[0].TimeSeries[0]
+	labels := map[string]string{}
+	for i, key := range metrics[0].Descriptor.LabelKeys {
+		labels[key.Key] = series.LabelValues[i].Value
+	}
+	assert.Equal(t, map[string]string{
+		"version":    "v0.37.0",
+		"git_hash":   "abc123",
+		"build_date": "2024-02-01T00:00:00Z",
+		"go_version": "go1.21.6",
+		"os":         "linux",
+		"arch":       "arm64",
+	}, labels)
+	require.Len(t, series.Points, 1)
+	assert.Equal(t, int64(1), series.Points[0].Value)
+}
+
+func TestVersionSubCommand(t *testing.T) {
+	registry := featuregate.NewRegistry()
+	registry.MustRegister("test.enabled", featuregate.StageBeta)
+	registry.MustRegister("test.disabled", featuregate.StageAlpha)
+
+	var out bytes.Buffer
+	cmd := newVersionSubCommand(registry)
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--json"})
+	require.NoError(t, cmd.Execute())
+
+	var info version.BuildInfo
+	require.NoError(t, json.Unmarshal(out.Bytes(), &info))
+	assert.Equal(t, version.Version, info.Version)
+	assert.Equal(t, []string{"test.enabled"}, info.FeatureGates)
+
+	out.Reset()
+	cmd = newVersionSubCommand(registry)
+	cmd.SetOut(&out)
+	cmd.SetArgs(nil)
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "Version")
+	assert.Contains(t, out.String(), "test.enabled")
+}
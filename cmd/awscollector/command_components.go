@@ -0,0 +1,72 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/collector/otelcol"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/defaultcomponents"
+)
+
+// newComponentsSubCommand constructs the components command listing every factory built into the distribution.
+func newComponentsSubCommand(params otelcol.CollectorSettings) *cobra.Command {
+	var jsonOutput bool
+	componentsCmd := &cobra.Command{
+		Use:          "components",
+		Short:        "Outputs the components built into this collector distribution",
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			factories, err := params.Factories()
+			if err != nil {
+				return fmt.Errorf("failed to initialize factories: %w", err)
+			}
+			infos := defaultcomponents.Describe(factories)
+
+			if jsonOutput {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(infos)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TYPE\tKIND\tSTABILITY\tMODULE\tVERSION")
+			for _, info := range infos {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", info.Type, info.Kind, formatStability(info.Stability), info.Module, info.Version)
+			}
+			return w.Flush()
+		},
+	}
+	componentsCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the components as JSON")
+	return componentsCmd
+}
+
+// formatStability formats the stability levels as a sorted list of signal=level pairs.
+func formatStability(stability map[string]string) string {
+	pairs := make([]string, 0, len(stability))
+	for signal, level := range stability {
+		pairs = append(pairs, signal+"="+level)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
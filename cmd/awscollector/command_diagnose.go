@@ -0,0 +1,130 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/collector/otelcol"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/config"
+	"github.com/aws-observability/aws-otel-collector/pkg/diagnose"
+)
+
+// newDiagnoseSubCommand constructs the diagnose command, it checks the region, the credentials and
+// the endpoints of the AWS components of the resolved configuration.
+func newDiagnoseSubCommand(flagSet *flag.FlagSet) *cobra.Command {
+	var jsonOutput bool
+	diagnoseCmd := &cobra.Command{
+		Use:          "diagnose",
+		Short:        "Checks the AWS connectivity and credentials of the configured AWS components",
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, err := config.NewConfigProvider(flagSet)
+			if err != nil {
+				return err
+			}
+			cp, ok := provider.(otelcol.ConfmapProvider)
+			if !ok {
+				return errors.New("config provider does not expose the resolved configuration")
+			}
+			conf, err := cp.GetConfmap(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			report := diagnose.Run(cmd.Context(), conf.ToStringMap(), diagnose.NewSettings())
+			if jsonOutput {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				if err = encoder.Encode(report); err != nil {
+					return err
+				}
+			} else {
+				writeReport(cmd.OutOrStdout(), report)
+			}
+			if n := report.Problems(); n > 0 {
+				return fmt.Errorf("%d problem(s) found", n)
+			}
+			return nil
+		},
+	}
+	diagnoseCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the report as JSON")
+	diagnoseCmd.Flags().AddGoFlagSet(flagSet)
+	return diagnoseCmd
+}
+
+// writeReport writes the report as text, one block per component.
+func writeReport(w io.Writer, report diagnose.Report) {
+	imds := report.IMDS
+	switch {
+	case imds.Endpoint == "":
+		fmt.Fprintln(w, "IMDS: disabled")
+	case !imds.Available:
+		fmt.Fprintf(w, "IMDS: %s not available\n", imds.Endpoint)
+	default:
+		version := "IMDSv1"
+		if imds.IMDSv2 {
+			version = "IMDSv2"
+		}
+		fmt.Fprintf(w, "IMDS: %s available with %s, region %s\n", imds.Endpoint, version, imds.Region)
+	}
+	writeProblems(w, imds.Problems)
+
+	if len(report.Components) == 0 {
+		fmt.Fprintln(w, "no AWS component configured")
+	}
+	for _, c := range report.Components {
+		fmt.Fprintf(w, "\n%s\n", c.ID)
+		if c.Region != "" {
+			fmt.Fprintf(w, "  region:      %s (%s)\n", c.Region, c.RegionSource)
+		}
+		if c.Credentials != "" {
+			identity := c.Identity
+			if identity == "" {
+				identity = "not verified"
+			}
+			fmt.Fprintf(w, "  credentials: %s (%s)\n", c.Credentials, identity)
+		}
+		if c.Proxy != "" {
+			fmt.Fprintf(w, "  proxy:       %s\n", c.Proxy)
+		}
+		if c.Endpoint != "" {
+			status := "not reachable"
+			if c.Reachable {
+				status = "reachable"
+			}
+			if c.ClockSkew != "" {
+				status += ", clock skew " + c.ClockSkew
+			}
+			fmt.Fprintf(w, "  endpoint:    %s (%s)\n", c.Endpoint, status)
+		}
+		writeProblems(w, c.Problems)
+	}
+}
+
+func writeProblems(w io.Writer, problems []string) {
+	for _, problem := range problems {
+		fmt.Fprintf(w, "  ERROR: %s\n", strings.TrimSpace(problem))
+	}
+}
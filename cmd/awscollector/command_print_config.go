@@ -0,0 +1,62 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/collector/otelcol"
+	"gopkg.in/yaml.v3"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/config"
+)
+
+// newPrintConfigSubCommand constructs the print-config command, it outputs the configuration
+// after all the providers and converters ran, with the values of sensitive keys masked.
+func newPrintConfigSubCommand(flagSet *flag.FlagSet) *cobra.Command {
+	printConfigCmd := &cobra.Command{
+		Use:          "print-config",
+		Short:        "Outputs the resolved configuration with secrets redacted",
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, err := config.NewConfigProvider(flagSet)
+			if err != nil {
+				return err
+			}
+			cp, ok := provider.(otelcol.ConfmapProvider)
+			if !ok {
+				return errors.New("config provider does not expose the resolved configuration")
+			}
+			conf, err := cp.GetConfmap(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			out, err := yaml.Marshal(config.Redact(conf.ToStringMap()))
+			if err != nil {
+				return fmt.Errorf("failed to marshal the configuration: %w", err)
+			}
+			_, err = cmd.OutOrStdout().Write(out)
+			return err
+		},
+	}
+	printConfigCmd.Flags().AddGoFlagSet(flagSet)
+	return printConfigCmd
+}
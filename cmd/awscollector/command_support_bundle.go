@@ -0,0 +1,117 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/collector/featuregate"
+	"go.opentelemetry.io/collector/otelcol"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/config"
+	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
+	"github.com/aws-observability/aws-otel-collector/pkg/logger"
+	"github.com/aws-observability/aws-otel-collector/pkg/supportbundle"
+	"github.com/aws-observability/aws-otel-collector/tools/version"
+)
+
+// newSupportBundleSubCommand constructs the support-bundle command, it writes the logs, the
+// configuration and the state of the collector to a tar.gz archive to attach to support requests.
+func newSupportBundleSubCommand(flagSet *flag.FlagSet) *cobra.Command {
+	var (
+		output    string
+		logSizeMB int
+	)
+	supportBundleCmd := &cobra.Command{
+		Use:          "support-bundle",
+		Short:        "Collects the logs, configuration and profiles of the collector into a tar.gz archive",
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if logSizeMB <= 0 {
+				return fmt.Errorf("invalid --log-size %d, must be greater than 0", logSizeMB)
+			}
+			settings := supportbundle.Settings{
+				LogFile:         logger.GetFileSettings().Path,
+				MaxLogSize:      int64(logSizeMB) << 20,
+				ExtraConfigFile: extraconfig.FilePath(),
+				Config:          resolvedConfig(flagSet),
+				BuildInfo:       version.NewBuildInfo(featuregate.GlobalRegistry()),
+				Platform:        extraconfig.Detect(),
+				Environ:         os.Environ(),
+				AdminEndpoint:   config.GetAdminEndpoint(flagSet),
+			}
+			if limits, err := extraconfig.GetCgroupLimits(); err == nil {
+				settings.Cgroup = &limits
+			}
+			if settings.AdminEndpoint == "" {
+				if extraCfg, _ := extraconfig.GetExtraConfig(); extraCfg != nil {
+					settings.AdminEndpoint = extraCfg.AdminEndpoint
+				}
+			}
+			if output == "" {
+				output = "aws-otel-collector-support-" + time.Now().UTC().Format("20060102T150405Z") + ".tar.gz"
+			}
+
+			f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				return fmt.Errorf("failed to create the support bundle: %w", err)
+			}
+			problems, err := supportbundle.Write(cmd.Context(), f, settings)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = os.Remove(output)
+				return fmt.Errorf("failed to write the support bundle: %w", err)
+			}
+			for _, problem := range problems {
+				fmt.Fprintf(cmd.OutOrStdout(), "WARN: %s\n", problem)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "support bundle written to %s\n", output)
+			return nil
+		},
+	}
+	supportBundleCmd.Flags().StringVarP(&output, "output", "o", "", "Path of the archive, aws-otel-collector-support-<time>.tar.gz by default")
+	supportBundleCmd.Flags().IntVar(&logSizeMB, "log-size", supportbundle.DefaultMaxLogSize>>20, "Megabytes kept from the end of the log file")
+	supportBundleCmd.Flags().AddGoFlagSet(flagSet)
+	return supportBundleCmd
+}
+
+// resolvedConfig returns the function resolving the configuration of the flags.
+func resolvedConfig(flagSet *flag.FlagSet) func(ctx context.Context) (map[string]any, error) {
+	return func(ctx context.Context) (map[string]any, error) {
+		provider, err := config.NewConfigProvider(flagSet)
+		if err != nil {
+			return nil, err
+		}
+		cp, ok := provider.(otelcol.ConfmapProvider)
+		if !ok {
+			return nil, errors.New("config provider does not expose the resolved configuration")
+		}
+		conf, err := cp.GetConfmap(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return conf.ToStringMap(), nil
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/collector/otelcol"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/config"
+)
+
+// newValidateSubCommand constructs the validate command, it resolves the configuration the same way
+// the collector does and reports every problem found without starting any pipeline.
+func newValidateSubCommand(params otelcol.CollectorSettings, flagSet *flag.FlagSet) *cobra.Command {
+	validateCmd := &cobra.Command{
+		Use:          "validate",
+		Short:        "Validates the config without running the collector",
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the flags are parsed by cobra for sub commands, so the provider
+			// has to be created here instead of using params.ConfigProvider
+			provider, err := config.NewConfigProvider(flagSet)
+			if err != nil {
+				return err
+			}
+			factories, err := params.Factories()
+			if err != nil {
+				return fmt.Errorf("failed to initialize factories: %w", err)
+			}
+
+			result := config.ValidateConfig(cmd.Context(), provider, factories)
+			for _, unused := range result.Unused {
+				fmt.Fprintf(cmd.OutOrStdout(), "WARN: %s is configured but not used by the service\n", unused)
+			}
+			for _, err := range result.Errors {
+				fmt.Fprintf(cmd.OutOrStdout(), "ERROR: %v\n", err)
+			}
+			if !result.Valid() {
+				return fmt.Errorf("configuration is invalid: %d error(s) found", len(result.Errors))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "configuration is valid")
+			return nil
+		},
+	}
+	validateCmd.Flags().AddGoFlagSet(flagSet)
+	return validateCmd
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/collector/featuregate"
+
+	"github.com/aws-observability/aws-otel-collector/tools/version"
+)
+
+// newVersionSubCommand constructs the version command printing the build of the collector.
+func newVersionSubCommand(registry *featuregate.Registry) *cobra.Command {
+	var jsonOutput bool
+	versionCmd := &cobra.Command{
+		Use:          "version",
+		Short:        "Outputs the version, build and upstream module versions of this collector",
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := version.NewBuildInfo(registry)
+			if jsonOutput {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(info)
+			}
+			_, err := fmt.Fprintf(cmd.OutOrStdout(), "%s\nModules:\n%s", info.Info(), info.ModulesInfo())
+			return err
+		},
+	}
+	versionCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the version as JSON")
+	return versionCmd
+}
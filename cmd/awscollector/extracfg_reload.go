@@ -0,0 +1,115 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/otelcol"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/config"
+	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
+)
+
+// extraCfgReloader is set when the extracfg file enables reloadOnSIGHUP.
+var extraCfgReloader *extraCfgReloadState
+
+// extraCfgReloadState holds the extracfg file last applied.
+type extraCfgReloadState struct {
+	mu      sync.Mutex
+	current *extraconfig.ExtraConfig
+}
+
+// newConfigProvider creates the config provider of the collector, it reloads the extracfg file
+// along with the configuration when enabled.
+func newConfigProvider(flagSet *flag.FlagSet) (otelcol.ConfigProvider, error) {
+	provider, err := config.NewConfigProvider(flagSet)
+	if err != nil {
+		return nil, err
+	}
+	if extraCfgReloader == nil {
+		return provider, nil
+	}
+	return &extraCfgReloadingProvider{ConfigProvider: provider, state: extraCfgReloader}, nil
+}
+
+// extraCfgReloadingProvider reloads the extracfg file every time the collector reloads its
+// configuration, e.g. on SIGHUP, before the configuration is resolved. The new environment
+// variables and feature gates are then used by the reloaded configuration.
+type extraCfgReloadingProvider struct {
+	otelcol.ConfigProvider
+	state *extraCfgReloadState
+
+	once sync.Once
+}
+
+var _ otelcol.ConfmapProvider = (*extraCfgReloadingProvider)(nil)
+
+func (p *extraCfgReloadingProvider) GetConfmap(ctx context.Context) (*confmap.Conf, error) {
+	reload := true
+	// the file was just read by main on the first resolution
+	p.once.Do(func() { reload = false })
+	if reload {
+		p.state.reload()
+	}
+	return p.ConfigProvider.(otelcol.ConfmapProvider).GetConfmap(ctx)
+}
+
+func (s *extraCfgReloadState) reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.current.ReloadOnSIGHUP {
+		return
+	}
+	extraConfig, err := extraconfig.GetExtraConfig()
+	if extraConfig == nil {
+		log.Printf("E! failed to reload extra config, keeping the current settings: %v\n", err)
+		return
+	}
+	logExtraCfgProblems(extraConfig, err)
+	log.Printf("I! reloaded extra config\n")
+
+	// variables removed from the file are removed from the environment as well
+	newEnv := extraCfgEnv(extraConfig)
+	for key := range extraCfgEnv(s.current) {
+		if _, ok := newEnv[key]; !ok {
+			if err := os.Unsetenv(key); err != nil {
+				log.Printf("failed to unset env var %s:%v\n", key, err)
+			}
+		}
+	}
+	for key, val := range newEnv {
+		if err := os.Setenv(key, val); err != nil {
+			log.Printf("failed to set env var %s:%v\n", key, err)
+		}
+	}
+	setFeatureGatesFromExtraCfg(extraConfig)
+	setComponentLogLevelsFromExtraCfg(extraConfig)
+	setOutboundFromExtraCfg(extraConfig)
+	setCredentialSettingsFromExtraCfg(extraConfig)
+
+	if extraConfig.LoggingLevel != s.current.LoggingLevel || !reflect.DeepEqual(extraConfig.LogFile, s.current.LogFile) {
+		log.Printf("W! the logging level or log file settings of the extra config changed, they are applied on restart\n")
+	}
+	s.current = extraConfig
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+	"github.com/aws-observability/aws-otel-collector/pkg/config"
+	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
+)
+
+func TestExtraCfgReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "extracfg.txt")
+	unixPath, windowsPath := extraconfig.UnixExtraConfigPath, extraconfig.WindowsExtraConfigPath
+	extraconfig.UnixExtraConfigPath, extraconfig.WindowsExtraConfigPath = path, path
+	t.Cleanup(func() {
+		extraconfig.UnixExtraConfigPath, extraconfig.WindowsExtraConfigPath = unixPath, windowsPath
+	})
+	t.Setenv(awsRegionKey, "")
+	t.Setenv("REMOVED_VAR", "")
+	t.Cleanup(func() { require.NoError(t, config.SetDefaultCredentialSettings(awssession.CredentialSettings{})) })
+
+	require.NoError(t, os.WriteFile(path, []byte("reloadOnSIGHUP=true\nawsRegion=us-west-2\nREMOVED_VAR=1\nroleArn=arn:aws:iam::123456789012:role/adot\nroleSessionName=adot"), 0600))
+	extraCfg, err := extraconfig.GetExtraConfig()
+	require.NoError(t, err)
+	setCollectorConfigFromExtraCfg(extraCfg)
+	state := &extraCfgReloadState{current: extraCfg}
+	assert.Equal(t, "us-west-2", os.Getenv(awsRegionKey))
+	assert.Equal(t, "1", os.Getenv("REMOVED_VAR"))
+	assert.Equal(t, awssession.CredentialSettings{RoleARN: "arn:aws:iam::123456789012:role/adot", RoleSessionName: "adot"},
+		awssession.GetCredentialSettings())
+
+	require.NoError(t, os.WriteFile(path, []byte("reloadOnSIGHUP=true\nawsRegion=eu-west-1"), 0600))
+	state.reload()
+	assert.Equal(t, "eu-west-1", os.Getenv(awsRegionKey))
+	_, ok := os.LookupEnv("REMOVED_VAR")
+	assert.False(t, ok)
+	assert.Equal(t, awssession.CredentialSettings{}, awssession.GetCredentialSettings())
+
+	// once disabled, the file is no longer reloaded
+	require.NoError(t, os.WriteFile(path, []byte("awsRegion=us-east-1"), 0600))
+	state.reload()
+	assert.Equal(t, "us-east-1", os.Getenv(awsRegionKey))
+	require.NoError(t, os.WriteFile(path, []byte("awsRegion=ap-south-1"), 0600))
+	state.reload()
+	assert.Equal(t, "us-east-1", os.Getenv(awsRegionKey))
+}
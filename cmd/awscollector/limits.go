@@ -0,0 +1,93 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
+)
+
+const (
+	goMemLimitKey = "GOMEMLIMIT"
+	goMaxProcsKey = "GOMAXPROCS"
+	// memoryLimitRatioKey sets the share of the cgroup memory limit used as GOMEMLIMIT, the rest
+	// is left as headroom for the memory the Go runtime does not account for.
+	memoryLimitRatioKey     = "AOT_MEMORY_LIMIT_RATIO"
+	defaultMemoryLimitRatio = 0.9
+)
+
+// runtimeLimits are the GOMEMLIMIT and GOMAXPROCS derived from the cgroup limits, zero when they
+// are not set.
+type runtimeLimits struct {
+	memory   int64
+	maxProcs int
+}
+
+// setRuntimeLimitsFromCgroup sets GOMEMLIMIT and GOMAXPROCS from the memory limit and the CPU quota
+// of the cgroup, unless they are set in the environment. Without them, the Go runtime neither
+// knows the memory it may use nor the CPUs it is allowed to run on, which gets small containers
+// OOM killed and throttled.
+func setRuntimeLimitsFromCgroup() {
+	cgroup, err := extraconfig.GetCgroupLimits()
+	if err != nil {
+		log.Printf("W! failed to read the cgroup limits, GOMEMLIMIT and GOMAXPROCS are not set: %v\n", err)
+		return
+	}
+	limits := deriveRuntimeLimits(cgroup, memoryLimitRatio(), runtime.NumCPU(), os.LookupEnv)
+	if limits.memory > 0 {
+		debug.SetMemoryLimit(limits.memory)
+		log.Printf("I! set GOMEMLIMIT to %d bytes from the cgroup memory limit of %d bytes\n", limits.memory, cgroup.Memory)
+	}
+	if limits.maxProcs > 0 {
+		runtime.GOMAXPROCS(limits.maxProcs)
+		log.Printf("I! set GOMAXPROCS to %d from the cgroup CPU quota of %g\n", limits.maxProcs, cgroup.CPU)
+	}
+}
+
+// deriveRuntimeLimits returns the GOMEMLIMIT, the given ratio of the memory limit, and the
+// GOMAXPROCS, the CPU quota rounded down to at least 1. Those set in the environment are left as is.
+func deriveRuntimeLimits(cgroup extraconfig.CgroupLimits, ratio float64, numCPU int, lookupEnv func(string) (string, bool)) runtimeLimits {
+	var limits runtimeLimits
+	if _, ok := lookupEnv(goMemLimitKey); !ok && cgroup.Memory > 0 {
+		limits.memory = int64(float64(cgroup.Memory) * ratio)
+	}
+	if _, ok := lookupEnv(goMaxProcsKey); !ok && cgroup.CPU > 0 {
+		procs := int(math.Max(1, math.Floor(cgroup.CPU)))
+		if procs < numCPU {
+			limits.maxProcs = procs
+		}
+	}
+	return limits
+}
+
+func memoryLimitRatio() float64 {
+	value, ok := os.LookupEnv(memoryLimitRatioKey)
+	if !ok {
+		return defaultMemoryLimitRatio
+	}
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil || ratio <= 0 || ratio > 1 {
+		log.Printf("W! invalid %s %q, it must be greater than 0 and at most 1, using %g\n", memoryLimitRatioKey, value, defaultMemoryLimitRatio)
+		return defaultMemoryLimitRatio
+	}
+	return ratio
+}
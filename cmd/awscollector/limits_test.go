@@ -0,0 +1,80 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
+)
+
+func TestDeriveRuntimeLimits(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cgroup   extraconfig.CgroupLimits
+		env      map[string]string
+		expected runtimeLimits
+	}{
+		{
+			name:     "Unlimited",
+			expected: runtimeLimits{},
+		},
+		{
+			name:     "SmallFargateTask",
+			cgroup:   extraconfig.CgroupLimits{Memory: 512 << 20, CPU: 0.25},
+			expected: runtimeLimits{memory: 483183820, maxProcs: 1},
+		},
+		{
+			name:     "QuotaRoundedDown",
+			cgroup:   extraconfig.CgroupLimits{CPU: 2.5},
+			expected: runtimeLimits{maxProcs: 2},
+		},
+		{
+			name:     "QuotaAboveCPUs",
+			cgroup:   extraconfig.CgroupLimits{CPU: 8},
+			expected: runtimeLimits{},
+		},
+		{
+			name:     "ExplicitEnv",
+			cgroup:   extraconfig.CgroupLimits{Memory: 512 << 20, CPU: 0.25},
+			env:      map[string]string{goMemLimitKey: "400MiB", goMaxProcsKey: "2"},
+			expected: runtimeLimits{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lookupEnv := func(key string) (string, bool) {
+				value, ok := tc.env[key]
+				return value, ok
+			}
+			assert.Equal(t, tc.expected, deriveRuntimeLimits(tc.cgroup, defaultMemoryLimitRatio, 4, lookupEnv))
+		})
+	}
+}
+
+func TestMemoryLimitRatio(t *testing.T) {
+	assert.Equal(t, defaultMemoryLimitRatio, memoryLimitRatio())
+
+	t.Setenv(memoryLimitRatioKey, "0.75")
+	assert.Equal(t, 0.75, memoryLimitRatio())
+
+	for _, invalid := range []string{"0", "1.5", "-0.2", "most"} {
+		t.Setenv(memoryLimitRatioKey, invalid)
+		assert.Equal(t, defaultMemoryLimitRatio, memoryLimitRatio(), invalid)
+	}
+}
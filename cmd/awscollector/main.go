@@ -16,10 +16,12 @@
 package main // import "aws-observability.io/collector/cmd/awscollector"
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/collector/featuregate"
@@ -28,18 +30,25 @@ import (
 	"go.opentelemetry.io/collector/component"
 	"go.uber.org/zap"
 
+	"github.com/aws-observability/aws-otel-collector/pkg/admin"
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
 	"github.com/aws-observability/aws-otel-collector/pkg/config"
 	"github.com/aws-observability/aws-otel-collector/pkg/defaultcomponents"
 	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
 	"github.com/aws-observability/aws-otel-collector/pkg/logger"
+	"github.com/aws-observability/aws-otel-collector/pkg/outbound"
 	"github.com/aws-observability/aws-otel-collector/tools/version"
 )
 
 const (
 	awsProfileKey        = "AWS_PROFILE"
 	awsCredentialFileKey = "AWS_SHARED_CREDENTIALS_FILE" //nolint:gosec // this is a false positive for G101: Potential hardcoded credentials
+	awsRegionKey         = "AWS_REGION"
 )
 
+// environmentOutbound are the outbound settings of the environment, which the extracfg file overrides.
+var environmentOutbound outbound.Settings
+
 // aws-otel-collector is built upon opentelemetry-collector.
 // in main() function, aws team has customized logging and configuration handling
 // logic and it only supports the selected components which have been verified by AWS
@@ -48,16 +57,32 @@ func main() {
 	// get extra config
 
 	extraConfig, err := extraconfig.GetExtraConfig()
-	if err != nil {
-		log.Printf("found no extra config, skip it, err: %v", err)
+	// including the variables of the extracfg file which are not part of its schema
+	environmentOutbound = outbound.FromEnvironment()
+	if extraConfig != nil {
+		// the log file has to be known before the error logger is set up
+		setLogFileFromExtraCfg(extraConfig)
 	}
 
 	logger.SetupErrorLogger()
+	log.Printf("I! ADOT Collector version: %s\n", version.Version)
+	log.Printf("I! detected platform: %s\n", extraconfig.Detect())
 
 	// set the collector config from extracfg file
 	if extraConfig != nil {
+		logExtraCfgProblems(extraConfig, err)
 		setCollectorConfigFromExtraCfg(extraConfig)
+		// the AWS settings of the extracfg file are exported before the client is created
+		setCloudWatchLogsFromExtraCfg(extraConfig)
+		if extraConfig.ReloadOnSIGHUP {
+			extraCfgReloader = &extraCfgReloadState{current: extraConfig}
+		}
+	} else {
+		log.Printf("found no extra config, skip it, err: %v", err)
+		setOutboundFromExtraCfg(nil)
 	}
+	// after the extracfg file, which may set the headroom ratio
+	setRuntimeLimitsFromCgroup()
 
 	info := component.BuildInfo{
 		Command:     "aws-otel-collector",
@@ -69,21 +94,50 @@ func main() {
 	if err != nil {
 		logFatal(err)
 	}
+	// after the flags, which may enable feature gates
+	if err = registerBuildInfoMetric(version.NewBuildInfo(featuregate.GlobalRegistry())); err != nil {
+		log.Printf("W! failed to register the %s metric: %v\n", buildInfoMetric, err)
+	}
 
 	params := otelcol.CollectorSettings{
 		Factories:      defaultcomponents.Components,
 		BuildInfo:      info,
 		LoggingOptions: []zap.Option{logger.WrapCoreOpt()},
-		ConfigProvider: config.GetConfigProvider(flagSet),
 	}
 
-	if err = run(params, flagSet); err != nil {
+	adminServer := startAdminServer(flagSet, extraConfig)
+	err = run(params, flagSet)
+	if adminServer != nil {
+		_ = adminServer.Shutdown(context.Background())
+	}
+	// send the buffered logs before exiting
+	logger.Close()
+	if err != nil {
 		logFatal(err)
 	}
 }
 
+// startAdminServer starts the admin server when enabled by the flag or the extracfg file, the flag
+// taking precedence. It is not started for the sub commands.
+func startAdminServer(flagSet *flag.FlagSet, extraCfg *extraconfig.ExtraConfig) *admin.Server {
+	endpoint := config.GetAdminEndpoint(flagSet)
+	if endpoint == "" && extraCfg != nil {
+		endpoint = extraCfg.AdminEndpoint
+	}
+	if endpoint == "" || flagSet.NArg() > 0 {
+		return nil
+	}
+	server := admin.NewServer(endpoint)
+	if err := server.Start(); err != nil {
+		log.Printf("E! failed to start the admin server: %v\n", err)
+		return nil
+	}
+	return server
+}
+
 // We parse the flags manually here so that we can use feature gates when constructing
-// our default component list. Flags also need to be parsed before creating the config provider.
+// our default component list. Flags also need to be parsed before creating the config provider
+// when the collector runs as a Windows service, in interactive mode cobra parses them again.
 func buildAndParseFlagSet(featgate *featuregate.Registry) (*flag.FlagSet, error) {
 	flagSet := config.Flags(featgate)
 
@@ -93,8 +147,10 @@ func buildAndParseFlagSet(featgate *featuregate.Registry) (*flag.FlagSet, error)
 	return flagSet, nil
 }
 
-func runInteractive(params otelcol.CollectorSettings, flagSet *flag.FlagSet) error {
-	cmd := newCommand(params, flagSet)
+func runInteractive(params otelcol.CollectorSettings) error {
+	// cobra parses the arguments of the root and sub commands, so it gets a fresh flag set
+	// rather than the one already parsed in main.
+	cmd := newCommand(params, config.Flags(featuregate.GlobalRegistry()))
 	err := cmd.Execute()
 	if err != nil {
 		return fmt.Errorf("application run finished with error: %w", err)
@@ -106,20 +162,146 @@ func setCollectorConfigFromExtraCfg(extraCfg *extraconfig.ExtraConfig) {
 	if extraCfg.LoggingLevel != "" {
 		logger.SetLogLevel(extraCfg.LoggingLevel)
 	}
+	setComponentLogLevelsFromExtraCfg(extraCfg)
+
+	for key, val := range extraCfgEnv(extraCfg) {
+		if err := os.Setenv(key, val); err != nil {
+			log.Printf("failed to set env var %s:%v\n", key, err)
+		}
+	}
+
+	setFeatureGatesFromExtraCfg(extraCfg)
+	setOutboundFromExtraCfg(extraCfg)
+	setCredentialSettingsFromExtraCfg(extraCfg)
+	// the --strict-env flag takes precedence
+	config.SetDefaultStrictEnv(extraCfg.StrictEnv)
+}
+
+// setOutboundFromExtraCfg sets the proxy and CA bundle of the environment, overridden by the ones of
+// the extracfg file when there is one. The service::outbound block of the configuration overrides them.
+func setOutboundFromExtraCfg(extraCfg *extraconfig.ExtraConfig) {
+	settings := environmentOutbound
+	if extraCfg != nil {
+		settings = settings.Override(outbound.Settings{
+			HTTPProxy:  extraCfg.Proxy.HTTPProxy,
+			HTTPSProxy: extraCfg.Proxy.HTTPSProxy,
+			NoProxy:    extraCfg.Proxy.NoProxy,
+			CABundle:   extraCfg.Proxy.CABundle,
+		})
+	}
+	if err := config.SetDefaultOutboundSettings(settings); err != nil {
+		log.Printf("E! ignoring invalid outbound settings: %v\n", err)
+	}
+}
+
+// setCredentialSettingsFromExtraCfg sets the AWS credential settings of the extracfg file, which the
+// service::aws block of the configuration overrides.
+func setCredentialSettingsFromExtraCfg(extraCfg *extraconfig.ExtraConfig) {
+	settings := awssession.CredentialSettings{
+		RoleARN:              extraCfg.RoleArn,
+		ExternalID:           extraCfg.ExternalID,
+		RoleSessionName:      extraCfg.RoleSessionName,
+		WebIdentityTokenFile: extraCfg.WebIdentityTokenFile,
+		STSRegionalEndpoints: extraCfg.STSRegionalEndpoints,
+		CredentialDuration:   extraCfg.CredentialDuration,
+	}
+	if err := config.SetDefaultCredentialSettings(settings); err != nil {
+		log.Printf("E! ignoring invalid AWS credential settings of extra config: %v\n", err)
+	}
+}
 
-	if extraCfg.AwsProfile != "" {
-		if err := os.Setenv(awsProfileKey, extraCfg.AwsProfile); err != nil {
-			log.Printf("failed to set env var %s:%v\n", awsProfileKey, err)
+// extraCfgEnv returns the environment variables set by the extracfg file.
+func extraCfgEnv(extraCfg *extraconfig.ExtraConfig) map[string]string {
+	env := map[string]string{}
+	for key, val := range extraCfg.Env {
+		env[key] = val
+	}
+	for key, val := range map[string]string{
+		awsProfileKey:        extraCfg.AwsProfile,
+		awsCredentialFileKey: extraCfg.AwsCredentialFile,
+		awsRegionKey:         extraCfg.AwsRegion,
+	} {
+		if val != "" {
+			env[key] = val
 		}
 	}
+	return env
+}
 
-	if extraCfg.AwsCredentialFile != "" {
-		if err := os.Setenv(awsCredentialFileKey, extraCfg.AwsCredentialFile); err != nil {
-			log.Printf("failed to set env var %s:%v\n", awsCredentialFileKey, err)
+// setFeatureGatesFromExtraCfg applies the feature gates before the flags are parsed, so that the
+// --feature-gates flag takes precedence.
+func setFeatureGatesFromExtraCfg(extraCfg *extraconfig.ExtraConfig) {
+	for _, gate := range extraCfg.FeatureGates {
+		id := strings.TrimLeft(gate, "+-")
+		if err := featuregate.GlobalRegistry().Set(id, !strings.HasPrefix(gate, "-")); err != nil {
+			log.Printf("W! failed to set feature gate %q from extra config: %v\n", gate, err)
 		}
 	}
 }
 
+func setComponentLogLevelsFromExtraCfg(extraCfg *extraconfig.ExtraConfig) {
+	levels, err := logger.ParseComponentLevels(extraCfg.ComponentLogLevels)
+	if err != nil {
+		log.Printf("E! ignoring invalid component log levels: %v\n", err)
+		return
+	}
+	logger.SetDefaultComponentLevels(levels)
+}
+
+func setLogFileFromExtraCfg(extraCfg *extraconfig.ExtraConfig) {
+	settings := logger.GetDefaultFileSettings()
+	if extraCfg.LogFile.Path != "" {
+		settings.Path = extraCfg.LogFile.Path
+	}
+	if extraCfg.LogFile.MaxSize != nil {
+		settings.MaxSize = *extraCfg.LogFile.MaxSize
+	}
+	if extraCfg.LogFile.MaxBackups != nil {
+		settings.MaxBackups = *extraCfg.LogFile.MaxBackups
+	}
+	if extraCfg.LogFile.MaxAge != nil {
+		settings.MaxAge = *extraCfg.LogFile.MaxAge
+	}
+	if extraCfg.LogFile.Compress != nil {
+		settings.Compress = *extraCfg.LogFile.Compress
+	}
+	if extraCfg.LogFile.Encoding != "" {
+		settings.Encoding = extraCfg.LogFile.Encoding
+	}
+	if extraCfg.LogFile.RotateAt != "" {
+		settings.RotateAt = extraCfg.LogFile.RotateAt
+	}
+	logger.SetDefaultFileSettings(settings)
+}
+
+func setCloudWatchLogsFromExtraCfg(extraCfg *extraconfig.ExtraConfig) {
+	cw := extraCfg.CloudWatchLogs
+	if cw.LogGroup == "" {
+		return
+	}
+	settings := logger.DefaultCloudWatchSettings()
+	settings.LogGroup, settings.LogStream = cw.LogGroup, cw.LogStream
+	settings.Region, settings.Endpoint = cw.Region, cw.Endpoint
+	if cw.FlushInterval > 0 {
+		settings.FlushInterval = cw.FlushInterval
+	}
+	if cw.MaxBufferSize > 0 {
+		settings.MaxBufferSize = cw.MaxBufferSize
+	}
+	if err := logger.SetCloudWatchSettings(settings); err != nil {
+		log.Printf("E! failed to ship the collector logs to CloudWatch Logs: %v\n", err)
+	}
+}
+
+func logExtraCfgProblems(extraCfg *extraconfig.ExtraConfig, err error) {
+	for _, warning := range extraCfg.Warnings {
+		log.Printf("W! extra config %s\n", warning)
+	}
+	if err != nil {
+		log.Printf("E! ignoring invalid extra config settings: %v\n", err)
+	}
+}
+
 // newCommand constructs a new cobra.Command using the given settings.
 func newCommand(params otelcol.CollectorSettings, flagSet *flag.FlagSet) *cobra.Command {
 	rootCmd := &cobra.Command{
@@ -127,6 +309,13 @@ func newCommand(params otelcol.CollectorSettings, flagSet *flag.FlagSet) *cobra.
 		Version:      params.BuildInfo.Version,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if params.ConfigProvider == nil {
+				provider, err := newConfigProvider(flagSet)
+				if err != nil {
+					return err
+				}
+				params.ConfigProvider = provider
+			}
 			col, err := otelcol.NewCollector(params)
 			if err != nil {
 				return fmt.Errorf("failed to construct the application: %w", err)
@@ -135,6 +324,12 @@ func newCommand(params otelcol.CollectorSettings, flagSet *flag.FlagSet) *cobra.
 		},
 	}
 
+	rootCmd.AddCommand(newValidateSubCommand(params, flagSet))
+	rootCmd.AddCommand(newComponentsSubCommand(params))
+	rootCmd.AddCommand(newPrintConfigSubCommand(flagSet))
+	rootCmd.AddCommand(newVersionSubCommand(featuregate.GlobalRegistry()))
+	rootCmd.AddCommand(newDiagnoseSubCommand(flagSet))
+	rootCmd.AddCommand(newSupportBundleSubCommand(flagSet))
 	rootCmd.Flags().AddGoFlagSet(flagSet)
 	return rootCmd
 }
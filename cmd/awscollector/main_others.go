@@ -26,8 +26,8 @@ import (
 	"go.opentelemetry.io/collector/otelcol"
 )
 
-func run(params otelcol.CollectorSettings, flagSet *flag.FlagSet) error {
-	return runInteractive(params, flagSet)
+func run(params otelcol.CollectorSettings, _ *flag.FlagSet) error {
+	return runInteractive(params)
 }
 
 func logFatal(err error) {
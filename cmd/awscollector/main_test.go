@@ -16,15 +16,23 @@
 package main
 
 import (
+	"bytes"
 	"flag"
+	"os"
 	"testing"
 
+	"go.opentelemetry.io/collector/featuregate"
 	"go.opentelemetry.io/collector/otelcol"
 
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+	"github.com/aws-observability/aws-otel-collector/pkg/config"
 	"github.com/aws-observability/aws-otel-collector/pkg/defaultcomponents"
+	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
+	"github.com/aws-observability/aws-otel-collector/pkg/logger"
 )
 
 func TestNewCommandFlagSet(t *testing.T) {
@@ -33,9 +41,73 @@ func TestNewCommandFlagSet(t *testing.T) {
 		Factories: defaultcomponents.Components,
 	}
 
-	validFlags := []string{"config", "set", "feature-gates"}
+	validFlags := []string{"config", "config-dir", "set", "unset", "set-file", "strict-env", "watch-config", "config-poll-interval", "admin-endpoint", "feature-gates"}
 	fs := newCommand(params, flagSet).Flags()
 	fs.VisitAll(func(f *pflag.Flag) {
 		assert.Contains(t, validFlags, f.Name)
 	})
 }
+
+func TestNewCommandSubCommands(t *testing.T) {
+	params := otelcol.CollectorSettings{
+		Factories: defaultcomponents.Components,
+	}
+
+	cmd := newCommand(params, new(flag.FlagSet))
+	for _, name := range []string{"validate", "components", "print-config", "version", "diagnose", "support-bundle"} {
+		subCmd, _, err := cmd.Find([]string{name})
+		assert.NoError(t, err)
+		assert.Equal(t, name, subCmd.Name())
+	}
+}
+
+// TestSubCommandsWithLogLevel runs the sub commands with the log level of the extracfg file, which
+// must not add flags they reject.
+func TestSubCommandsWithLogLevel(t *testing.T) {
+	t.Cleanup(func() { logger.SetLogLevel("") })
+	args := append([]string(nil), os.Args...)
+	setCollectorConfigFromExtraCfg(&extraconfig.ExtraConfig{LoggingLevel: "DEBUG"})
+	assert.Equal(t, args, os.Args)
+
+	params := otelcol.CollectorSettings{
+		Factories: defaultcomponents.Components,
+	}
+	conf := "--config=yaml:{receivers: {otlp: {protocols: {grpc: }}}, exporters: {logging: }, " +
+		"service: {pipelines: {traces: {receivers: [otlp], exporters: [logging]}}}}"
+	for _, args := range [][]string{
+		{"components"},
+		{"version"},
+		{"validate", conf},
+		{"print-config", conf},
+	} {
+		t.Run(args[0], func(t *testing.T) {
+			var out bytes.Buffer
+			cmd := newCommand(params, config.Flags(featuregate.NewRegistry()))
+			cmd.SetOut(&out)
+			cmd.SetErr(&out)
+			cmd.SetArgs(args)
+			require.NoError(t, cmd.Execute(), out.String())
+		})
+	}
+
+	var out bytes.Buffer
+	cmd := newCommand(params, config.Flags(featuregate.NewRegistry()))
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"print-config", conf})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "level: DEBUG")
+}
+
+// TestExtraCfgRoleWithWebIdentity checks that the role of the extracfg file is assumed with the web
+// identity of an EKS service account, rather than replacing the role of the service account.
+func TestExtraCfgRoleWithWebIdentity(t *testing.T) {
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "/var/run/secrets/eks.amazonaws.com/serviceaccount/token")
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/service-account")
+	t.Cleanup(func() { require.NoError(t, config.SetDefaultCredentialSettings(awssession.CredentialSettings{})) })
+
+	setCollectorConfigFromExtraCfg(&extraconfig.ExtraConfig{RoleArn: "arn:aws:iam::210987654321:role/adot"})
+	assert.Equal(t, "arn:aws:iam::123456789012:role/service-account", os.Getenv("AWS_ROLE_ARN"))
+	settings := awssession.GetCredentialSettings()
+	assert.Equal(t, "arn:aws:iam::210987654321:role/adot", settings.RoleARN)
+	assert.True(t, settings.AssumesRole())
+}
@@ -37,13 +37,19 @@ func run(params otelcol.CollectorSettings, flagSet *flag.FlagSet) error {
 	}
 
 	if isService {
-		return runService(params)
+		return runService(params, flagSet)
 	} else {
-		return runInteractive(params, flagSet)
+		return runInteractive(params)
 	}
 }
 
-func runService(params otelcol.CollectorSettings) error {
+func runService(params otelcol.CollectorSettings, flagSet *flag.FlagSet) error {
+	provider, err := newConfigProvider(flagSet)
+	if err != nil {
+		return err
+	}
+	params.ConfigProvider = provider
+
 	if err := svc.Run("", otelcol.NewSvcHandler(params)); err != nil {
 		return errors.Wrap(err, "failed to start service")
 	}
@@ -1,54 +1,276 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
-func main() {
+// maxBodySize caps the response body read from the health_check extension.
+const maxBodySize = 64 * 1024
 
-	const host = "127.0.0.1" // default host
-	usedPort := "13133"      // default port
-	const path = "/"         //default path
-	generateCmd := flag.NewFlagSet("generate", flag.ExitOnError)
-	port := generateCmd.String("port", usedPort, "Specify collector health-check port")
+const (
+	defaultPort = "13133"
+	// pipelineHealthPort is the default port of the pipeline_health extension, queried with --mode
+	pipelineHealthPort = "13135"
+)
+
+// modePaths are the paths of the pipeline_health extension for each mode.
+var modePaths = map[string]string{
+	"liveness":  "/liveness",
+	"readiness": "/readiness",
+	"pipeline":  "/pipelines",
+}
+
+// options are the command line options of the health check.
+type options struct {
+	scheme        string
+	host          string
+	port          string
+	path          string
+	timeout       time.Duration
+	caFile        string
+	certFile      string
+	keyFile       string
+	retries       int
+	retryInterval time.Duration
+	json          bool
+	mode          string
+}
 
-	if len(os.Args) > 1 {
-		err := generateCmd.Parse(os.Args[1:])
-		if err != nil {
-			log.Fatalf("%s", err)
+// healthResponse is the body returned by the health_check and pipeline_health extensions.
+type healthResponse struct {
+	Status    string    `json:"status"`
+	UpSince   time.Time `json:"upSince"`
+	Uptime    string    `json:"uptime"`
+	Error     string    `json:"error"`
+	Pipelines map[string]struct {
+		Status string `json:"status"`
+	} `json:"pipelines"`
+}
+
+// healthResult is the result of the health check, printed with --json.
+type healthResult struct {
+	Healthy    bool       `json:"healthy"`
+	StatusCode int        `json:"statusCode,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	UpSince    *time.Time `json:"upSince,omitempty"`
+	Uptime     string     `json:"uptime,omitempty"`
+	// Pipelines maps each pipeline to its health, with --mode=pipeline
+	Pipelines map[string]string `json:"pipelines,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+func (r healthResult) String() string {
+	s := fmt.Sprintf("STATUS: %d", r.StatusCode)
+	if r.Status != "" {
+		s += " " + r.Status
+	}
+	if r.Uptime != "" {
+		s += ", uptime " + r.Uptime
+	}
+	if len(r.Pipelines) > 0 {
+		names := make([]string, 0, len(r.Pipelines))
+		for name := range r.Pipelines {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		pipelines := make([]string, len(names))
+		for i, name := range names {
+			pipelines[i] = name + ": " + r.Pipelines[name]
 		}
+		s += " (" + strings.Join(pipelines, ", ") + ")"
 	}
+	return s
+}
 
-	validationErr := validatePort(*port)
-	if validationErr != nil {
-		log.Fatalf("%s", validationErr)
+func main() {
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		log.Fatalf("%s", err)
 	}
 
-	status, healthCheckError := executeHealthCheck(host, port, path)
+	client, err := newClient(opts)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	result, healthCheckError := checkWithRetries(client, healthURL(opts), opts.retries, opts.retryInterval)
+
+	if opts.json {
+		out, _ := json.Marshal(result)
+		fmt.Println(string(out))
+		if healthCheckError != nil {
+			os.Exit(1)
+		}
+		return
+	}
 
 	if healthCheckError != nil {
 		log.Fatalf(healthCheckError.Error())
 	}
 
-	log.Printf("%s", status)
+	log.Printf("%s", result)
 
 }
 
-func executeHealthCheck(host string, port *string, path string) (string, error) {
+func parseFlags(args []string) (options, error) {
+	opts := options{}
+	generateCmd := flag.NewFlagSet("generate", flag.ExitOnError)
+	generateCmd.StringVar(&opts.port, "port", defaultPort, "Specify collector health-check port")
+	generateCmd.StringVar(&opts.scheme, "scheme", "http", "Scheme of the health-check endpoint, http or https")
+	generateCmd.StringVar(&opts.host, "host", "127.0.0.1", "Host of the health-check endpoint")
+	generateCmd.StringVar(&opts.path, "path", "/", "Path of the health-check endpoint")
+	generateCmd.DurationVar(&opts.timeout, "timeout", 5*time.Second, "Timeout of each health-check request")
+	generateCmd.StringVar(&opts.caFile, "ca-file", "", "CA certificate used to verify the health-check endpoint with https")
+	generateCmd.StringVar(&opts.certFile, "cert-file", "", "Client certificate used with https")
+	generateCmd.StringVar(&opts.keyFile, "key-file", "", "Client key used with https")
+	generateCmd.IntVar(&opts.retries, "retries", 0, "Number of times a failed health check is retried")
+	generateCmd.DurationVar(&opts.retryInterval, "retry-interval", time.Second, "Time between retries")
+	generateCmd.BoolVar(&opts.json, "json", false, "Print the health status as JSON")
+	generateCmd.StringVar(&opts.mode, "mode", "", "Query the pipeline_health extension instead of health_check: liveness, readiness or pipeline")
 
-	resp, err := http.Get(fmt.Sprint("http://", host, ":", *port, path))
+	if len(args) > 0 {
+		if err := generateCmd.Parse(args); err != nil {
+			return opts, err
+		}
+	}
+
+	if opts.mode != "" {
+		path, ok := modePaths[opts.mode]
+		if !ok {
+			return opts, fmt.Errorf("invalid mode %q, must be liveness, readiness or pipeline", opts.mode)
+		}
+		// the port and path default to the ones of the pipeline_health extension
+		set := map[string]bool{}
+		generateCmd.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["port"] {
+			opts.port = pipelineHealthPort
+		}
+		if !set["path"] {
+			opts.path = path
+		}
+	}
+
+	if err := validatePort(opts.port); err != nil {
+		return opts, err
+	}
+	if opts.scheme != "http" && opts.scheme != "https" {
+		return opts, fmt.Errorf("invalid scheme %q, must be http or https", opts.scheme)
+	}
+	if opts.timeout <= 0 {
+		return opts, fmt.Errorf("timeout must be positive: %s", opts.timeout)
+	}
+	if opts.retries < 0 {
+		return opts, fmt.Errorf("retries must not be negative: %d", opts.retries)
+	}
+	if (opts.certFile == "") != (opts.keyFile == "") {
+		return opts, errors.New("cert-file and key-file must be set together")
+	}
+	return opts, nil
+}
+
+func healthURL(opts options) string {
+	u := url.URL{
+		Scheme: opts.scheme,
+		Host:   net.JoinHostPort(opts.host, opts.port),
+		Path:   opts.path,
+	}
+	return u.String()
+}
+
+// newClient creates the client of the health check, it never uses a proxy as the collector is
+// expected to run on the same host or pod.
+func newClient(opts options) (*http.Client, error) {
+	transport := &http.Transport{Proxy: nil}
+	if opts.scheme == "https" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if opts.caFile != "" {
+			pem, err := os.ReadFile(opts.caFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in CA file %s", opts.caFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if opts.certFile != "" {
+			cert, err := tls.LoadX509KeyPair(opts.certFile, opts.keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{Transport: transport, Timeout: opts.timeout}, nil
+}
+
+func checkWithRetries(client *http.Client, endpoint string, retries int, interval time.Duration) (healthResult, error) {
+	result, err := executeHealthCheck(client, endpoint)
+	for attempt := 0; err != nil && attempt < retries; attempt++ {
+		time.Sleep(interval)
+		result, err = executeHealthCheck(client, endpoint)
+	}
+	return result, err
+}
+
+func executeHealthCheck(client *http.Client, endpoint string) (healthResult, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, endpoint, nil)
+	if err != nil {
+		return healthResult{Error: err.Error()}, fmt.Errorf("invalid health-check endpoint: %w", err)
+	}
+	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("unable to retrieve health status: %s", err.Error())
+		err = fmt.Errorf("unable to retrieve health status: %s", err.Error())
+		return healthResult{Error: err.Error()}, err
+	}
+	defer resp.Body.Close()
+
+	result := healthResult{StatusCode: resp.StatusCode}
+	// the body is informative only, the status code decides the health
+	if body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxBodySize)); readErr == nil {
+		var health healthResponse
+		if json.Unmarshal(body, &health) == nil {
+			result.Status = health.Status
+			result.Uptime = health.Uptime
+			if health.Error != "" {
+				result.Status += ": " + health.Error
+			}
+			for name, pipeline := range health.Pipelines {
+				if result.Pipelines == nil {
+					result.Pipelines = map[string]string{}
+				}
+				result.Pipelines[name] = pipeline.Status
+			}
+			if !health.UpSince.IsZero() {
+				result.UpSince = &health.UpSince
+			}
+		}
 	}
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("STATUS: %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("%s", result)
+		result.Error = err.Error()
+		return result, err
 	}
-	return fmt.Sprintf("STATUS: %d", resp.StatusCode), nil
+	result.Healthy = true
+	return result, nil
 }
 
 // validatePort checks if the port configuration is valid
@@ -1,24 +1,37 @@
 package main
 
 import (
+	"encoding/pem"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+func defaultOptions(t *testing.T) options {
+	opts, err := parseFlags(nil)
+	require.NoError(t, err)
+	return opts
+}
+
 func TestHealthStatusHealthy(t *testing.T) {
 
 	server := setUpMockCollector(t, "127.0.0.1:13133", http.StatusOK)
 	defer server.Close()
-	port := "13133"
-	got, err := executeHealthCheck("127.0.0.1", &port, "/")
+	opts := defaultOptions(t)
+	client, err := newClient(opts)
+	require.NoError(t, err)
+	got, err := executeHealthCheck(client, healthURL(opts))
 	expectedErrorString := "STATUS: 200"
-	assert.Contains(t, got, expectedErrorString,
+	assert.Contains(t, got.String(), expectedErrorString,
 		fmt.Sprintf("Unexpected log message. Got %s but should contain %s", got, expectedErrorString))
 	assert.NoError(t, err)
 
@@ -28,8 +41,10 @@ func TestHealthStatusUnhealthy(t *testing.T) {
 
 	server := setUpMockCollector(t, "127.0.0.1:13133", http.StatusInternalServerError)
 	defer server.Close()
-	port := "13133"
-	got, err := executeHealthCheck("127.0.0.1", &port, "/")
+	opts := defaultOptions(t)
+	client, err := newClient(opts)
+	require.NoError(t, err)
+	got, err := executeHealthCheck(client, healthURL(opts))
 	expectedErrorString := "STATUS: 500"
 	assert.Contains(t, err.Error(), expectedErrorString,
 		fmt.Sprintf("Unexpected log message. Got %s but should contain %s", got, expectedErrorString))
@@ -40,14 +55,174 @@ func TestHealthStatusServerDown(t *testing.T) {
 
 	server := setUpMockCollector(t, "127.0.0.1:13132", http.StatusInternalServerError)
 	defer server.Close()
-	port := "13133"
-	got, err := executeHealthCheck("127.0.0.1", &port, "/")
+	opts := defaultOptions(t)
+	client, err := newClient(opts)
+	require.NoError(t, err)
+	got, err := executeHealthCheck(client, healthURL(opts))
 	expectedErrorString := "unable to retrieve health status"
 	assert.Contains(t, err.Error(), expectedErrorString,
 		fmt.Sprintf("Unexpected log message. Got %s but should contain %s", got, expectedErrorString))
 
 }
 
+func TestHealthStatusBody(t *testing.T) {
+	upSince := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/health/status", req.URL.Path)
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(rw, `{"status":"Server available","upSince":%q,"uptime":"1h0m0s"}`, upSince.Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	opts, err := parseFlags([]string{"--host", host, "--port", port, "--path", "/health/status", "--json"})
+	require.NoError(t, err)
+	client, err := newClient(opts)
+	require.NoError(t, err)
+
+	got, err := executeHealthCheck(client, healthURL(opts))
+	require.NoError(t, err)
+	assert.Equal(t, healthResult{
+		Healthy:    true,
+		StatusCode: http.StatusOK,
+		Status:     "Server available",
+		UpSince:    &upSince,
+		Uptime:     "1h0m0s",
+	}, got)
+	assert.Equal(t, "STATUS: 200 Server available, uptime 1h0m0s", got.String())
+}
+
+func TestHealthStatusPipelines(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/pipelines", req.URL.Path)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = fmt.Fprint(rw, `{"status":"unhealthy","uptime":"10m0s","pipelines":{"traces":{"status":"healthy","components":{}},"metrics":{"status":"unhealthy","components":{}}}}`)
+	}))
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	opts, err := parseFlags([]string{"--mode", "pipeline", "--host", host, "--port", port})
+	require.NoError(t, err)
+	client, err := newClient(opts)
+	require.NoError(t, err)
+
+	got, err := executeHealthCheck(client, healthURL(opts))
+	assert.EqualError(t, err, "STATUS: 503 unhealthy, uptime 10m0s (metrics: unhealthy, traces: healthy)")
+	assert.Equal(t, map[string]string{"traces": "healthy", "metrics": "unhealthy"}, got.Pipelines)
+	assert.False(t, got.Healthy)
+}
+
+func TestHealthStatusTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	opts, err := parseFlags([]string{"--scheme", "https", "--port", port, "--ca-file", caFile})
+	require.NoError(t, err)
+	client, err := newClient(opts)
+	require.NoError(t, err)
+	_, err = executeHealthCheck(client, healthURL(opts))
+	assert.NoError(t, err)
+
+	// the certificate of the server is not trusted without the CA file
+	opts.caFile = ""
+	client, err = newClient(opts)
+	require.NoError(t, err)
+	_, err = executeHealthCheck(client, healthURL(opts))
+	assert.Error(t, err)
+}
+
+func TestHealthStatusRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) < 3 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := newClient(defaultOptions(t))
+	require.NoError(t, err)
+	_, err = checkWithRetries(client, server.URL, 1, time.Millisecond)
+	assert.Error(t, err)
+	result, err := checkWithRetries(client, server.URL, 1, time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, result.Healthy)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestParseFlags(t *testing.T) {
+	testCases := []struct {
+		name           string
+		args           []string
+		expectedURL    string
+		errorAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:           "Defaults",
+			expectedURL:    "http://127.0.0.1:13133/",
+			errorAssertion: assert.NoError,
+		},
+		{
+			name:           "Custom",
+			args:           []string{"--scheme=https", "--host=::1", "--port=8443", "--path=/healthz"},
+			expectedURL:    "https://[::1]:8443/healthz",
+			errorAssertion: assert.NoError,
+		},
+		{
+			name:           "PipelineMode",
+			args:           []string{"--mode=pipeline"},
+			expectedURL:    "http://127.0.0.1:13135/pipelines",
+			errorAssertion: assert.NoError,
+		},
+		{
+			name:           "ReadinessModeCustomPort",
+			args:           []string{"--mode", "readiness", "--port", "8080"},
+			expectedURL:    "http://127.0.0.1:8080/readiness",
+			errorAssertion: assert.NoError,
+		},
+		{
+			name:           "WrongMode",
+			args:           []string{"--mode=startup"},
+			errorAssertion: assert.Error,
+		},
+		{
+			name:           "WrongScheme",
+			args:           []string{"--scheme=ftp"},
+			errorAssertion: assert.Error,
+		},
+		{
+			name:           "WrongTimeout",
+			args:           []string{"--timeout=0s"},
+			errorAssertion: assert.Error,
+		},
+		{
+			name:           "CertWithoutKey",
+			args:           []string{"--cert-file=client.pem"},
+			errorAssertion: assert.Error,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := parseFlags(tc.args)
+			tc.errorAssertion(t, err)
+			if tc.expectedURL != "" {
+				assert.Equal(t, tc.expectedURL, healthURL(opts))
+			}
+		})
+	}
+}
+
 func setUpMockCollector(t *testing.T, healthCheckDefaultEndpoint string, statusCode int) *httptest.Server {
 	l, err := net.Listen("tcp", healthCheckDefaultEndpoint)
 	require.NoError(t, err)
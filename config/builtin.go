@@ -0,0 +1,25 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package config embeds the configurations shipped with the collector, so that they can be loaded
+// with the builtin scheme, e.g. --config=builtin:ecs/ecs-amp-xray.
+package config // import "github.com/aws-observability/aws-otel-collector/config"
+
+import "embed"
+
+// FS holds the built-in configurations, named after their path without the .yaml extension.
+//
+//go:embed apprunner ec2 ecs eks
+var FS embed.FS
@@ -0,0 +1,89 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/logger"
+)
+
+const logLevelsPath = "/loglevels"
+
+// logLevelRequest is the body of a request setting the level of a component.
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// logLevelsHandler serves the component log levels:
+//
+//	GET    /loglevels             returns the level of every component, e.g. {"awsemf":"debug"}
+//	PUT    /loglevels/<component> sets the level of the component, e.g. {"level":"debug"}
+//	DELETE /loglevels/<component> removes the level of the component
+//
+// The component is either a component ID, e.g. otlp/app, or a type, e.g. awsemf. The changes are
+// lost when the configuration is reloaded.
+func logLevelsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		component := strings.Trim(strings.TrimPrefix(r.URL.Path, logLevelsPath), "/")
+		if component == "" {
+			if r.Method != http.MethodGet {
+				w.Header().Set("Allow", http.MethodGet)
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			writeLevels(w)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			var req logLevelRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+				http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			levels, err := logger.ParseComponentLevels(map[string]string{component: req.Level})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logger.SetComponentLevel(component, levels[component])
+			log.Printf("I! log level of %s set to %s through the admin server\n", component, levels[component])
+		case http.MethodDelete:
+			logger.UnsetComponentLevel(component)
+			log.Printf("I! log level of %s removed through the admin server\n", component)
+		default:
+			w.Header().Set("Allow", http.MethodPut+", "+http.MethodDelete)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeLevels(w)
+	})
+}
+
+func writeLevels(w http.ResponseWriter) {
+	levels := map[string]string{}
+	for component, level := range logger.GetComponentLevels() {
+		levels[component] = level.String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(levels)
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package admin
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/logger"
+)
+
+func TestLogLevelsHandler(t *testing.T) {
+	levels := logger.GetComponentLevels()
+	t.Cleanup(func() { logger.SetComponentLevels(levels) })
+	logger.SetComponentLevels(map[string]zapcore.Level{"otlp": zapcore.WarnLevel})
+
+	server := NewServer("localhost:0")
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "get",
+			method:         http.MethodGet,
+			path:           "/loglevels",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"otlp":"warn"}`,
+		},
+		{
+			name:           "set",
+			method:         http.MethodPut,
+			path:           "/loglevels/awsemf",
+			body:           `{"level":"DEBUG"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"awsemf":"debug","otlp":"warn"}`,
+		},
+		{
+			name:           "set_invalid_level",
+			method:         http.MethodPut,
+			path:           "/loglevels/awsemf",
+			body:           `{"level":"verbose"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "set_invalid_body",
+			method:         http.MethodPut,
+			path:           "/loglevels/awsemf",
+			body:           `debug`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "delete",
+			method:         http.MethodDelete,
+			path:           "/loglevels/otlp",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"awsemf":"debug"}`,
+		},
+		{
+			name:           "method_not_allowed",
+			method:         http.MethodPost,
+			path:           "/loglevels",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedBody != "" {
+				body, err := io.ReadAll(rec.Body)
+				require.NoError(t, err)
+				assert.JSONEq(t, tt.expectedBody, string(body))
+			}
+		})
+	}
+}
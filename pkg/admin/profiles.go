@@ -0,0 +1,38 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package admin
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// profilesPath is the path of the profiles, the same as the one of net/http/pprof and of the pprof
+// extension, so the same tools work with both.
+const profilesPath = "/debug/pprof/"
+
+// profiles are the profiles served, the ones collected in support bundles. The CPU profile and the
+// traces are left to the pprof extension, they keep the request busy for their whole duration.
+var profiles = []string{"goroutine", "heap", "allocs"}
+
+// profilesHandler serves the profiles, e.g. GET /debug/pprof/goroutine?debug=2 for a goroutine dump.
+func profilesHandler() http.Handler {
+	mux := http.NewServeMux()
+	for _, profile := range profiles {
+		mux.Handle(profilesPath+profile, pprof.Handler(profile))
+	}
+	return mux
+}
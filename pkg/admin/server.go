@@ -0,0 +1,85 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package admin implements the local admin HTTP server of the collector, used to inspect and change
+// the running collector, e.g. its log levels.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
+)
+
+const readHeaderTimeout = 10 * time.Second
+
+// Server is the admin HTTP server. It has no authentication, so it should only listen on a local address.
+type Server struct {
+	mux    *http.ServeMux
+	server *http.Server
+}
+
+// NewServer creates a server listening on the endpoint, e.g. localhost:13134, with the log levels
+// and profiles handlers registered.
+func NewServer(endpoint string) *Server {
+	mux := http.NewServeMux()
+	s := &Server{
+		mux: mux,
+		server: &http.Server{
+			Addr:              endpoint,
+			Handler:           mux,
+			ReadHeaderTimeout: readHeaderTimeout,
+		},
+	}
+	s.Handle(logLevelsPath, logLevelsHandler())
+	s.Handle(logLevelsPath+"/", logLevelsHandler())
+	s.Handle(profilesPath, profilesHandler())
+	return s
+}
+
+// Handle registers a handler, it must be called before Start.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start listens on the endpoint and serves the requests in the background. It fails when the
+// endpoint is not on a loopback address, see extraconfig.ValidateAdminEndpoint.
+func (s *Server) Start() error {
+	if err := extraconfig.ValidateAdminEndpoint(s.server.Addr); err != nil {
+		return fmt.Errorf("invalid admin endpoint %s: %w", s.server.Addr, err)
+	}
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+	}
+	log.Printf("I! admin server listening on %s\n", listener.Addr())
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("E! admin server stopped: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops the server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,35 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerStart(t *testing.T) {
+	server := NewServer("localhost:0")
+	require.NoError(t, server.Start())
+	assert.NoError(t, server.Shutdown(context.Background()))
+
+	// the server has no authentication
+	for _, endpoint := range []string{"0.0.0.0:0", ":0", "[::]:0"} {
+		assert.ErrorContains(t, NewServer(endpoint).Start(), "is not a loopback address", endpoint)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package awssession
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/exportedenv"
+)
+
+const (
+	envKeyRoleARN              = "AWS_ROLE_ARN"
+	envKeyRoleSessionName      = "AWS_ROLE_SESSION_NAME"
+	envKeyWebIdentityTokenFile = "AWS_WEB_IDENTITY_TOKEN_FILE"
+	envKeySTSRegionalEndpoints = "AWS_STS_REGIONAL_ENDPOINTS"
+
+	// the limits of the duration of the role sessions of STS
+	minCredentialDuration = 15 * time.Minute
+	maxCredentialDuration = 12 * time.Hour
+)
+
+// CredentialSettings are the credential settings shared by the AWS components of the collector, set
+// with the extracfg file and the service::aws block of the configuration.
+type CredentialSettings struct {
+	// RoleARN is the role assumed by the AWS components which do not set their own
+	RoleARN    string `mapstructure:"role_arn"`
+	ExternalID string `mapstructure:"external_id"`
+	// RoleSessionName is the name of the role sessions, a name is generated when empty
+	RoleSessionName string `mapstructure:"role_session_name"`
+	// WebIdentityTokenFile assumes the role with the web identity token of the file, e.g. the one of
+	// an EKS service account, instead of assuming it with the default credentials
+	WebIdentityTokenFile string `mapstructure:"web_identity_token_file"`
+	// STSRegionalEndpoints is regional or legacy, see AWS_STS_REGIONAL_ENDPOINTS
+	STSRegionalEndpoints string `mapstructure:"sts_regional_endpoints"`
+	// CredentialDuration is the duration of the role sessions, the credentials are cached until they expire
+	CredentialDuration time.Duration `mapstructure:"credential_duration"`
+}
+
+// Validate checks the settings, the role settings require the role.
+func (s CredentialSettings) Validate() error {
+	var errs error
+	if s.RoleARN == "" {
+		for _, setting := range []struct {
+			key string
+			set bool
+		}{
+			{"external_id", s.ExternalID != ""},
+			{"role_session_name", s.RoleSessionName != ""},
+			{"web_identity_token_file", s.WebIdentityTokenFile != ""},
+			{"credential_duration", s.CredentialDuration != 0},
+		} {
+			if setting.set {
+				errs = multierr.Append(errs, fmt.Errorf("%s requires role_arn", setting.key))
+			}
+		}
+	}
+	if s.ExternalID != "" && s.WebIdentityTokenFile != "" {
+		errs = multierr.Append(errs, errors.New("external_id cannot be used with web_identity_token_file"))
+	}
+	switch s.STSRegionalEndpoints {
+	case "", "regional", "legacy":
+	default:
+		errs = multierr.Append(errs, fmt.Errorf("invalid sts_regional_endpoints %q, must be regional or legacy", s.STSRegionalEndpoints))
+	}
+	if s.CredentialDuration != 0 && (s.CredentialDuration < minCredentialDuration || s.CredentialDuration > maxCredentialDuration) {
+		errs = multierr.Append(errs, fmt.Errorf("invalid credential_duration %s, must be between %s and %s",
+			s.CredentialDuration, minCredentialDuration, maxCredentialDuration))
+	}
+	return errs
+}
+
+// Override returns the settings with the ones set in other taking precedence.
+func (s CredentialSettings) Override(other CredentialSettings) CredentialSettings {
+	if other.RoleARN != "" {
+		// the settings of a role do not carry over to another one
+		s = CredentialSettings{STSRegionalEndpoints: s.STSRegionalEndpoints}
+		s.RoleARN = other.RoleARN
+	}
+	if other.ExternalID != "" {
+		s.ExternalID = other.ExternalID
+	}
+	if other.RoleSessionName != "" {
+		s.RoleSessionName = other.RoleSessionName
+	}
+	if other.WebIdentityTokenFile != "" {
+		s.WebIdentityTokenFile = other.WebIdentityTokenFile
+	}
+	if other.STSRegionalEndpoints != "" {
+		s.STSRegionalEndpoints = other.STSRegionalEndpoints
+	}
+	if other.CredentialDuration != 0 {
+		s.CredentialDuration = other.CredentialDuration
+	}
+	return s
+}
+
+// AssumesRole reports whether the role is assumed with the default credentials. It is not with web
+// identity, the default credentials are then the ones of the role already.
+func (s CredentialSettings) AssumesRole() bool {
+	if s.RoleARN == "" || s.WebIdentityTokenFile != "" {
+		return false
+	}
+	return os.Getenv(envKeyWebIdentityTokenFile) == "" || os.Getenv(envKeyRoleARN) != s.RoleARN
+}
+
+// env returns the environment variables of the settings, which apply them to the AWS SDKs of every
+// component: the STS endpoints and the web identity of the default credentials.
+func (s CredentialSettings) env() map[string]string {
+	env := map[string]string{}
+	if s.STSRegionalEndpoints != "" {
+		env[envKeySTSRegionalEndpoints] = s.STSRegionalEndpoints
+	}
+	if s.WebIdentityTokenFile != "" {
+		env[envKeyWebIdentityTokenFile] = s.WebIdentityTokenFile
+		env[envKeyRoleARN] = s.RoleARN
+		if s.RoleSessionName != "" {
+			env[envKeyRoleSessionName] = s.RoleSessionName
+		}
+	}
+	return env
+}
+
+var (
+	credentialsMu      sync.Mutex
+	credentialSettings CredentialSettings
+	exported           exportedenv.Env
+)
+
+// SetCredentialSettings sets the settings used by the sessions created afterwards, and exports their
+// environment variables. The variables exported by previous settings and not by these are restored.
+func SetCredentialSettings(settings CredentialSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+
+	err := exported.Export(settings.env())
+	credentialSettings = settings
+	return err
+}
+
+// GetCredentialSettings returns the settings last set.
+func GetCredentialSettings() CredentialSettings {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	return credentialSettings
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package awssession
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRoleARN = "arn:aws:iam::123456789012:role/collector"
+
+func TestCredentialSettingsValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    CredentialSettings
+		expectedErr string
+	}{
+		{
+			name: "valid",
+			settings: CredentialSettings{
+				RoleARN:              testRoleARN,
+				ExternalID:           "external",
+				RoleSessionName:      "collector",
+				STSRegionalEndpoints: "regional",
+				CredentialDuration:   time.Hour,
+			},
+		},
+		{
+			name:        "role settings without role",
+			settings:    CredentialSettings{ExternalID: "external", CredentialDuration: time.Hour},
+			expectedErr: "external_id requires role_arn; credential_duration requires role_arn",
+		},
+		{
+			name:        "external id with web identity",
+			settings:    CredentialSettings{RoleARN: testRoleARN, ExternalID: "external", WebIdentityTokenFile: "/token"},
+			expectedErr: "external_id cannot be used with web_identity_token_file",
+		},
+		{
+			name:        "invalid sts endpoints",
+			settings:    CredentialSettings{STSRegionalEndpoints: "global"},
+			expectedErr: `invalid sts_regional_endpoints "global", must be regional or legacy`,
+		},
+		{
+			name:        "duration too short",
+			settings:    CredentialSettings{RoleARN: testRoleARN, CredentialDuration: time.Minute},
+			expectedErr: "invalid credential_duration 1m0s, must be between 15m0s and 12h0m0s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.Validate()
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestCredentialSettingsOverride(t *testing.T) {
+	base := CredentialSettings{
+		RoleARN:              testRoleARN,
+		ExternalID:           "external",
+		STSRegionalEndpoints: "regional",
+		CredentialDuration:   time.Hour,
+	}
+	assert.Equal(t, CredentialSettings{
+		RoleARN:              testRoleARN,
+		ExternalID:           "external",
+		RoleSessionName:      "collector",
+		STSRegionalEndpoints: "regional",
+		CredentialDuration:   2 * time.Hour,
+	}, base.Override(CredentialSettings{RoleSessionName: "collector", CredentialDuration: 2 * time.Hour}))
+	// the settings of the role do not apply to another role
+	assert.Equal(t, CredentialSettings{
+		RoleARN:              "arn:aws:iam::123456789012:role/other",
+		STSRegionalEndpoints: "regional",
+	}, base.Override(CredentialSettings{RoleARN: "arn:aws:iam::123456789012:role/other"}))
+}
+
+func TestCredentialSettingsAssumesRole(t *testing.T) {
+	t.Setenv(envKeyWebIdentityTokenFile, "")
+	t.Setenv(envKeyRoleARN, "")
+	assert.False(t, CredentialSettings{}.AssumesRole())
+	assert.True(t, CredentialSettings{RoleARN: testRoleARN}.AssumesRole())
+	assert.False(t, CredentialSettings{RoleARN: testRoleARN, WebIdentityTokenFile: "/token"}.AssumesRole())
+
+	// the role of the service account is the one of the default credentials already
+	t.Setenv(envKeyWebIdentityTokenFile, "/var/run/secrets/eks.amazonaws.com/serviceaccount/token")
+	t.Setenv(envKeyRoleARN, testRoleARN)
+	assert.False(t, CredentialSettings{RoleARN: testRoleARN}.AssumesRole())
+	assert.True(t, CredentialSettings{RoleARN: "arn:aws:iam::123456789012:role/other"}.AssumesRole())
+}
+
+func TestSetCredentialSettingsEnv(t *testing.T) {
+	t.Setenv(envKeyRoleARN, "arn:aws:iam::123456789012:role/previous")
+	require.NoError(t, os.Unsetenv(envKeyRoleARN))
+	t.Setenv(envKeySTSRegionalEndpoints, "legacy")
+	t.Cleanup(func() { require.NoError(t, SetCredentialSettings(CredentialSettings{})) })
+
+	require.NoError(t, SetCredentialSettings(CredentialSettings{
+		RoleARN:              testRoleARN,
+		RoleSessionName:      "collector",
+		WebIdentityTokenFile: "/token",
+		STSRegionalEndpoints: "regional",
+	}))
+	assert.Equal(t, "regional", os.Getenv(envKeySTSRegionalEndpoints))
+	assert.Equal(t, testRoleARN, os.Getenv(envKeyRoleARN))
+	assert.Equal(t, "/token", os.Getenv(envKeyWebIdentityTokenFile))
+	assert.Equal(t, "collector", os.Getenv(envKeyRoleSessionName))
+	assert.Equal(t, "/token", GetCredentialSettings().WebIdentityTokenFile)
+
+	// the variables which are no longer exported get their previous values back
+	require.NoError(t, SetCredentialSettings(CredentialSettings{RoleARN: testRoleARN}))
+	assert.Equal(t, "legacy", os.Getenv(envKeySTSRegionalEndpoints))
+	_, ok := os.LookupEnv(envKeyRoleARN)
+	assert.False(t, ok)
+	_, ok = os.LookupEnv(envKeyWebIdentityTokenFile)
+	assert.False(t, ok)
+
+	assert.Error(t, SetCredentialSettings(CredentialSettings{STSRegionalEndpoints: "global"}))
+	assert.Equal(t, testRoleARN, GetCredentialSettings().RoleARN)
+}
+
+func TestNewAssumesRole(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAUSER")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+	t.Setenv(envKeyWebIdentityTokenFile, "")
+
+	sts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		require.NoError(t, req.ParseForm())
+		assert.Equal(t, "AssumeRole", req.Form.Get("Action"))
+		assert.Equal(t, testRoleARN, req.Form.Get("RoleArn"))
+		assert.Equal(t, "external", req.Form.Get("ExternalId"))
+		assert.Equal(t, "collector", req.Form.Get("RoleSessionName"))
+		assert.Equal(t, "3600", req.Form.Get("DurationSeconds"))
+		fmt.Fprintf(rw, `<AssumeRoleResponse><AssumeRoleResult><Credentials><AccessKeyId>ASIAROLE</AccessKeyId>`+
+			`<SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken><Expiration>%s</Expiration></Credentials>`+
+			`</AssumeRoleResult></AssumeRoleResponse>`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	defer sts.Close()
+
+	require.NoError(t, SetCredentialSettings(CredentialSettings{
+		RoleARN:            testRoleARN,
+		ExternalID:         "external",
+		RoleSessionName:    "collector",
+		CredentialDuration: time.Hour,
+	}))
+	t.Cleanup(func() { require.NoError(t, SetCredentialSettings(CredentialSettings{})) })
+
+	sess, err := New("us-west-2", sts.URL)
+	require.NoError(t, err)
+	value, err := sess.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "ASIAROLE", value.AccessKeyID)
+	assert.Equal(t, "AssumeRoleProvider", value.ProviderName)
+
+	require.NoError(t, SetCredentialSettings(CredentialSettings{}))
+	sess, err = New("us-west-2", sts.URL)
+	require.NoError(t, err)
+	value, err = sess.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "AKIAUSER", value.AccessKeyID)
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package awssession creates the AWS SDK sessions used by the code of the distribution itself,
+// e.g. the confmap providers, as opposed to the upstream components which manage their own.
+package awssession
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// New creates a session resolving credentials through the standard chain: environment variables,
+// shared config and credentials files, web identity, ECS container role and EC2 instance role. The
+// role of the CredentialSettings is assumed with these credentials, see SetCredentialSettings.
+// An empty region falls back to the region of the environment or shared config, an empty endpoint
+// uses the default endpoint of each service.
+func New(region, endpoint string) (*session.Session, error) {
+	// each session has its own client, the SDK otherwise loads AWS_CA_BUNDLE into the transport of
+	// http.DefaultClient while other sessions use it
+	cfg := aws.NewConfig().WithHTTPClient(&http.Client{})
+	if region != "" {
+		cfg = cfg.WithRegion(region)
+	}
+	if endpoint != "" {
+		cfg = cfg.WithEndpoint(endpoint)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *cfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+	if settings := GetCredentialSettings(); settings.AssumesRole() {
+		// the credentials are cached by the session until they expire
+		sess.Config.Credentials = stscreds.NewCredentials(sess, settings.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			if settings.ExternalID != "" {
+				p.ExternalID = aws.String(settings.ExternalID)
+			}
+			if settings.RoleSessionName != "" {
+				p.RoleSessionName = settings.RoleSessionName
+			}
+			if settings.CredentialDuration != 0 {
+				p.Duration = settings.CredentialDuration
+			}
+		})
+	}
+	return sess, nil
+}
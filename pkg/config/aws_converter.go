@@ -0,0 +1,205 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+)
+
+const (
+	awsKey        = "aws"
+	awsConfigKey  = serviceKey + confmap.KeyDelimiter + awsKey
+	roleARNKey    = "role_arn"
+	assumeRoleKey = "assume_role"
+	sigv4authType = "sigv4auth"
+)
+
+// roleARNComponents are the types of the components assuming the role set by their role_arn, by kind.
+var roleARNComponents = map[string]map[string]bool{
+	exportersKey:  {"awsemf": true, "awsxray": true, "awscloudwatchlogs": true},
+	extensionsKey: {"awsproxy": true},
+}
+
+var (
+	defaultCredentialsMu sync.Mutex
+	// defaultCredentialSettings are the settings of the extracfg file, see SetDefaultCredentialSettings.
+	defaultCredentialSettings awssession.CredentialSettings
+)
+
+// SetDefaultCredentialSettings sets the AWS credential settings overridden by the service::aws block
+// of the configuration, e.g. from the extracfg file. They apply right away, so that the s3 provider
+// reads the configuration with them.
+func SetDefaultCredentialSettings(settings awssession.CredentialSettings) error {
+	if err := awssession.SetCredentialSettings(settings); err != nil {
+		return err
+	}
+	defaultCredentialsMu.Lock()
+	defer defaultCredentialsMu.Unlock()
+	defaultCredentialSettings = settings
+	return nil
+}
+
+func getDefaultCredentialSettings() awssession.CredentialSettings {
+	defaultCredentialsMu.Lock()
+	defer defaultCredentialsMu.Unlock()
+	return defaultCredentialSettings
+}
+
+// awsConverter removes the service::aws block from the configuration, as it is unknown to the
+// collector, and keeps the AWS credential settings shared by the AWS components. They are applied
+// once the configuration is accepted, see reloadingConfigProvider.use. For example:
+//
+//	service:
+//	  aws:
+//	    role_arn: arn:aws:iam::123456789012:role/adot-collector
+//	    role_session_name: adot-collector
+//	    sts_regional_endpoints: regional
+//
+// The settings left out keep the values of the extracfg file. The role is assumed by the sessions of
+// aws-otel-collector and set as the default role_arn of the awsemf, awsxray and awscloudwatchlogs
+// exporters, of the awsproxy extension and as the default assume_role of the sigv4auth extension.
+// The components setting their own role, including an empty one, keep it.
+type awsConverter struct {
+	mu sync.Mutex
+	// settings are the settings of the last converted configuration
+	settings awssession.CredentialSettings
+}
+
+var _ confmap.Converter = (*awsConverter)(nil)
+
+func (c *awsConverter) Convert(_ context.Context, conf *confmap.Conf) error {
+	settings := getDefaultCredentialSettings()
+	if conf.IsSet(awsConfigKey) {
+		var block awssession.CredentialSettings
+		sub, err := conf.Sub(awsConfigKey)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", awsConfigKey, err)
+		}
+		if err = sub.Unmarshal(&block); err != nil {
+			return fmt.Errorf("invalid %s: %w", awsConfigKey, err)
+		}
+		if err = block.Validate(); err != nil {
+			return fmt.Errorf("invalid %s: %w", awsConfigKey, err)
+		}
+		settings = settings.Override(block)
+		removeKeys(conf, serviceKey, awsKey)
+	}
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("invalid %s: %w", awsConfigKey, err)
+	}
+	if settings.AssumesRole() {
+		raw := conf.ToStringMap()
+		if err := setDefaultRoles(raw, settings); err != nil {
+			return fmt.Errorf("invalid %s: %w", awsConfigKey, err)
+		}
+		*conf = *confmap.NewFromStringMap(raw)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settings = settings
+	return nil
+}
+
+// lastSettings returns the settings of the last converted configuration.
+func (c *awsConverter) lastSettings() awssession.CredentialSettings {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.settings
+}
+
+// applyCredentialSettings applies the settings of the configuration the collector runs.
+func applyCredentialSettings(settings awssession.CredentialSettings) {
+	if err := awssession.SetCredentialSettings(settings); err != nil {
+		log.Printf("E! failed to apply the AWS credential settings: %v\n", err)
+	}
+}
+
+// setDefaultRoles sets the role of the settings on the AWS components which do not set one. It fails
+// when the settings have an external ID or a credential duration, which the components do not
+// support, rather than letting them assume the role without.
+func setDefaultRoles(raw map[string]any, settings awssession.CredentialSettings) error {
+	var defaulted []string
+	for _, kind := range []string{exportersKey, extensionsKey} {
+		components, _ := raw[kind].(map[string]any)
+		for id, value := range components {
+			typ := componentType(id)
+			if !roleARNComponents[kind][typ] && (kind != extensionsKey || typ != sigv4authType) {
+				continue
+			}
+			if value == nil {
+				value = map[string]any{}
+			}
+			component, ok := value.(map[string]any)
+			if !ok {
+				// left to the validation of the component
+				continue
+			}
+			var set bool
+			if typ == sigv4authType {
+				set = setDefaultAssumeRole(component, settings)
+			} else if _, ok = component[roleARNKey]; !ok {
+				component[roleARNKey] = settings.RoleARN
+				set = true
+			}
+			if !set {
+				continue
+			}
+			components[id] = component
+			defaulted = append(defaulted, kind+confmap.KeyDelimiter+id)
+		}
+	}
+	if len(defaulted) > 0 && (settings.ExternalID != "" || settings.CredentialDuration != 0) {
+		sort.Strings(defaulted)
+		return fmt.Errorf("external_id and credential_duration are only supported by the configuration providers,"+
+			" %v would assume %s without them, set their own role instead", defaulted, settings.RoleARN)
+	}
+	return nil
+}
+
+// componentType returns the type of a component id, e.g. awsemf for awsemf/app.
+func componentType(id string) string {
+	typ, _, _ := strings.Cut(id, "/")
+	return typ
+}
+
+// setDefaultAssumeRole sets the assume_role of a sigv4auth extension which does not set its arn.
+func setDefaultAssumeRole(component map[string]any, settings awssession.CredentialSettings) bool {
+	if component[assumeRoleKey] == nil {
+		component[assumeRoleKey] = map[string]any{}
+	}
+	assumeRole, ok := component[assumeRoleKey].(map[string]any)
+	if !ok {
+		return false
+	}
+	if _, ok = assumeRole["arn"]; ok {
+		return false
+	}
+	assumeRole["arn"] = settings.RoleARN
+	if _, ok = assumeRole["session_name"]; !ok && settings.RoleSessionName != "" {
+		assumeRole["session_name"] = settings.RoleSessionName
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+)
+
+const (
+	testRoleARN  = "arn:aws:iam::123456789012:role/adot-collector"
+	otherRoleARN = "arn:aws:iam::123456789012:role/other"
+)
+
+func TestAWSConverter(t *testing.T) {
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv("AWS_STS_REGIONAL_ENDPOINTS", "")
+
+	tests := []struct {
+		name             string
+		defaults         awssession.CredentialSettings
+		input            map[string]any
+		expected         map[string]any
+		expectedSettings awssession.CredentialSettings
+		expectedErr      string
+	}{
+		{
+			name:     "no_settings",
+			input:    map[string]any{"exporters": map[string]any{"awsemf": nil}},
+			expected: map[string]any{"exporters": map[string]any{"awsemf": nil}},
+		},
+		{
+			name:     "extracfg_role",
+			defaults: awssession.CredentialSettings{RoleARN: testRoleARN, RoleSessionName: "adot"},
+			input: map[string]any{
+				"exporters": map[string]any{
+					"awsemf":              nil,
+					"awsxray":             map[string]any{"region": "us-west-2"},
+					"awscloudwatchlogs/a": map[string]any{"role_arn": otherRoleARN},
+					"awscloudwatchlogs/b": map[string]any{"role_arn": ""},
+					"otlp":                map[string]any{"endpoint": "localhost:4317"},
+				},
+				"extensions": map[string]any{
+					"awsproxy":  nil,
+					"sigv4auth": map[string]any{"region": "us-west-2"},
+					"sigv4auth/own": map[string]any{
+						"assume_role": map[string]any{"arn": otherRoleARN},
+					},
+				},
+			},
+			expected: map[string]any{
+				"exporters": map[string]any{
+					"awsemf":              map[string]any{"role_arn": testRoleARN},
+					"awsxray":             map[string]any{"region": "us-west-2", "role_arn": testRoleARN},
+					"awscloudwatchlogs/a": map[string]any{"role_arn": otherRoleARN},
+					"awscloudwatchlogs/b": map[string]any{"role_arn": ""},
+					"otlp":                map[string]any{"endpoint": "localhost:4317"},
+				},
+				"extensions": map[string]any{
+					"awsproxy": map[string]any{"role_arn": testRoleARN},
+					"sigv4auth": map[string]any{
+						"region":      "us-west-2",
+						"assume_role": map[string]any{"arn": testRoleARN, "session_name": "adot"},
+					},
+					"sigv4auth/own": map[string]any{
+						"assume_role": map[string]any{"arn": otherRoleARN},
+					},
+				},
+			},
+			expectedSettings: awssession.CredentialSettings{RoleARN: testRoleARN, RoleSessionName: "adot"},
+		},
+		{
+			name:     "service_block_overrides_extracfg",
+			defaults: awssession.CredentialSettings{RoleARN: testRoleARN, ExternalID: "adot", STSRegionalEndpoints: "legacy"},
+			input: map[string]any{
+				"exporters": map[string]any{"awsemf": nil},
+				"service": map[string]any{
+					"aws":       map[string]any{"role_arn": otherRoleARN, "role_session_name": "other"},
+					"pipelines": map[string]any{},
+				},
+			},
+			expected: map[string]any{
+				"exporters": map[string]any{"awsemf": map[string]any{"role_arn": otherRoleARN}},
+				"service":   map[string]any{"pipelines": map[string]any{}},
+			},
+			expectedSettings: awssession.CredentialSettings{
+				RoleARN:              otherRoleARN,
+				RoleSessionName:      "other",
+				STSRegionalEndpoints: "legacy",
+			},
+		},
+		{
+			name:     "provider_only_settings",
+			defaults: awssession.CredentialSettings{RoleARN: testRoleARN, ExternalID: "adot", CredentialDuration: time.Hour},
+			input: map[string]any{
+				"exporters": map[string]any{"awsemf": map[string]any{"role_arn": ""}, "otlp": nil},
+			},
+			expected: map[string]any{
+				"exporters": map[string]any{"awsemf": map[string]any{"role_arn": ""}, "otlp": nil},
+			},
+			expectedSettings: awssession.CredentialSettings{RoleARN: testRoleARN, ExternalID: "adot", CredentialDuration: time.Hour},
+		},
+		{
+			name:     "unsupported_component_settings",
+			defaults: awssession.CredentialSettings{RoleARN: testRoleARN, ExternalID: "adot"},
+			input: map[string]any{
+				"exporters":  map[string]any{"awsemf": nil, "awsxray": map[string]any{"role_arn": otherRoleARN}},
+				"extensions": map[string]any{"sigv4auth": nil},
+			},
+			expectedErr: "[exporters::awsemf extensions::sigv4auth] would assume " + testRoleARN + " without them",
+		},
+		{
+			name: "web_identity",
+			input: map[string]any{
+				"exporters": map[string]any{"awsemf": nil},
+				"service": map[string]any{"aws": map[string]any{
+					"role_arn":                testRoleARN,
+					"web_identity_token_file": "/var/run/secrets/token",
+				}},
+			},
+			// the default credentials are the ones of the role
+			expected: map[string]any{
+				"exporters": map[string]any{"awsemf": nil},
+				"service":   map[string]any{},
+			},
+			expectedSettings: awssession.CredentialSettings{RoleARN: testRoleARN, WebIdentityTokenFile: "/var/run/secrets/token"},
+		},
+		{
+			name: "unknown_key",
+			input: map[string]any{"service": map[string]any{"aws": map[string]any{
+				"role": testRoleARN,
+			}}},
+			expectedErr: "invalid service::aws",
+		},
+		{
+			name: "invalid_settings",
+			input: map[string]any{"service": map[string]any{"aws": map[string]any{
+				"external_id": "adot",
+			}}},
+			expectedErr: "external_id requires role_arn",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, SetDefaultCredentialSettings(tt.defaults))
+			t.Cleanup(func() {
+				require.NoError(t, SetDefaultCredentialSettings(awssession.CredentialSettings{}))
+			})
+
+			conf := confmap.NewFromStringMap(tt.input)
+			converter := &awsConverter{}
+			err := converter.Convert(context.Background(), conf)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, conf.ToStringMap())
+			assert.Equal(t, tt.expectedSettings, converter.lastSettings())
+			// the settings are applied once the configuration is accepted
+			assert.Equal(t, tt.defaults, awssession.GetCredentialSettings())
+		})
+	}
+}
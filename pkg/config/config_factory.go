@@ -17,30 +17,75 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
-	"github.com/open-telemetry/opentelemetry-collector-contrib/confmap/provider/s3provider"
 	"go.opentelemetry.io/collector/confmap"
-	"go.opentelemetry.io/collector/confmap/converter/expandconverter"
 	"go.opentelemetry.io/collector/confmap/provider/envprovider"
 	"go.opentelemetry.io/collector/confmap/provider/fileprovider"
 	"go.opentelemetry.io/collector/confmap/provider/httpprovider"
 	"go.opentelemetry.io/collector/confmap/provider/httpsprovider"
 	"go.opentelemetry.io/collector/confmap/provider/yamlprovider"
 	"go.opentelemetry.io/collector/otelcol"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/awsprovider"
+	"github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/builtinprovider"
+	"github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/dirprovider"
+	"github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/s3provider"
+	"github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/secretsmanagerprovider"
+	"github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/ssmprovider"
+	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
+	"github.com/aws-observability/aws-otel-collector/pkg/logger"
 )
 
 const (
-	envKey = "AOT_CONFIG_CONTENT"
+	envKey          = "AOT_CONFIG_CONTENT"
+	envMergeModeKey = "AOT_CONFIG_MERGE_MODE"
+
+	// mergeModeReplace uses the env var content instead of the --config locations and --set flags
+	mergeModeReplace = "replace"
+	// mergeModeMerge layers the env var content between the --config locations and the --set flags
+	mergeModeMerge = "merge"
 )
 
+// indexedEnvKeyRegexp matches the indexed variables, e.g. AOT_CONFIG_CONTENT_1, which are layered
+// on top of AOT_CONFIG_CONTENT in the order of their index.
+var indexedEnvKeyRegexp = regexp.MustCompile(`^` + envKey + `_(\d+)$`)
+
+// defaultConfigs are the built-in configurations used for each platform when no configuration is
+// given, the other platforms use the one of EC2.
+var defaultConfigs = map[extraconfig.Platform]string{
+	extraconfig.PlatformEC2:        "builtin:ec2/ec2-default-config",
+	extraconfig.PlatformECS:        "builtin:ecs/ecs-default-config",
+	extraconfig.PlatformECSFargate: "builtin:ecs/ecs-default-config",
+	extraconfig.PlatformEKS:        "builtin:eks/eks-default-config",
+	extraconfig.PlatformAppRunner:  "builtin:apprunner/apprunner-default-config",
+}
+
+// GetConfigProvider returns the config provider for the given flags, it panics if the provider
+// cannot be created.
 func GetConfigProvider(flags *flag.FlagSet) otelcol.ConfigProvider {
-	// aws-otel-collector supports loading yaml config from Env Var
-	// including SSM parameter store for ECS use case
-	loc := getConfigFlag(flags)
-	if _, ok := os.LookupEnv(envKey); ok {
-		loc = []string{"env:" + envKey}
+	configProvider, err := NewConfigProvider(flags)
+	if err != nil {
+		log.Panicf("Err on creating Config Provider: %v\n", err)
+	}
+	return configProvider
+}
+
+// NewConfigProvider creates the config provider for the given flags.
+func NewConfigProvider(flags *flag.FlagSet) (otelcol.ConfigProvider, error) {
+	// aws-otel-collector supports loading yaml config from Env Vars
+	// including SSM parameter store for ECS use case, the config can
+	// also be read directly with the ssm and secretsmanager providers,
+	// see configLocations for how the env vars and flags are combined
+	loc, err := configLocations(flags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config provider: %w", err)
 	}
 
 	// generate the MapProviders for the Config Provider Settings
@@ -51,28 +96,133 @@ func GetConfigProvider(flags *flag.FlagSet) otelcol.ConfigProvider {
 		httpprovider.NewWithSettings(confmap.ProviderSettings{}),
 		httpsprovider.NewWithSettings(confmap.ProviderSettings{}),
 		s3provider.New(),
+		ssmprovider.New(),
+		secretsmanagerprovider.New(),
+		builtinprovider.New(),
+		awsprovider.New(),
+		dirprovider.New(),
 	}
 
+	watch, pollInterval := getWatchFlags(flags)
+	strictEnv := getStrictEnvFlag(flags)
 	mapProviders := make(map[string]confmap.Provider, len(providers))
 	for _, provider := range providers {
+		provider = withEnvExpansion(provider, strictEnv)
+		if watch {
+			provider = newWatchingProvider(provider, pollInterval)
+		}
 		mapProviders[provider.Scheme()] = provider
 	}
 
 	// create Config Provider Settings
+	logs, aws, outbound := &logsConverter{}, &awsConverter{}, &outboundConverter{}
 	settings := otelcol.ConfigProviderSettings{
 		ResolverSettings: confmap.ResolverSettings{
-			URIs:       loc,
-			Providers:  mapProviders,
-			Converters: []confmap.Converter{expandconverter.New(confmap.ConverterSettings{})},
+			URIs:      loc,
+			Providers: mapProviders,
+			// the env vars are expanded by the providers, the log, AWS and outbound settings are converted
+			// once they are, see reloadingConfigProvider.use
+			Converters: []confmap.Converter{envConverter{}, setConverter{}, logs, aws, outbound},
 		},
 	}
 
 	// get New config Provider
-	config_provider, err := otelcol.NewConfigProvider(settings)
-
+	configProvider, err := otelcol.NewConfigProvider(settings)
 	if err != nil {
-		log.Panicf("Err on creating Config Provider: %v\n", err)
+		return nil, fmt.Errorf("failed to create config provider: %w", err)
 	}
 
-	return config_provider
+	return newReloadingConfigProvider(configProvider, logs, aws, outbound), nil
+}
+
+// configLocations returns the config locations in the order they are merged, later ones taking
+// precedence: the --config and --config-dir locations, AOT_CONFIG_CONTENT, AOT_CONFIG_CONTENT_1..N,
+// the log level of the extracfg file and the --set flags.
+// In the default replace mode, the env vars take the place of the --config locations and --set flags.
+// Without any --config location or env var, the built-in default of the detected platform is used.
+func configLocations(flags *flag.FlagSet) ([]string, error) {
+	cfv := flags.Lookup(configFlag).Value.(*configFlagValue)
+	envLocations := envConfigLocations()
+	if len(envLocations) == 0 {
+		if len(cfv.values) == 0 {
+			return withLogLevel([]string{defaultConfigLocation()}, cfv.sets), nil
+		}
+		return withLogLevel(cfv.values, cfv.sets), nil
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv(envMergeModeKey)))
+	switch mode {
+	case "", mergeModeReplace:
+		if len(cfv.values) > 0 || len(cfv.sets) > 0 {
+			log.Printf("W! %s is set, ignoring the --config and --set flags, set %s=%s to merge them instead\n",
+				envKey, envMergeModeKey, mergeModeMerge)
+		}
+		return withLogLevel(envLocations, nil), nil
+	case mergeModeMerge:
+		loc := make([]string, 0, len(cfv.values)+len(envLocations))
+		loc = append(loc, cfv.values...)
+		loc = append(loc, envLocations...)
+		return withLogLevel(loc, cfv.sets), nil
+	default:
+		return nil, fmt.Errorf("invalid %s %q, must be %q or %q", envMergeModeKey, mode, mergeModeReplace, mergeModeMerge)
+	}
+}
+
+// withLogLevel returns the config locations followed by the log level set with logger.SetLogLevel,
+// e.g. from the extracfg file, and the --set flags.
+func withLogLevel(configs []string, sets []string) []string {
+	loc := append([]string(nil), configs...)
+	if level := logger.GetLogLevel(); level != "" {
+		loc = append(loc, "yaml:"+logLevelConfigKey+": "+level)
+	}
+	return append(loc, sets...)
+}
+
+// defaultConfigLocation returns the built-in default configuration of the detected platform.
+func defaultConfigLocation() string {
+	platform := extraconfig.DetectPlatform()
+	loc, ok := defaultConfigs[platform]
+	if !ok {
+		loc = defaultConfigs[extraconfig.PlatformEC2]
+	}
+	log.Printf("I! no configuration given, using the default configuration of %s: %s\n", platform, loc)
+	return loc
+}
+
+// envConfigLocations returns the env locations of AOT_CONFIG_CONTENT followed by the indexed
+// variables sorted by index.
+func envConfigLocations() []string {
+	var loc []string
+	if _, ok := os.LookupEnv(envKey); ok {
+		loc = append(loc, "env:"+envKey)
+	}
+
+	type indexedKey struct {
+		key   string
+		index uint64
+	}
+	var indexed []indexedKey
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		matches := indexedEnvKeyRegexp.FindStringSubmatch(key)
+		if matches == nil {
+			continue
+		}
+		index, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			log.Printf("W! ignoring %s, the index is out of range\n", key)
+			continue
+		}
+		indexed = append(indexed, indexedKey{key: key, index: index})
+	}
+	sort.Slice(indexed, func(i, j int) bool {
+		if indexed[i].index != indexed[j].index {
+			return indexed[i].index < indexed[j].index
+		}
+		return indexed[i].key < indexed[j].key
+	})
+	for _, k := range indexed {
+		loc = append(loc, "env:"+k.key)
+	}
+	return loc
 }
@@ -20,17 +20,23 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"go.opentelemetry.io/collector/featuregate"
 	"go.opentelemetry.io/collector/otelcol"
+	"go.opentelemetry.io/collector/processor/batchprocessor"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/pprofextension"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/receiver/awsxrayreceiver"
 	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/aws-observability/aws-otel-collector/pkg/defaultcomponents"
+	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
+	"github.com/aws-observability/aws-otel-collector/pkg/logger"
 )
 
 func getValidTestConfigPath() string {
@@ -125,3 +131,215 @@ func TestGetMapProviderContainer(t *testing.T) {
 	require.NotNil(t, cfg.Receivers[component.NewID("otlp")])
 	require.NotNil(t, cfg.Exporters[component.NewID("awsemf")])
 }
+
+func TestConfigLocations(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		logLevel string
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "no_env",
+			args:     []string{"--config=file:config.yaml", "--set=processors.batch.timeout=2s"},
+			expected: []string{"file:config.yaml", "yaml:processors::batch::timeout: 2s"},
+		},
+		{
+			name:     "replace_by_default",
+			args:     []string{"--config=file:config.yaml", "--set=processors.batch.timeout=2s"},
+			env:      map[string]string{envKey: "receivers:"},
+			expected: []string{"env:" + envKey},
+		},
+		{
+			name: "replace_with_indexed",
+			args: []string{"--config=file:config.yaml"},
+			env: map[string]string{
+				envKey:          "receivers:",
+				envKey + "_10":  "processors:",
+				envKey + "_2":   "exporters:",
+				envMergeModeKey: "replace",
+			},
+			expected: []string{"env:" + envKey, "env:" + envKey + "_2", "env:" + envKey + "_10"},
+		},
+		{
+			name:     "indexed_only",
+			env:      map[string]string{envKey + "_1": "receivers:"},
+			expected: []string{"env:" + envKey + "_1"},
+		},
+		{
+			name: "merge",
+			args: []string{"--config=file:config.yaml", "--set=processors.batch.timeout=2s"},
+			env: map[string]string{
+				envKey:          "receivers:",
+				envKey + "_1":   "exporters:",
+				envMergeModeKey: "Merge",
+			},
+			expected: []string{"file:config.yaml", "env:" + envKey, "env:" + envKey + "_1", "yaml:processors::batch::timeout: 2s"},
+		},
+		{
+			name: "ignore_non_numeric_index",
+			env: map[string]string{
+				envKey + "_A":   "receivers:",
+				envKey + "_1":   "exporters:",
+				envMergeModeKey: "merge",
+			},
+			expected: []string{"env:" + envKey + "_1"},
+		},
+		{
+			name:     "builtin",
+			args:     []string{"--config=builtin:ecs/ecs-amp-xray"},
+			expected: []string{"builtin:ecs/ecs-amp-xray"},
+		},
+		{
+			name:     "config_dir",
+			args:     []string{"--config-dir=/opt/aws/aws-otel-collector/etc/conf.d", "--set=processors.batch.timeout=2s"},
+			expected: []string{"dir:/opt/aws/aws-otel-collector/etc/conf.d", "yaml:processors::batch::timeout: 2s"},
+		},
+		{
+			name:     "default_ec2",
+			env:      map[string]string{extraconfig.EnvKeyPlatform: "ec2"},
+			expected: []string{"builtin:ec2/ec2-default-config"},
+		},
+		{
+			name:     "default_ecs_with_set",
+			args:     []string{"--set=processors.batch.timeout=2s"},
+			env:      map[string]string{extraconfig.EnvKeyPlatform: "ecs"},
+			expected: []string{"builtin:ecs/ecs-default-config", "yaml:processors::batch::timeout: 2s"},
+		},
+		{
+			name:     "default_apprunner",
+			env:      map[string]string{extraconfig.EnvKeyPlatform: "apprunner"},
+			expected: []string{"builtin:apprunner/apprunner-default-config"},
+		},
+		{
+			name:     "default_eks",
+			env:      map[string]string{extraconfig.EnvKeyPlatform: "eks"},
+			expected: []string{"builtin:eks/eks-default-config"},
+		},
+		{
+			name:     "log_level",
+			args:     []string{"--config=file:config.yaml", "--set=service.telemetry.logs.level=warn"},
+			logLevel: "DEBUG",
+			expected: []string{"file:config.yaml", "yaml:service::telemetry::logs::level: DEBUG", "yaml:service::telemetry::logs::level: warn"},
+		},
+		{
+			name:     "log_level_keeps_default",
+			env:      map[string]string{extraconfig.EnvKeyPlatform: "ecs"},
+			logLevel: "DEBUG",
+			expected: []string{"builtin:ecs/ecs-default-config", "yaml:service::telemetry::logs::level: DEBUG"},
+		},
+		{
+			name:     "log_level_with_env",
+			args:     []string{"--config=file:config.yaml"},
+			env:      map[string]string{envKey: "receivers:"},
+			logLevel: "DEBUG",
+			expected: []string{"env:" + envKey, "yaml:service::telemetry::logs::level: DEBUG"},
+		},
+		{
+			name: "invalid_mode",
+			env: map[string]string{
+				envKey:          "receivers:",
+				envMergeModeKey: "append",
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			logger.SetLogLevel(tt.logLevel)
+			t.Cleanup(func() { logger.SetLogLevel("") })
+			flgs := Flags(featuregate.NewRegistry())
+			require.NoError(t, flgs.Parse(tt.args))
+
+			loc, err := configLocations(flgs)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, loc)
+		})
+	}
+}
+
+// TestDefaultConfigWithLogLevel checks that the log level of the extracfg file does not replace the
+// built-in default configuration.
+func TestDefaultConfigWithLogLevel(t *testing.T) {
+	t.Setenv(extraconfig.EnvKeyPlatform, "ec2")
+	logger.SetLogLevel("DEBUG")
+	t.Cleanup(func() { logger.SetLogLevel("") })
+
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+	provider := GetConfigProvider(Flags(featuregate.NewRegistry()))
+	cfg, err := provider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+	assert.NotEmpty(t, cfg.Service.Pipelines)
+	assert.Equal(t, zapcore.DebugLevel, cfg.Service.Telemetry.Logs.Level)
+}
+
+func TestDefaultConfigs(t *testing.T) {
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+	for platform := range defaultConfigs {
+		t.Run(string(platform), func(t *testing.T) {
+			t.Setenv(extraconfig.EnvKeyPlatform, string(platform))
+			provider := GetConfigProvider(Flags(featuregate.NewRegistry()))
+			cfg, err := provider.Get(context.Background(), factories)
+			require.NoError(t, err)
+			assert.NoError(t, cfg.Validate())
+		})
+	}
+}
+
+func TestMergeEnvConfig(t *testing.T) {
+	t.Setenv(envMergeModeKey, mergeModeMerge)
+	t.Setenv(envKey, "processors:\n  batch/traces:\n    timeout: 3s\n    send_batch_size: 100")
+	t.Setenv(envKey+"_1", "processors:\n  batch/traces:\n    timeout: 4s")
+
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+	flgs := Flags(featuregate.NewRegistry())
+	require.NoError(t, flgs.Parse([]string{
+		"--config=" + getValidTestConfigPath(),
+		"--set=processors.batch/traces.send_batch_max_size=200",
+	}))
+	provider, err := NewConfigProvider(flgs)
+	require.NoError(t, err)
+
+	cfg, err := provider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	batch := cfg.Processors[component.NewIDWithName("batch", "traces")].(*batchprocessor.Config)
+	assert.Equal(t, 4*time.Second, batch.Timeout)
+	assert.Equal(t, uint32(100), batch.SendBatchSize)
+	assert.Equal(t, uint32(200), batch.SendBatchMaxSize)
+	// the file config is kept
+	require.NotNil(t, cfg.Receivers[component.NewID("awsxray")])
+}
+
+func TestSetEditsConfig(t *testing.T) {
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+	flgs := Flags(featuregate.NewRegistry())
+	require.NoError(t, flgs.Parse([]string{
+		"--config=" + getValidTestConfigPath(),
+		"--set=service.pipelines.traces.receivers[1]=otlp",
+		"--unset=service.pipelines.traces.receivers[1]",
+		"--unset=receivers.awsxray",
+	}))
+	provider, err := NewConfigProvider(flgs)
+	require.NoError(t, err)
+
+	cfg, err := provider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Nil(t, cfg.Receivers[component.NewID("awsxray")])
+	assert.Equal(t, []component.ID{component.NewID("otlp")}, cfg.Service.Pipelines[component.NewID("traces")].Receivers)
+}
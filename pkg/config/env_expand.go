@@ -0,0 +1,311 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/multierr"
+)
+
+// envExpandingProvider expands the environment variable references of the configuration it
+// retrieves, before the resolver expands the ${scheme:...} uris. This replaces the upstream
+// expandconverter, which runs after the resolver and therefore cannot support the ${VAR:-default}
+// and ${VAR:?message} syntax, the resolver taking VAR for a scheme. The syntax is:
+//
+//	$VAR or ${VAR}      the value of VAR
+//	${VAR:-default}     the value of VAR, or default when VAR is unset or empty
+//	${VAR:?message}     the value of VAR, or an error with the message when VAR is unset or empty
+//	$$                  a literal $, unescaped by the envConverter
+//
+// An undefined variable expands to an empty string, or is an error in strict mode. All the
+// undefined variables of a config location are reported together.
+type envExpandingProvider struct {
+	confmap.Provider
+	strict    bool
+	lookupEnv func(string) (string, bool)
+}
+
+// secretSchemes are the schemes of the secret stores, whose values are used as is, e.g. a password
+// with a $.
+var secretSchemes = map[string]bool{
+	"ssm":            true,
+	"secretsmanager": true,
+}
+
+// withEnvExpansion returns the provider expanding the environment variable references of the
+// configurations retrieved by provider, or escaping their $ for the secret stores.
+func withEnvExpansion(provider confmap.Provider, strict bool) confmap.Provider {
+	if secretSchemes[provider.Scheme()] {
+		return &literalProvider{Provider: provider}
+	}
+	return newEnvExpandingProvider(provider, strict)
+}
+
+func newEnvExpandingProvider(provider confmap.Provider, strict bool) *envExpandingProvider {
+	return &envExpandingProvider{Provider: provider, strict: strict, lookupEnv: os.LookupEnv}
+}
+
+func (p *envExpandingProvider) Retrieve(ctx context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	ret, err := p.Provider.Retrieve(ctx, uri, watcher)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ret.AsRaw()
+	if err != nil {
+		return nil, err
+	}
+	e := &envExpansion{strict: p.strict, lookupEnv: p.lookupEnv, undefined: map[string]bool{}}
+	expanded := e.expandValue(raw)
+	if err = e.err(); err != nil {
+		_ = ret.Close(ctx)
+		return nil, fmt.Errorf("failed to expand the environment variables of %q: %w", uri, err)
+	}
+	return confmap.NewRetrieved(expanded, confmap.WithRetrievedClose(ret.Close))
+}
+
+// envExpansion is the expansion of a single config location.
+type envExpansion struct {
+	strict    bool
+	lookupEnv func(string) (string, bool)
+	undefined map[string]bool
+	required  []error
+}
+
+func (e *envExpansion) err() error {
+	errs := e.required
+	if e.strict && len(e.undefined) > 0 {
+		names := make([]string, 0, len(e.undefined))
+		for name := range e.undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		errs = append(errs, fmt.Errorf("undefined environment variables: %s", strings.Join(names, ", ")))
+	}
+	return multierr.Combine(errs...)
+}
+
+func (e *envExpansion) expandValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return e.expand(v)
+	case []any:
+		nslice := make([]any, 0, len(v))
+		for _, vint := range v {
+			nslice = append(nslice, e.expandValue(vint))
+		}
+		return nslice
+	case map[string]any:
+		nmap := make(map[string]any, len(v))
+		for mk, mv := range v {
+			nmap[mk] = e.expandValue(mv)
+		}
+		return nmap
+	default:
+		return v
+	}
+}
+
+// expand follows os.Expand for the $VAR and ${VAR} references, so that the configurations keep
+// their meaning, and leaves the ${scheme:...} uris to the resolver.
+func (e *envExpansion) expand(s string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+	var buf strings.Builder
+	i := 0
+	for j := 0; j < len(s); j++ {
+		if s[j] != '$' || j+1 >= len(s) {
+			continue
+		}
+		buf.WriteString(s[i:j])
+		switch {
+		case s[j+1] == '$':
+			// unescaped by the envConverter once all the values are expanded
+			buf.WriteString("$$")
+			j++
+		case s[j+1] == '{':
+			end := closingBrace(s, j+1)
+			if end == -1 {
+				// invalid syntax, left as is
+				buf.WriteString(s[j:])
+				return buf.String()
+			}
+			buf.WriteString(e.expandBraces(s[j:end+1], s[j+2:end]))
+			j = end
+		default:
+			name, w := shellName(s[j+1:])
+			if name == "" && w == 0 {
+				buf.WriteByte('$')
+			} else if name != "" {
+				buf.WriteString(e.lookup(name))
+			}
+			j += w
+		}
+		i = j + 1
+	}
+	buf.WriteString(s[i:])
+	return buf.String()
+}
+
+// expandBraces expands the content of ${...}.
+func (e *envExpansion) expandBraces(ref, content string) string {
+	name, rest, hasColon := strings.Cut(content, ":")
+	switch {
+	case !hasColon:
+		if content == "" {
+			// os.Expand eats the bad syntax
+			return ""
+		}
+		return e.lookup(content)
+	case strings.HasPrefix(rest, "-"):
+		if value, ok := e.lookupEnv(name); ok && value != "" {
+			return escapeDollars(value)
+		}
+		return e.expand(rest[1:])
+	case strings.HasPrefix(rest, "?"):
+		if value, ok := e.lookupEnv(name); ok && value != "" {
+			return escapeDollars(value)
+		}
+		message := strings.TrimSpace(rest[1:])
+		if message == "" {
+			message = "required but not set"
+		}
+		e.required = append(e.required, fmt.Errorf("%s: %s", name, message))
+		return ""
+	default:
+		// a uri like ${env:VAR}, expanded by the resolver
+		return ref
+	}
+}
+
+func (e *envExpansion) lookup(name string) string {
+	value, ok := e.lookupEnv(name)
+	if !ok {
+		e.undefined[name] = true
+	}
+	return escapeDollars(value)
+}
+
+// escapeDollars escapes the values of the variables, which are not expanded again.
+func escapeDollars(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+// closingBrace returns the index of the brace closing the one at open, taking nested braces into
+// account, or -1.
+func closingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// shellName returns the name of a $VAR reference and the number of bytes it takes, like the
+// unexported function of os.Expand: a special shell variable or a digit is a single character.
+func shellName(s string) (string, int) {
+	if isShellSpecialVar(s[0]) {
+		return s[0:1], 1
+	}
+	var i int
+	for i = 0; i < len(s) && isAlphaNum(s[i]); i++ {
+	}
+	return s[:i], i
+}
+
+func isShellSpecialVar(c uint8) bool {
+	switch c {
+	case '*', '#', '$', '@', '!', '?', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	}
+	return false
+}
+
+func isAlphaNum(c uint8) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// literalProvider escapes the $ of the configuration it retrieves instead of expanding the
+// environment variable references, so that the envConverter restores the values as retrieved.
+type literalProvider struct {
+	confmap.Provider
+}
+
+func (p *literalProvider) Retrieve(ctx context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	ret, err := p.Provider.Retrieve(ctx, uri, watcher)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ret.AsRaw()
+	if err != nil {
+		return nil, err
+	}
+	return confmap.NewRetrieved(mapStrings(raw, escapeDollars), confmap.WithRetrievedClose(ret.Close))
+}
+
+// envConverter unescapes $$ into $ once the configuration is resolved, like the upstream
+// expandconverter does.
+type envConverter struct{}
+
+var _ confmap.Converter = envConverter{}
+
+func (envConverter) Convert(_ context.Context, conf *confmap.Conf) error {
+	out := make(map[string]any)
+	for _, k := range conf.AllKeys() {
+		out[k] = mapStrings(conf.Get(k), unescapeDollars)
+	}
+	return conf.Merge(confmap.NewFromStringMap(out))
+}
+
+func unescapeDollars(value string) string {
+	return strings.ReplaceAll(value, "$$", "$")
+}
+
+// mapStrings returns the value with fn applied to the strings it holds.
+func mapStrings(value any, fn func(string) string) any {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case []any:
+		nslice := make([]any, 0, len(v))
+		for _, vint := range v {
+			nslice = append(nslice, mapStrings(vint, fn))
+		}
+		return nslice
+	case map[string]any:
+		nmap := make(map[string]any, len(v))
+		for mk, mv := range v {
+			nmap[mk] = mapStrings(mv, fn)
+		}
+		return nmap
+	default:
+		return v
+	}
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/envprovider"
+	"go.opentelemetry.io/collector/confmap/provider/fileprovider"
+	"go.opentelemetry.io/collector/featuregate"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/defaultcomponents"
+)
+
+func TestEnvExpansion(t *testing.T) {
+	env := map[string]string{
+		"REGION": "us-west-2",
+		"EMPTY":  "",
+		"PRICE":  "$5",
+		"1":      "one",
+	}
+	tests := []struct {
+		name              string
+		input             string
+		expected          string
+		expectedUndefined []string
+		expectedRequired  string
+	}{
+		{name: "no reference", input: "us-west-2", expected: "us-west-2"},
+		{name: "plain", input: "$REGION", expected: "us-west-2"},
+		{name: "braces", input: "region-${REGION}-1", expected: "region-us-west-2-1"},
+		{name: "escaped", input: "$$REGION and $$$REGION", expected: "$$REGION and $$us-west-2"},
+		{name: "value is not expanded again", input: "${PRICE}", expected: "$$5"},
+		{name: "special variable", input: "$1-$REGION", expected: "one-us-west-2"},
+		{name: "trailing dollar", input: "match$", expected: "match$"},
+		{name: "dollar before other character", input: "^$.*", expected: "^$.*"},
+		{name: "uri is left to the resolver", input: "${env:REGION} ${aws:ecs.cluster}", expected: "${env:REGION} ${aws:ecs.cluster}"},
+		{name: "unclosed brace", input: "${REGION", expected: "${REGION"},
+		{name: "undefined", input: "http://${HOST}:$PORT", expected: "http://:", expectedUndefined: []string{"HOST", "PORT"}},
+		{name: "empty is defined", input: "${EMPTY}", expected: ""},
+		{name: "default", input: "${HOST:-localhost}:${PORT:-4317}", expected: "localhost:4317"},
+		{name: "default of empty", input: "${EMPTY:-default}", expected: "default"},
+		{name: "default not used", input: "${REGION:-us-east-1}", expected: "us-west-2"},
+		{name: "default with reference", input: "${HOST:-${REGION}.example.com}", expected: "us-west-2.example.com"},
+		{name: "default with undefined reference", input: "${HOST:-$DOMAIN}", expected: "", expectedUndefined: []string{"DOMAIN"}},
+		{name: "required", input: "${REGION:?the region is required}", expected: "us-west-2"},
+		{name: "required missing", input: "${TOKEN:?set the API token}", expectedRequired: "TOKEN: set the API token"},
+		{name: "required empty", input: "${EMPTY:?}", expectedRequired: "EMPTY: required but not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &envExpansion{
+				lookupEnv: func(name string) (string, bool) {
+					value, ok := env[name]
+					return value, ok
+				},
+				undefined: map[string]bool{},
+			}
+			assert.Equal(t, tt.expected, e.expand(tt.input))
+			var undefined []string
+			for name := range e.undefined {
+				undefined = append(undefined, name)
+			}
+			assert.ElementsMatch(t, tt.expectedUndefined, undefined)
+			if tt.expectedRequired == "" {
+				assert.Empty(t, e.required)
+			} else {
+				require.Len(t, e.required, 1)
+				assert.EqualError(t, e.required[0], tt.expectedRequired)
+			}
+		})
+	}
+}
+
+func TestEnvExpandingProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`receivers:
+  awsxray:
+    endpoint: ${XRAY_ENDPOINT}
+exporters:
+  awsemf:
+    region: ${AWS_REGION:-us-west-2}
+    namespace: $NAMESPACE
+    log_group_name: /aws/$${group}
+    resource_to_telemetry_conversion:
+      enabled: ${ENABLED:-true}
+`), 0600))
+	t.Setenv("ENABLED", "false")
+
+	resolve := func(strict bool) (*confmap.Conf, error) {
+		resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+			URIs: []string{"file:" + path},
+			Providers: map[string]confmap.Provider{
+				"file": newEnvExpandingProvider(fileprovider.NewWithSettings(confmap.ProviderSettings{}), strict),
+				"env":  newEnvExpandingProvider(envprovider.NewWithSettings(confmap.ProviderSettings{}), strict),
+			},
+			Converters: []confmap.Converter{envConverter{}},
+		})
+		require.NoError(t, err)
+		return resolver.Resolve(context.Background())
+	}
+
+	conf, err := resolve(false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"receivers": map[string]any{"awsxray": map[string]any{"endpoint": ""}},
+		"exporters": map[string]any{"awsemf": map[string]any{
+			"region":                           "us-west-2",
+			"namespace":                        "",
+			"log_group_name":                   "/aws/${group}",
+			"resource_to_telemetry_conversion": map[string]any{"enabled": "false"},
+		}},
+	}, conf.ToStringMap())
+
+	_, err = resolve(true)
+	assert.ErrorContains(t, err, `failed to expand the environment variables of "file:`+path+`": undefined environment variables: NAMESPACE, XRAY_ENDPOINT`)
+
+	t.Setenv("XRAY_ENDPOINT", "0.0.0.0:2000")
+	t.Setenv("NAMESPACE", "app")
+	_, err = resolve(true)
+	assert.NoError(t, err)
+}
+
+// secretProvider retrieves the same value for every uri, like a secret store.
+type secretProvider struct {
+	scheme string
+	value  any
+}
+
+func (p secretProvider) Retrieve(context.Context, string, confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	return confmap.NewRetrieved(p.value)
+}
+
+func (p secretProvider) Scheme() string {
+	return p.scheme
+}
+
+func (secretProvider) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestSecretsAreNotExpanded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`exporters:
+  otlphttp:
+    headers:
+      password: ${secretsmanager:db}
+      basic: user:${ssm:/db/password}
+    endpoint: https://${HOST}
+`), 0600))
+	t.Setenv("HOST", "collector.example.com")
+
+	const secret = "pa$$w0rd${X}"
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"file:" + path},
+		Providers: map[string]confmap.Provider{
+			"file":           withEnvExpansion(fileprovider.NewWithSettings(confmap.ProviderSettings{}), true),
+			"ssm":            withEnvExpansion(secretProvider{scheme: "ssm", value: secret}, true),
+			"secretsmanager": withEnvExpansion(secretProvider{scheme: "secretsmanager", value: secret}, true),
+		},
+		Converters: []confmap.Converter{envConverter{}},
+	})
+	require.NoError(t, err)
+	// X is undefined, the strict mode does not apply to the secrets
+	conf, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"exporters": map[string]any{"otlphttp": map[string]any{
+		"headers": map[string]any{
+			"password": secret,
+			"basic":    "user:" + secret,
+		},
+		"endpoint": "https://collector.example.com",
+	}}}, conf.ToStringMap())
+
+	resolver, err = confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"secretsmanager:config"},
+		Providers: map[string]confmap.Provider{
+			"secretsmanager": withEnvExpansion(secretProvider{scheme: "secretsmanager", value: map[string]any{
+				"exporters": map[string]any{"otlphttp": map[string]any{"headers": map[string]any{"password": secret}}},
+			}}, true),
+		},
+		Converters: []confmap.Converter{envConverter{}},
+	})
+	require.NoError(t, err)
+	conf, err = resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, secret, conf.Get("exporters::otlphttp::headers::password"))
+}
+
+func TestStrictEnvFlag(t *testing.T) {
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+
+	flgs := Flags(featuregate.NewRegistry())
+	require.NoError(t, flgs.Parse([]string{"--config=" + getValidTestConfigPath(), "--strict-env"}))
+	provider, err := NewConfigProvider(flgs)
+	require.NoError(t, err)
+	_, err = provider.Get(context.Background(), factories)
+	assert.ErrorContains(t, err, "undefined environment variables: XRAY_ENDPOINT")
+
+	t.Cleanup(func() { SetDefaultStrictEnv(false) })
+	SetDefaultStrictEnv(true)
+	assert.True(t, getStrictEnvFlag(Flags(featuregate.NewRegistry())))
+}
@@ -18,17 +18,26 @@ import (
 	"errors"
 	"flag"
 	"strings"
+	"time"
 
 	"go.opentelemetry.io/collector/featuregate"
 )
 
 const (
-	configFlag = "config"
+	configFlag       = "config"
+	configDirFlag    = "config-dir"
+	watchConfigFlag  = "watch-config"
+	pollIntervalFlag = "config-poll-interval"
+	adminFlag        = "admin-endpoint"
+	strictEnvFlag    = "strict-env"
 )
 
 type configFlagValue struct {
 	values []string
-	sets   []string
+	// sets are the yaml locations of the --set, --unset and --set-file flags
+	sets []string
+	// edits is the number of edits recorded in sets, see addEdit
+	edits int
 }
 
 func (s *configFlagValue) Set(val string) error {
@@ -47,19 +56,41 @@ func Flags(reg *featuregate.Registry) *flag.FlagSet {
 	flagSet.Var(cfgs, configFlag, "Locations to the config file(s), note that only a"+
 		" single location can be set per flag entry e.g. `--config=file:/path/to/first --config=file:path/to/second`.")
 
-	flagSet.Func("set",
-		"Set arbitrary component config property. The component has to be defined in the config file and the flag"+
-			" has a higher precedence. Array config properties are overridden and maps are joined. Example --set=processors.batch.timeout=2s",
+	flagSet.Func(configDirFlag, "Directory of config files, every *.yaml file of the directory is merged in"+
+		" lexical order. It is merged with the --config locations in the order of the flags, e.g."+
+		" `--config=file:/path/to/config.yaml --config-dir=/path/to/conf.d`.",
 		func(s string) error {
-			idx := strings.Index(s, "=")
-			if idx == -1 {
-				// No need for more context, see TestSetFlag/invalid_set.
-				return errors.New("missing equal sign")
+			if strings.TrimSpace(s) == "" {
+				return errors.New("empty directory")
 			}
-			cfgs.sets = append(cfgs.sets, "yaml:"+strings.TrimSpace(strings.ReplaceAll(s[:idx], ".", "::"))+": "+strings.TrimSpace(s[idx+1:]))
-			return nil
+			return cfgs.Set("dir:" + s)
 		})
 
+	flagSet.Func("set",
+		"Set arbitrary component config property. The component has to be defined in the config file and the flag"+
+			" has a higher precedence. Array config properties are overridden and maps are joined. Example --set=processors.batch.timeout=2s."+
+			" A backslash escapes a dot in a key, e.g. --set=exporters.otlphttp.headers.X-Api\\.Key=secret, and list"+
+			" elements are addressed by index, e.g. --set=processors.attributes.actions[0].value=prod.",
+		cfgs.setLocations)
+	flagSet.Func("unset", "Remove a config property, with the path syntax of --set. Example --unset=exporters.awsemf.namespace",
+		cfgs.unsetLocations)
+	flagSet.Func("set-file", "Set a config property to the content of a file, with the path syntax of --set. A file holding"+
+		" a yaml map or list is set as such, any other content as a string. Example --set-file=exporters.otlp.tls.ca_pem=/path/to/ca.pem",
+		cfgs.setFileLocations)
+
+	flagSet.Bool(watchConfigFlag, false, "Reload the configuration when its sources change. Files and directories are watched, while"+
+		" S3, HTTP(S), SSM and Secrets Manager sources are polled. An invalid new configuration is rejected and the"+
+		" running one is kept.")
+	flagSet.Duration(pollIntervalFlag, defaultPollInterval, "Interval at which remote configuration sources are polled"+
+		" for changes when --"+watchConfigFlag+" is set.")
+
+	flagSet.Bool(strictEnvFlag, defaultStrictEnv, "Fail when the configuration references undefined environment variables,"+
+		" instead of expanding them to empty strings. All the undefined variables of a config location are reported.")
+
+	flagSet.String(adminFlag, "", "Endpoint of the local admin HTTP server, e.g. localhost:13134, which allows changing"+
+		" the log level of components at runtime. The server has no authentication, so only loopback addresses are"+
+		" accepted. The server is disabled when empty.")
+
 	reg.RegisterFlags(flagSet)
 
 	return flagSet
@@ -69,3 +100,40 @@ func getConfigFlag(flagSet *flag.FlagSet) []string {
 	cfv := flagSet.Lookup(configFlag).Value.(*configFlagValue)
 	return append(cfv.values, cfv.sets...)
 }
+
+// getWatchFlags returns whether the configuration sources have to be watched and the poll interval.
+func getWatchFlags(flagSet *flag.FlagSet) (bool, time.Duration) {
+	watch, interval := false, defaultPollInterval
+	if f := flagSet.Lookup(watchConfigFlag); f != nil {
+		watch = f.Value.(flag.Getter).Get().(bool)
+	}
+	if f := flagSet.Lookup(pollIntervalFlag); f != nil {
+		interval = f.Value.(flag.Getter).Get().(time.Duration)
+	}
+	return watch, interval
+}
+
+// defaultStrictEnv is the default of --strict-env, see SetDefaultStrictEnv.
+var defaultStrictEnv = false
+
+// SetDefaultStrictEnv sets the default of --strict-env, e.g. from the extracfg file. It has to be
+// called before the flags are created.
+func SetDefaultStrictEnv(strict bool) {
+	defaultStrictEnv = strict
+}
+
+// getStrictEnvFlag returns whether undefined environment variables are an error.
+func getStrictEnvFlag(flagSet *flag.FlagSet) bool {
+	if f := flagSet.Lookup(strictEnvFlag); f != nil {
+		return f.Value.(flag.Getter).Get().(bool)
+	}
+	return defaultStrictEnv
+}
+
+// GetAdminEndpoint returns the endpoint of the admin server, empty when it is disabled.
+func GetAdminEndpoint(flagSet *flag.FlagSet) string {
+	if f := flagSet.Lookup(adminFlag); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
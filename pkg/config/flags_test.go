@@ -15,20 +15,54 @@
 package config
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/envprovider"
+	"go.opentelemetry.io/collector/confmap/provider/fileprovider"
+	"go.opentelemetry.io/collector/confmap/provider/yamlprovider"
 
 	"go.opentelemetry.io/collector/featuregate"
 )
 
 func TestSetFlag(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"), 0600))
+	scrapeFile := filepath.Join(dir, "scrape.yaml")
+	require.NoError(t, os.WriteFile(scrapeFile, []byte("job_name: app\nstatic_configs:\n  - targets: [localhost:9090]\n"), 0600))
+	baseFile := filepath.Join(dir, "base.yaml")
+	require.NoError(t, os.WriteFile(baseFile, []byte(`processors:
+  attributes:
+    actions:
+      - key: env
+        value: dev
+        action: insert
+      - key: team
+        value: core
+        action: insert
+exporters:
+  awsemf:
+    namespace: app
+    metric_declarations:
+      - dimensions: [[service]]
+`), 0600))
+	t.Setenv("ENV_NAME", "prod")
+
 	tests := []struct {
 		name            string
 		args            []string
 		expectedConfigs []string
-		expectedErr     string
+		// expectedConf is the resolved configuration, when set
+		expectedConf map[string]any
+		expectedErr  string
+		// expectedResolveErr is the error resolving the configuration
+		expectedResolveErr string
 	}{
 		{
 			name:            "simple set",
@@ -60,11 +94,167 @@ func TestSetFlag(t *testing.T) {
 			args:            []string{"--config=file:testdata/otelcol-nop.yaml", "--set=key=value"},
 			expectedConfigs: []string{"file:testdata/otelcol-nop.yaml", "yaml:key: value"},
 		},
+		{
+			name:            "escaped dot",
+			args:            []string{`--set=exporters.otlphttp.headers.X-Api\.Key=secret`},
+			expectedConfigs: []string{"yaml:exporters::otlphttp::headers::X-Api.Key: secret"},
+			expectedConf: map[string]any{
+				"exporters": map[string]any{"otlphttp": map[string]any{"headers": map[string]any{"X-Api.Key": "secret"}}},
+			},
+		},
+		{
+			name:            "escaped key is quoted",
+			args:            []string{`--set=processors.attributes.include.attributes.http\.status code=200`},
+			expectedConfigs: []string{`yaml:"processors::attributes::include::attributes::http.status code": 200`},
+			expectedConf: map[string]any{
+				"processors": map[string]any{"attributes": map[string]any{"include": map[string]any{"attributes": map[string]any{"http.status code": 200}}}},
+			},
+		},
+		{
+			name:            "escaped backslash and equal sign",
+			args:            []string{`--set=key\\with\=sign=a=b`},
+			expectedConfigs: []string{`yaml:"key\\with=sign": a=b`},
+			expectedConf:    map[string]any{`key\with=sign`: "a=b"},
+		},
+		{
+			name: "list index",
+			args: []string{
+				"--config=file:" + baseFile,
+				"--set=processors.attributes.actions[1].value=${env:ENV_NAME}",
+				"--set=exporters.awsemf.metric_declarations[0].dimensions[0]=[service, operation]",
+			},
+			expectedConf: map[string]any{
+				"processors": map[string]any{"attributes": map[string]any{"actions": []any{
+					map[string]any{"key": "env", "value": "dev", "action": "insert"},
+					map[string]any{"key": "team", "value": "prod", "action": "insert"},
+				}}},
+				"exporters": map[string]any{"awsemf": map[string]any{
+					"namespace":           "app",
+					"metric_declarations": []any{map[string]any{"dimensions": []any{[]any{"service", "operation"}}}},
+				}},
+			},
+		},
+		{
+			name: "list append",
+			args: []string{"--config=file:" + baseFile, "--set=processors.attributes.actions[2]={key: region, value: us-west-2, action: insert}", "--unset=exporters"},
+			expectedConf: map[string]any{
+				"processors": map[string]any{"attributes": map[string]any{"actions": []any{
+					map[string]any{"key": "env", "value": "dev", "action": "insert"},
+					map[string]any{"key": "team", "value": "core", "action": "insert"},
+					map[string]any{"key": "region", "value": "us-west-2", "action": "insert"},
+				}}},
+			},
+		},
+		{
+			name:               "list index out of range",
+			args:               []string{"--config=file:" + baseFile, "--set=processors.attributes.actions[5].value=prod"},
+			expectedResolveErr: "--set processors.attributes.actions[5].value: index 5 out of range, the list has 2 elements",
+		},
+		{
+			name:               "index of a map",
+			args:               []string{"--config=file:" + baseFile, "--set=exporters.awsemf[0]=x"},
+			expectedResolveErr: "--set exporters.awsemf[0]: cannot set index 0 of a map[string]interface {}",
+		},
+		{
+			name: "unset",
+			args: []string{
+				"--config=file:" + baseFile,
+				"--unset=processors.attributes.actions[0]",
+				"--unset=exporters.awsemf.namespace",
+				"--unset=exporters.missing.key",
+			},
+			expectedConf: map[string]any{
+				"processors": map[string]any{"attributes": map[string]any{"actions": []any{
+					map[string]any{"key": "team", "value": "core", "action": "insert"},
+				}}},
+				"exporters": map[string]any{"awsemf": map[string]any{
+					"metric_declarations": []any{map[string]any{"dimensions": []any{[]any{"service"}}}},
+				}},
+			},
+		},
+		{
+			name: "edits in flag order",
+			args: []string{"--set=key[0]=a", "--unset=key[0]", "--set=key[0]=b"},
+			expectedConfigs: []string{
+				"yaml:" + setEditsKey + "::0::op: set", "yaml:" + setEditsKey + `::0::path: ["key",0]`, "yaml:" + setEditsKey + "::0::value: a",
+				"yaml:" + setEditsKey + "::1::op: unset", "yaml:" + setEditsKey + `::1::path: ["key",0]`, "yaml:" + setEditsKey + "::1::value: ",
+				"yaml:" + setEditsKey + "::2::op: set", "yaml:" + setEditsKey + `::2::path: ["key",0]`, "yaml:" + setEditsKey + "::2::value: b",
+			},
+			expectedConf: map[string]any{"key": []any{"b"}},
+		},
+		{
+			name: "set file",
+			args: []string{
+				"--set-file=exporters.otlp.tls.ca_pem=" + caFile,
+				"--set-file=receivers.prometheus.config.scrape_configs[0]=" + scrapeFile,
+			},
+			expectedConf: map[string]any{
+				"exporters": map[string]any{"otlp": map[string]any{"tls": map[string]any{"ca_pem": "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"}}},
+				"receivers": map[string]any{"prometheus": map[string]any{"config": map[string]any{"scrape_configs": []any{
+					map[string]any{"job_name": "app", "static_configs": []any{map[string]any{"targets": []any{"localhost:9090"}}}},
+				}}}},
+			},
+		},
+		{
+			name:               "set missing file",
+			args:               []string{"--set-file=exporters.otlp.tls.ca_pem=" + filepath.Join(dir, "missing.pem")},
+			expectedResolveErr: "--set-file exporters.otlp.tls.ca_pem: open " + filepath.Join(dir, "missing.pem"),
+		},
+		{
+			name:        "set file without file",
+			args:        []string{"--set-file=exporters.otlp.tls.ca_pem="},
+			expectedErr: `invalid value "exporters.otlp.tls.ca_pem=" for flag -set-file: missing file`,
+		},
 		{
 			name:        "invalid set",
 			args:        []string{"--set=key:name"},
 			expectedErr: `invalid value "key:name" for flag -set: missing equal sign`,
 		},
+		{
+			name:        "empty key",
+			args:        []string{"--set=outer..inner=value"},
+			expectedErr: `invalid value "outer..inner=value" for flag -set: invalid path "outer..inner": empty key`,
+		},
+		{
+			name:        "invalid index",
+			args:        []string{"--set=list[-1]=value"},
+			expectedErr: `invalid value "list[-1]=value" for flag -set: invalid path "list[-1]": invalid index "-1"`,
+		},
+		{
+			name:        "unclosed index",
+			args:        []string{"--unset=list[0"},
+			expectedErr: `invalid value "list[0" for flag -unset: invalid path "list[0": missing closing bracket`,
+		},
+		{
+			name:        "index without key",
+			args:        []string{"--unset=[0]"},
+			expectedErr: `invalid value "[0]" for flag -unset: invalid path "[0]": index without a key`,
+		},
+		{
+			name:        "text after index",
+			args:        []string{"--unset=list[0]key"},
+			expectedErr: `invalid value "list[0]key" for flag -unset: invalid path "list[0]key": unexpected 'k' after index`,
+		},
+		{
+			name:        "escaped equal sign",
+			args:        []string{`--set=key\=value`},
+			expectedErr: `invalid value "key\\=value" for flag -set: missing equal sign`,
+		},
+		{
+			name:        "trailing backslash",
+			args:        []string{`--unset=key\`},
+			expectedErr: `invalid value "key\\" for flag -unset: invalid path "key\\": trailing backslash`,
+		},
+		{
+			name:            "config dir between configs",
+			args:            []string{"--config=file:base.yaml", "--config-dir=/etc/conf.d", "--config=file:override.yaml", "--set=key=value"},
+			expectedConfigs: []string{"file:base.yaml", "dir:/etc/conf.d", "file:override.yaml", "yaml:key: value"},
+		},
+		{
+			name:        "empty config dir",
+			args:        []string{"--config-dir="},
+			expectedErr: `invalid value "" for flag -config-dir: empty directory`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -76,7 +266,30 @@ func TestSetFlag(t *testing.T) {
 				return
 			}
 			require.NoError(t, err)
-			assert.Equal(t, tt.expectedConfigs, getConfigFlag(flgs))
+			if tt.expectedConfigs != nil {
+				assert.Equal(t, tt.expectedConfigs, getConfigFlag(flgs))
+			}
+			if tt.expectedConf == nil && tt.expectedResolveErr == "" {
+				return
+			}
+
+			resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+				URIs: getConfigFlag(flgs),
+				Providers: map[string]confmap.Provider{
+					"file": fileprovider.NewWithSettings(confmap.ProviderSettings{}),
+					"env":  envprovider.NewWithSettings(confmap.ProviderSettings{}),
+					"yaml": yamlprovider.NewWithSettings(confmap.ProviderSettings{}),
+				},
+				Converters: []confmap.Converter{setConverter{}},
+			})
+			require.NoError(t, err)
+			conf, err := resolver.Resolve(context.Background())
+			if tt.expectedResolveErr != "" {
+				assert.ErrorContains(t, err, tt.expectedResolveErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedConf, conf.ToStringMap())
 		})
 	}
 }
@@ -0,0 +1,139 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/logger"
+)
+
+const (
+	telemetryKey       = "telemetry"
+	logsKey            = "logs"
+	logFileKey         = "file"
+	logComponentsKey   = "components"
+	logsConfigKey      = serviceKey + confmap.KeyDelimiter + telemetryKey + confmap.KeyDelimiter + logsKey
+	logFileConfigKey   = logsConfigKey + confmap.KeyDelimiter + logFileKey
+	logLevelsConfigKey = logsConfigKey + confmap.KeyDelimiter + logComponentsKey
+	logLevelConfigKey  = logsConfigKey + confmap.KeyDelimiter + "level"
+)
+
+// logSettings are the settings of the collector logs which are not part of the collector configuration.
+type logSettings struct {
+	// file is nil when the configuration has no file settings
+	file *logger.FileSettings
+	// levels is nil when the configuration has no component levels
+	levels map[string]zapcore.Level
+}
+
+// logsConverter removes the settings of aws-otel-collector from service::telemetry::logs, as they are
+// unknown to the collector, and keeps them for the logger. For example:
+//
+//	service:
+//	  telemetry:
+//	    logs:
+//	      level: info
+//	      components:
+//	        awsemf: debug
+//	      file:
+//	        max_size: 50
+//	        encoding: console
+//	        rotate_at: "00:00"
+//
+// The settings left out keep the values of the extracfg file or the defaults.
+type logsConverter struct {
+	mu sync.Mutex
+	// settings are the settings of the last converted configuration
+	settings logSettings
+}
+
+var _ confmap.Converter = (*logsConverter)(nil)
+
+func (c *logsConverter) Convert(_ context.Context, conf *confmap.Conf) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.settings = logSettings{}
+	if !conf.IsSet(logFileConfigKey) && !conf.IsSet(logLevelsConfigKey) {
+		return nil
+	}
+
+	var settings logSettings
+	if conf.IsSet(logFileConfigKey) {
+		file := logger.GetDefaultFileSettings()
+		sub, err := conf.Sub(logFileConfigKey)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", logFileConfigKey, err)
+		}
+		if err = sub.Unmarshal(&file); err != nil {
+			return fmt.Errorf("invalid %s: %w", logFileConfigKey, err)
+		}
+		if err = file.Validate(); err != nil {
+			return fmt.Errorf("invalid %s: %w", logFileConfigKey, err)
+		}
+		settings.file = &file
+	}
+	if conf.IsSet(logLevelsConfigKey) {
+		var levels map[string]string
+		sub, err := conf.Sub(logLevelsConfigKey)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", logLevelsConfigKey, err)
+		}
+		if err = sub.Unmarshal(&levels); err != nil {
+			return fmt.Errorf("invalid %s: %w", logLevelsConfigKey, err)
+		}
+		// the levels of the configuration are layered on top of the ones of the extracfg file
+		settings.levels = logger.GetDefaultComponentLevels()
+		parsed, err := logger.ParseComponentLevels(levels)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", logLevelsConfigKey, err)
+		}
+		for component, level := range parsed {
+			settings.levels[component] = level
+		}
+	}
+	c.settings = settings
+	removeKeys(conf, logsConfigKey, logFileKey, logComponentsKey)
+	return nil
+}
+
+// lastSettings returns the settings of the last converted configuration.
+func (c *logsConverter) lastSettings() logSettings {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.settings
+}
+
+// applyLogSettings applies the settings of the configuration the collector runs, or reverts to the
+// default ones for the settings it does not have.
+func applyLogSettings(settings logSettings) {
+	if settings.file == nil {
+		logger.SetFileSettings(logger.GetDefaultFileSettings())
+	} else {
+		logger.SetFileSettings(*settings.file)
+	}
+	if settings.levels == nil {
+		logger.SetComponentLevels(logger.GetDefaultComponentLevels())
+	} else {
+		logger.SetComponentLevels(settings.levels)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/logger"
+)
+
+func TestLogsConverter(t *testing.T) {
+	defaults := logger.GetDefaultFileSettings()
+
+	tests := []struct {
+		name             string
+		input            map[string]any
+		expected         map[string]any
+		expectedSettings func() logSettings
+		err              bool
+	}{
+		{
+			name:             "no_file_settings",
+			input:            map[string]any{"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{"level": "info"}}}},
+			expected:         map[string]any{"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{"level": "info"}}}},
+			expectedSettings: func() logSettings { return logSettings{} },
+		},
+		{
+			name: "file_settings",
+			input: map[string]any{"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{
+				"level": "info",
+				"file":  map[string]any{"max_size": 50, "encoding": "console", "rotate_at": "00:00"},
+			}}}},
+			expected: map[string]any{"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{"level": "info"}}}},
+			expectedSettings: func() logSettings {
+				settings := defaults
+				settings.MaxSize, settings.Encoding, settings.RotateAt = 50, logger.EncodingConsole, "00:00"
+				return logSettings{file: &settings}
+			},
+		},
+		{
+			name: "component_levels",
+			input: map[string]any{"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{
+				"level":      "info",
+				"components": map[string]any{"awsemf": "debug", "otlp/app": "WARN"},
+			}}}},
+			expected: map[string]any{"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{"level": "info"}}}},
+			expectedSettings: func() logSettings {
+				return logSettings{levels: map[string]zapcore.Level{"awsemf": zapcore.DebugLevel, "otlp/app": zapcore.WarnLevel}}
+			},
+		},
+		{
+			name: "invalid_component_level",
+			input: map[string]any{"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{
+				"components": map[string]any{"awsemf": "verbose"},
+			}}}},
+			err: true,
+		},
+		{
+			name: "invalid_encoding",
+			input: map[string]any{"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{
+				"file": map[string]any{"encoding": "xml"},
+			}}}},
+			err: true,
+		},
+		{
+			name: "unknown_key",
+			input: map[string]any{"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{
+				"file": map[string]any{"max_files": 3},
+			}}}},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := &logsConverter{}
+			conf := confmap.NewFromStringMap(tt.input)
+			err := converter.Convert(context.Background(), conf)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, conf.ToStringMap())
+			assert.Equal(t, tt.expectedSettings(), converter.lastSettings())
+		})
+	}
+}
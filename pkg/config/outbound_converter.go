@@ -0,0 +1,125 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/outbound"
+)
+
+const (
+	outboundKey       = "outbound"
+	outboundConfigKey = serviceKey + confmap.KeyDelimiter + outboundKey
+)
+
+var (
+	defaultOutboundMu sync.Mutex
+	// defaultOutboundSettings are the settings of the environment and the extracfg file, see
+	// SetDefaultOutboundSettings.
+	defaultOutboundSettings outbound.Settings
+)
+
+// SetDefaultOutboundSettings sets the outbound settings overridden by the service::outbound block of
+// the configuration, e.g. from the environment and the extracfg file. They apply right away, so that
+// the configuration providers read the configuration with them.
+func SetDefaultOutboundSettings(settings outbound.Settings) error {
+	if err := outbound.Apply(settings); err != nil {
+		return err
+	}
+	defaultOutboundMu.Lock()
+	defer defaultOutboundMu.Unlock()
+	defaultOutboundSettings = settings
+	return nil
+}
+
+func getDefaultOutboundSettings() outbound.Settings {
+	defaultOutboundMu.Lock()
+	defer defaultOutboundMu.Unlock()
+	return defaultOutboundSettings
+}
+
+// outboundConverter removes the service::outbound block from the configuration, as it is unknown to
+// the collector, and keeps the proxy and CA bundle of the outbound connections. They are applied once
+// the configuration is accepted, see reloadingConfigProvider.use. For example:
+//
+//	service:
+//	  outbound:
+//	    https_proxy: http://proxy.example.com:3128
+//	    no_proxy: 169.254.169.254,.internal
+//	    ca_bundle: /etc/pki/proxy-ca.pem
+//
+// The settings left out keep the values of the extracfg file or of the environment.
+type outboundConverter struct {
+	mu sync.Mutex
+	// settings are the settings of the last converted configuration
+	settings outbound.Settings
+	// applied are the settings of the last accepted configuration, nil before the first one
+	applied *outbound.Settings
+}
+
+var _ confmap.Converter = (*outboundConverter)(nil)
+
+func (c *outboundConverter) Convert(_ context.Context, conf *confmap.Conf) error {
+	settings := getDefaultOutboundSettings()
+	if conf.IsSet(outboundConfigKey) {
+		var block outbound.Settings
+		sub, err := conf.Sub(outboundConfigKey)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", outboundConfigKey, err)
+		}
+		if err = sub.Unmarshal(&block); err != nil {
+			return fmt.Errorf("invalid %s: %w", outboundConfigKey, err)
+		}
+		settings = settings.Override(block)
+		removeKeys(conf, serviceKey, outboundKey)
+	}
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("invalid %s: %w", outboundConfigKey, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settings = settings
+	return nil
+}
+
+// lastSettings returns the settings of the last converted configuration.
+func (c *outboundConverter) lastSettings() outbound.Settings {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.settings
+}
+
+// apply applies the settings of the configuration the collector runs.
+func (c *outboundConverter) apply(settings outbound.Settings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.applied != nil && *c.applied != settings {
+		// the system certificates and the proxy of the clients not using http.DefaultTransport are
+		// read once
+		log.Printf("W! the outbound settings changed, they fully apply on restart\n")
+	}
+	c.applied = &settings
+	if err := outbound.Apply(settings); err != nil {
+		log.Printf("E! failed to apply the outbound settings: %v\n", err)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/outbound"
+)
+
+func TestOutboundConverter(t *testing.T) {
+	for _, key := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "SSL_CERT_FILE"} {
+		t.Setenv(key, "")
+	}
+	defaults := outbound.Settings{HTTPSProxy: "http://proxy.example.com:3128", NoProxy: "localhost"}
+	require.NoError(t, SetDefaultOutboundSettings(defaults))
+	t.Cleanup(func() { require.NoError(t, SetDefaultOutboundSettings(outbound.Settings{})) })
+
+	tests := []struct {
+		name             string
+		input            map[string]any
+		expected         map[string]any
+		expectedSettings outbound.Settings
+		expectedErr      string
+	}{
+		{
+			name:             "no_block",
+			input:            map[string]any{"service": map[string]any{"pipelines": map[string]any{}}},
+			expected:         map[string]any{"service": map[string]any{"pipelines": map[string]any{}}},
+			expectedSettings: defaults,
+		},
+		{
+			name: "block_overrides_defaults",
+			input: map[string]any{"service": map[string]any{
+				"outbound":  map[string]any{"no_proxy": "169.254.169.254,.internal"},
+				"pipelines": map[string]any{},
+			}},
+			expected: map[string]any{"service": map[string]any{"pipelines": map[string]any{}}},
+			expectedSettings: outbound.Settings{
+				HTTPSProxy: "http://proxy.example.com:3128",
+				NoProxy:    "169.254.169.254,.internal",
+			},
+		},
+		{
+			name: "unknown_key",
+			input: map[string]any{"service": map[string]any{"outbound": map[string]any{
+				"proxy": "http://proxy.example.com:3128",
+			}}},
+			expectedErr: "invalid service::outbound",
+		},
+		{
+			name: "invalid_proxy",
+			input: map[string]any{"service": map[string]any{"outbound": map[string]any{
+				"http_proxy": "proxy.example.com:3128",
+			}}},
+			expectedErr: "invalid service::outbound: invalid http_proxy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := confmap.NewFromStringMap(tt.input)
+			converter := &outboundConverter{}
+			err := converter.Convert(context.Background(), conf)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, conf.ToStringMap())
+			assert.Equal(t, tt.expectedSettings, converter.lastSettings())
+			// the settings are applied once the configuration is accepted
+			assert.Equal(t, defaults, outbound.Current())
+			assert.Equal(t, defaults.NoProxy, os.Getenv("NO_PROXY"))
+
+			converter.apply(converter.lastSettings())
+			assert.Equal(t, tt.expectedSettings, outbound.Current())
+			assert.Equal(t, tt.expectedSettings.NoProxy, os.Getenv("NO_PROXY"))
+			require.NoError(t, outbound.Apply(defaults))
+		})
+	}
+}
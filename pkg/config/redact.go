@@ -0,0 +1,96 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"strings"
+)
+
+// RedactedValue replaces the values of sensitive keys.
+const RedactedValue = "[REDACTED]"
+
+// sensitiveKeyParts are matched against the normalized key, any key containing one of them is masked.
+var sensitiveKeyParts = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"api_key",
+	"apikey",
+	"access_key",
+	"private_key",
+	"signing_key",
+	"authorization",
+	"credential",
+	"key_pem",
+}
+
+// sensitiveKeys are matched against the whole normalized key, e.g. the datadog api::key.
+var sensitiveKeys = map[string]bool{
+	"key": true,
+}
+
+// IsSensitiveKey reports whether the value stored under the key should not be displayed.
+// Matching ignores the case and treats "-" as "_" so header names are covered as well.
+func IsSensitiveKey(key string) bool {
+	normalized := strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	if sensitiveKeys[normalized] {
+		return true
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(normalized, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns a copy of the raw configuration value where every non empty value stored
+// under a sensitive key is replaced by RedactedValue.
+func Redact(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, val := range v {
+			if IsSensitiveKey(key) && !isEmpty(val) {
+				redacted[key] = RedactedValue
+				continue
+			}
+			redacted[key] = Redact(val)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, 0, len(v))
+		for _, val := range v {
+			redacted = append(redacted, Redact(val))
+		}
+		return redacted
+	default:
+		return v
+	}
+}
+
+// isEmpty keeps unset values visible, which helps to spot a missing secret.
+func isEmpty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	default:
+		return false
+	}
+}
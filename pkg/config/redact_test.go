@@ -0,0 +1,106 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		sensitive bool
+	}{
+		{key: "password", sensitive: true},
+		{key: "db_password", sensitive: true},
+		{key: "secret_key", sensitive: true},
+		{key: "bearer_token", sensitive: true},
+		{key: "X-Api-Key", sensitive: true},
+		{key: "Authorization", sensitive: true},
+		{key: "key", sensitive: true},
+		{key: "key_file", sensitive: false},
+		{key: "endpoint", sensitive: false},
+		{key: "region", sensitive: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.sensitive, IsSensitiveKey(tt.key))
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	raw := map[string]any{
+		"exporters": map[string]any{
+			"datadog": map[string]any{
+				"api": map[string]any{
+					"key":  "abcd",
+					"site": "datadoghq.com",
+				},
+			},
+			"otlphttp": map[string]any{
+				"headers": map[string]any{
+					"Authorization": "Bearer abcd",
+				},
+				"tls": map[string]any{
+					"key_file": "/etc/key.pem",
+				},
+			},
+			"sapm": map[string]any{
+				"access_token": "",
+			},
+		},
+		"receivers": map[string]any{
+			"kafka": map[string]any{
+				"brokers": []any{map[string]any{"password": "p"}, "localhost:9092"},
+			},
+		},
+	}
+
+	expected := map[string]any{
+		"exporters": map[string]any{
+			"datadog": map[string]any{
+				"api": map[string]any{
+					"key":  RedactedValue,
+					"site": "datadoghq.com",
+				},
+			},
+			"otlphttp": map[string]any{
+				"headers": map[string]any{
+					"Authorization": RedactedValue,
+				},
+				"tls": map[string]any{
+					"key_file": "/etc/key.pem",
+				},
+			},
+			"sapm": map[string]any{
+				"access_token": "",
+			},
+		},
+		"receivers": map[string]any{
+			"kafka": map[string]any{
+				"brokers": []any{map[string]any{"password": RedactedValue}, "localhost:9092"},
+			},
+		},
+	}
+
+	assert.Equal(t, expected, Redact(raw))
+	// the input is left untouched
+	assert.Equal(t, "abcd", raw["exporters"].(map[string]any)["datadog"].(map[string]any)["api"].(map[string]any)["key"])
+}
@@ -0,0 +1,269 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/otelcol"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+	"github.com/aws-observability/aws-otel-collector/pkg/outbound"
+)
+
+// resolved is a configuration which has been unmarshalled and validated.
+type resolved struct {
+	conf     *confmap.Conf
+	cfg      *otelcol.Config
+	logs     logSettings
+	aws      awssession.CredentialSettings
+	outbound outbound.Settings
+}
+
+// reloadingConfigProvider wraps the upstream config provider so that a reload never replaces a
+// running configuration with an invalid one. Changes reported by the providers are validated before
+// the collector is notified, and when a reload is requested anyway, e.g. through SIGHUP, the last
+// known good configuration is returned in place of an invalid one.
+type reloadingConfigProvider struct {
+	provider otelcol.ConfigProvider
+	logs     *logsConverter
+	aws      *awsConverter
+	outbound *outboundConverter
+
+	mu        sync.Mutex
+	factories *otelcol.Factories
+	// conf is the configuration returned by the first GetConfmap, before the factories are known
+	conf     *confmap.Conf
+	lastGood *resolved
+	// pending is the validated configuration the collector is about to reload
+	pending *resolved
+
+	watchOnce sync.Once
+	watch     chan error
+}
+
+var _ otelcol.ConfigProvider = (*reloadingConfigProvider)(nil)
+var _ otelcol.ConfmapProvider = (*reloadingConfigProvider)(nil)
+
+func newReloadingConfigProvider(provider otelcol.ConfigProvider, logs *logsConverter, aws *awsConverter,
+	outbound *outboundConverter) *reloadingConfigProvider {
+	return &reloadingConfigProvider{
+		provider: provider,
+		logs:     logs,
+		aws:      aws,
+		outbound: outbound,
+		watch:    make(chan error, 1),
+	}
+}
+
+// GetConfmap returns the resolved configuration. Once the collector started, it returns the
+// configuration of the pending reload, or the last known good one if the new one is invalid.
+func (p *reloadingConfigProvider) GetConfmap(ctx context.Context) (*confmap.Conf, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.factories == nil {
+		conf, err := p.provider.(otelcol.ConfmapProvider).GetConfmap(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.conf = conf
+		return conf, nil
+	}
+
+	if p.pending == nil {
+		r, err := p.resolve(ctx)
+		if err != nil {
+			if p.lastGood == nil {
+				return nil, err
+			}
+			log.Printf("E! rejecting the new configuration, keeping the last known good one: %v\n", err)
+			r = p.lastGood
+		}
+		p.pending = r
+	}
+	return p.pending.conf, nil
+}
+
+// Get returns the configuration, see GetConfmap.
+func (p *reloadingConfigProvider) Get(ctx context.Context, factories otelcol.Factories) (*otelcol.Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending != nil {
+		r := p.pending
+		p.pending = nil
+		return p.use(r), nil
+	}
+
+	if p.factories == nil {
+		// first start, errors are reported by the collector as usual, the configuration was usually
+		// resolved by GetConfmap already
+		p.factories = &factories
+		conf := p.conf
+		if conf == nil {
+			var err error
+			if conf, err = p.provider.(otelcol.ConfmapProvider).GetConfmap(ctx); err != nil {
+				return nil, err
+			}
+		}
+		cfg, err := unmarshal(ctx, conf, factories)
+		if err != nil {
+			return nil, err
+		}
+		r := p.newResolved(conf, cfg)
+		if cfg.Validate() == nil {
+			return p.use(r), nil
+		}
+		return cfg, nil
+	}
+
+	r, err := p.resolve(ctx)
+	if err != nil {
+		if p.lastGood == nil {
+			return nil, err
+		}
+		log.Printf("E! rejecting the new configuration, keeping the last known good one: %v\n", err)
+		return p.lastGood.cfg, nil
+	}
+	return p.use(r), nil
+}
+
+// use records the configuration the collector is about to run and applies its log, AWS credential
+// and outbound settings, it must be called with the lock held. The converters only record the
+// settings, as a configuration is also resolved when it is only validated or printed, e.g. by
+// print-config, or when a reload is rejected, neither of which must change them.
+func (p *reloadingConfigProvider) use(r *resolved) *otelcol.Config {
+	p.lastGood = r
+	applyLogSettings(r.logs)
+	applyCredentialSettings(r.aws)
+	p.outbound.apply(r.outbound)
+	return r.cfg
+}
+
+// Watch notifies the collector of configuration changes, only once the new configuration was
+// found to be valid.
+func (p *reloadingConfigProvider) Watch() <-chan error {
+	p.watchOnce.Do(func() {
+		go p.watchChanges()
+	})
+	return p.watch
+}
+
+func (p *reloadingConfigProvider) Shutdown(ctx context.Context) error {
+	return p.provider.Shutdown(ctx)
+}
+
+func (p *reloadingConfigProvider) watchChanges() {
+	defer close(p.watch)
+	for err := range p.provider.Watch() {
+		if err != nil {
+			p.watch <- err
+			continue
+		}
+		if p.prepareReload() {
+			p.watch <- nil
+		}
+	}
+}
+
+// prepareReload resolves and validates the changed configuration, it returns whether the collector
+// should reload it.
+func (p *reloadingConfigProvider) prepareReload() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.factories == nil {
+		return true
+	}
+	r, err := p.resolve(context.Background())
+	if err != nil {
+		log.Printf("E! rejecting the new configuration, keeping the last known good one: %v\n", err)
+		return false
+	}
+	p.pending = r
+	return true
+}
+
+// resolve resolves, unmarshals and validates the configuration, it must be called with the lock held.
+func (p *reloadingConfigProvider) resolve(ctx context.Context) (*resolved, error) {
+	conf, err := p.provider.(otelcol.ConfmapProvider).GetConfmap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := unmarshal(ctx, conf, *p.factories)
+	if err != nil {
+		return nil, err
+	}
+	if err = cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return p.newResolved(conf, cfg), nil
+}
+
+// newResolved returns the configuration with the settings its conversion recorded.
+func (p *reloadingConfigProvider) newResolved(conf *confmap.Conf, cfg *otelcol.Config) *resolved {
+	return &resolved{
+		conf:     conf,
+		cfg:      cfg,
+		logs:     p.logs.lastSettings(),
+		aws:      p.aws.lastSettings(),
+		outbound: p.outbound.lastSettings(),
+	}
+}
+
+// resolvedScheme is the scheme of resolvedProvider.
+const resolvedScheme = "resolved"
+
+// unmarshal unmarshals the resolved configuration against the factories, without retrieving it from
+// the providers again. The upstream config provider is the only one which knows how to unmarshal the
+// whole configuration, it is given the configuration through resolvedProvider.
+func unmarshal(ctx context.Context, conf *confmap.Conf, factories otelcol.Factories) (*otelcol.Config, error) {
+	provider, err := otelcol.NewConfigProvider(otelcol.ConfigProviderSettings{
+		ResolverSettings: confmap.ResolverSettings{
+			URIs:      []string{resolvedScheme + ":conf"},
+			Providers: map[string]confmap.Provider{resolvedScheme: resolvedProvider{conf: conf}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = provider.Shutdown(ctx) }()
+	return provider.Get(ctx, factories)
+}
+
+// resolvedProvider returns a configuration which was already resolved.
+type resolvedProvider struct {
+	conf *confmap.Conf
+}
+
+var _ confmap.Provider = resolvedProvider{}
+
+func (p resolvedProvider) Retrieve(context.Context, string, confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	return confmap.NewRetrieved(p.conf.ToStringMap())
+}
+
+func (resolvedProvider) Scheme() string {
+	return resolvedScheme
+}
+
+func (resolvedProvider) Shutdown(context.Context) error {
+	return nil
+}
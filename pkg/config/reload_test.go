@@ -0,0 +1,203 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.opentelemetry.io/collector/featuregate"
+	"go.opentelemetry.io/collector/otelcol"
+	"go.opentelemetry.io/collector/processor/batchprocessor"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+	"github.com/aws-observability/aws-otel-collector/pkg/defaultcomponents"
+	"github.com/aws-observability/aws-otel-collector/pkg/outbound"
+)
+
+func batchTimeout(cfg *otelcol.Config) time.Duration {
+	return cfg.Processors[component.NewIDWithName("batch", "traces")].(*batchprocessor.Config).Timeout
+}
+
+func TestReloadingConfigProvider(t *testing.T) {
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+
+	original, err := os.ReadFile(getValidTestConfigPath())
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, original, 0600))
+
+	flagSet := Flags(featuregate.NewRegistry())
+	require.NoError(t, flagSet.Parse([]string{"--config=" + path, "--watch-config"}))
+	provider, err := NewConfigProvider(flagSet)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, provider.Shutdown(context.Background())) })
+
+	// start like the collector does
+	_, err = provider.(otelcol.ConfmapProvider).GetConfmap(context.Background())
+	require.NoError(t, err)
+	cfg, err := provider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, batchTimeout(cfg))
+	watch := provider.Watch()
+
+	// an invalid change is not reported and a forced reload keeps the last known good configuration
+	invalid := strings.Replace(string(original), "exporters: [awsxray]", "exporters: [awsxray, missing]", 1)
+	require.NoError(t, os.WriteFile(path, []byte(invalid), 0600))
+	select {
+	case <-watch:
+		t.Fatal("invalid configuration should not be reloaded")
+	case <-time.After(time.Second):
+	}
+	_, err = provider.(otelcol.ConfmapProvider).GetConfmap(context.Background())
+	require.NoError(t, err)
+	cfg, err = provider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, batchTimeout(cfg))
+
+	// a valid change is reported and returned
+	valid := strings.Replace(string(original), "timeout: 1s", "timeout: 5s", 1)
+	require.NoError(t, os.WriteFile(path, []byte(valid), 0600))
+	select {
+	case err = <-watch:
+		require.NoError(t, err)
+	case <-time.After(waitForChange):
+		t.Fatal("expected a reload")
+	}
+	_, err = provider.(otelcol.ConfmapProvider).GetConfmap(context.Background())
+	require.NoError(t, err)
+	cfg, err = provider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, batchTimeout(cfg))
+}
+
+func TestReloadingConfigProviderInvalidAtStart(t *testing.T) {
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+
+	flagSet := Flags(featuregate.NewRegistry())
+	require.NoError(t, flagSet.Parse([]string{"--config=" + filepath.Join("testdata", "invalid_config.yaml")}))
+	provider, err := NewConfigProvider(flagSet)
+	require.NoError(t, err)
+
+	_, err = provider.Get(context.Background(), factories)
+	assert.Error(t, err)
+}
+
+// countingConfigProvider resolves the configuration of the file and counts the resolutions, the
+// reloading provider must unmarshal the configuration it resolved instead of calling Get.
+type countingConfigProvider struct {
+	t           *testing.T
+	path        string
+	resolutions int
+}
+
+func (p *countingConfigProvider) GetConfmap(context.Context) (*confmap.Conf, error) {
+	p.resolutions++
+	return confmaptest.LoadConf(p.path)
+}
+
+func (p *countingConfigProvider) Get(context.Context, otelcol.Factories) (*otelcol.Config, error) {
+	p.t.Error("the configuration should be unmarshalled from the resolved one")
+	return nil, nil
+}
+
+func (p *countingConfigProvider) Watch() <-chan error {
+	return nil
+}
+
+func (p *countingConfigProvider) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestReloadingConfigProviderResolvesOnce(t *testing.T) {
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+
+	counting := &countingConfigProvider{t: t, path: getValidTestConfigPath()}
+	provider := newReloadingConfigProvider(counting, &logsConverter{}, &awsConverter{}, &outboundConverter{})
+
+	// start like the collector does
+	_, err = provider.GetConfmap(context.Background())
+	require.NoError(t, err)
+	cfg, err := provider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, batchTimeout(cfg))
+	assert.Equal(t, 1, counting.resolutions)
+
+	// a reload
+	require.True(t, provider.prepareReload())
+	_, err = provider.GetConfmap(context.Background())
+	require.NoError(t, err)
+	cfg, err = provider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, batchTimeout(cfg))
+	assert.Equal(t, 2, counting.resolutions)
+}
+
+// TestReloadingConfigProviderAppliesAcceptedSettings checks that the settings of the service block
+// apply only once the configuration is accepted, not when it is only resolved, e.g. by print-config,
+// nor when a reload is rejected.
+func TestReloadingConfigProviderAppliesAcceptedSettings(t *testing.T) {
+	for _, key := range []string{"AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_STS_REGIONAL_ENDPOINTS", "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "SSL_CERT_FILE"} {
+		t.Setenv(key, "")
+	}
+	t.Cleanup(func() {
+		require.NoError(t, SetDefaultCredentialSettings(awssession.CredentialSettings{}))
+		require.NoError(t, SetDefaultOutboundSettings(outbound.Settings{}))
+	})
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+
+	original, err := os.ReadFile(getValidTestConfigPath())
+	require.NoError(t, err)
+	withSettings := func(content, roleARN, noProxy string) []byte {
+		return []byte(strings.Replace(content, "service:\n",
+			"service:\n  aws:\n    role_arn: "+roleARN+"\n  outbound:\n    no_proxy: "+noProxy+"\n", 1))
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, withSettings(string(original), testRoleARN, "accepted.internal"), 0600))
+
+	flagSet := Flags(featuregate.NewRegistry())
+	require.NoError(t, flagSet.Parse([]string{"--config=" + path}))
+	provider, err := NewConfigProvider(flagSet)
+	require.NoError(t, err)
+
+	_, err = provider.(otelcol.ConfmapProvider).GetConfmap(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, awssession.GetCredentialSettings().RoleARN)
+	assert.Empty(t, outbound.Current().NoProxy)
+	_, err = provider.Get(context.Background(), factories)
+	require.NoError(t, err)
+	assert.Equal(t, testRoleARN, awssession.GetCredentialSettings().RoleARN)
+	assert.Equal(t, "accepted.internal", outbound.Current().NoProxy)
+
+	invalid := strings.Replace(string(original), "exporters: [awsxray]", "exporters: [awsxray, missing]", 1)
+	require.NoError(t, os.WriteFile(path, withSettings(invalid, otherRoleARN, "rejected.internal"), 0600))
+	assert.False(t, provider.(*reloadingConfigProvider).prepareReload())
+	assert.Equal(t, testRoleARN, awssession.GetCredentialSettings().RoleARN)
+	assert.Equal(t, "accepted.internal", outbound.Current().NoProxy)
+}
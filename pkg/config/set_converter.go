@@ -0,0 +1,418 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// setEditsKey holds the edits of the --set, --unset and --set-file flags which cannot be
+	// expressed as a yaml location, they are applied and removed by the setConverter.
+	setEditsKey = "__aws_otel_collector_set"
+
+	editSet   = "set"
+	editUnset = "unset"
+	editFile  = "file"
+)
+
+// plainKeyRegexp matches the keys which can be used unquoted in a yaml location.
+var plainKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_./-]*(::[A-Za-z0-9_./-]+)*$`)
+
+// setPath is the path of a --set flag, made of map keys (string) and list indices (int).
+type setPath []any
+
+// parseSetPath parses a path like processors.attributes/app.actions[0].key, a backslash escaping
+// the next character, e.g. exporters.otlphttp.headers.X-Api\.Key. The keys are the ones of the
+// resolved configuration, the path cannot reach into a list of an individual config location.
+func parseSetPath(s string) (setPath, error) {
+	var (
+		path    setPath
+		key     strings.Builder
+		hasKey  bool
+		escaped bool
+		// afterIndex is whether the last element is an index, which may be followed by a dot
+		afterIndex bool
+	)
+	endKey := func() error {
+		if !hasKey {
+			return errors.New("empty key")
+		}
+		if strings.Contains(key.String(), confmap.KeyDelimiter) {
+			return fmt.Errorf("key %q must not contain %q", key.String(), confmap.KeyDelimiter)
+		}
+		path = append(path, key.String())
+		key.Reset()
+		hasKey = false
+		return nil
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			key.WriteByte(c)
+			hasKey, escaped = true, false
+		case c == '\\':
+			escaped = true
+		case c == '.':
+			if !afterIndex {
+				if err := endKey(); err != nil {
+					return nil, err
+				}
+			}
+			afterIndex = false
+			if i == len(s)-1 {
+				return nil, errors.New("empty key")
+			}
+		case c == '[':
+			if hasKey {
+				if err := endKey(); err != nil {
+					return nil, err
+				}
+			} else if !afterIndex {
+				return nil, errors.New("index without a key")
+			}
+			end := strings.IndexByte(s[i:], ']')
+			if end == -1 {
+				return nil, errors.New("missing closing bracket")
+			}
+			index, err := strconv.Atoi(s[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index %q", s[i+1:i+end])
+			}
+			path = append(path, index)
+			afterIndex = true
+			i += end
+		case afterIndex:
+			return nil, fmt.Errorf("unexpected %q after index", c)
+		default:
+			key.WriteByte(c)
+			hasKey = true
+		}
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if !afterIndex {
+		if err := endKey(); err != nil {
+			return nil, err
+		}
+	}
+	return path, nil
+}
+
+func (p setPath) hasIndex() bool {
+	for _, elem := range p {
+		if _, ok := elem.(int); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlKey returns the key of a path without index, to be used in a yaml location.
+func (p setPath) yamlKey() string {
+	keys := make([]string, len(p))
+	for i, elem := range p {
+		keys[i] = elem.(string)
+	}
+	key := strings.Join(keys, confmap.KeyDelimiter)
+	if plainKeyRegexp.MatchString(key) {
+		return key
+	}
+	quoted, _ := json.Marshal(key)
+	return string(quoted)
+}
+
+func (p setPath) String() string {
+	var s strings.Builder
+	for i, elem := range p {
+		switch e := elem.(type) {
+		case int:
+			fmt.Fprintf(&s, "[%d]", e)
+		case string:
+			if i > 0 {
+				s.WriteByte('.')
+			}
+			s.WriteString(strings.NewReplacer(`\`, `\\`, ".", `\.`, "[", `\[`).Replace(e))
+		}
+	}
+	return s.String()
+}
+
+// splitSetFlag splits a flag value at the first unescaped equal sign.
+func splitSetFlag(s string) (string, string, error) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '=':
+			return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), nil
+		}
+	}
+	// No need for more context, see TestSetFlag/invalid_set.
+	return "", "", errors.New("missing equal sign")
+}
+
+// setLocations returns the config locations of a --set flag. A path without index is merged as a
+// yaml location, like a --config location. Other paths are recorded as an edit, the value being
+// passed through a yaml location so that it is expanded like any other.
+func (s *configFlagValue) setLocations(flagValue string) error {
+	key, value, err := splitSetFlag(flagValue)
+	if err != nil {
+		return err
+	}
+	path, err := parseSetPath(key)
+	if err != nil {
+		return fmt.Errorf("invalid path %q: %w", key, err)
+	}
+	if !path.hasIndex() {
+		s.sets = append(s.sets, "yaml:"+path.yamlKey()+": "+value)
+		return nil
+	}
+	s.addEdit(editSet, path, value)
+	return nil
+}
+
+func (s *configFlagValue) unsetLocations(flagValue string) error {
+	path, err := parseSetPath(strings.TrimSpace(flagValue))
+	if err != nil {
+		return fmt.Errorf("invalid path %q: %w", flagValue, err)
+	}
+	s.addEdit(editUnset, path, "")
+	return nil
+}
+
+func (s *configFlagValue) setFileLocations(flagValue string) error {
+	key, file, err := splitSetFlag(flagValue)
+	if err != nil {
+		return err
+	}
+	path, err := parseSetPath(key)
+	if err != nil {
+		return fmt.Errorf("invalid path %q: %w", key, err)
+	}
+	if file == "" {
+		return errors.New("missing file")
+	}
+	quoted, _ := json.Marshal(file)
+	s.addEdit(editFile, path, string(quoted))
+	return nil
+}
+
+// addEdit records an edit as yaml locations under setEditsKey, keyed by the order of the flags.
+func (s *configFlagValue) addEdit(op string, path setPath, value string) {
+	prefix := "yaml:" + setEditsKey + confmap.KeyDelimiter + strconv.Itoa(s.edits) + confmap.KeyDelimiter
+	encodedPath, _ := json.Marshal(path)
+	s.sets = append(s.sets,
+		prefix+"op: "+op,
+		prefix+"path: "+string(encodedPath),
+		prefix+"value: "+value)
+	s.edits++
+}
+
+// setEdit is an edit recorded by addEdit.
+type setEdit struct {
+	Op    string `mapstructure:"op"`
+	Path  []any  `mapstructure:"path"`
+	Value any    `mapstructure:"value"`
+}
+
+// setConverter applies the edits of the --set, --unset and --set-file flags which cannot be merged
+// as yaml locations, in the order of the flags. They are applied after all the config locations,
+// to the expanded configuration. The files of --set-file are read on every resolution, so that a
+// reload picks up their changes.
+type setConverter struct{}
+
+var _ confmap.Converter = setConverter{}
+
+func (setConverter) Convert(_ context.Context, conf *confmap.Conf) error {
+	if !conf.IsSet(setEditsKey) {
+		return nil
+	}
+	var edits map[string]setEdit
+	sub, err := conf.Sub(setEditsKey)
+	if err != nil {
+		return err
+	}
+	if err = sub.Unmarshal(&edits); err != nil {
+		return fmt.Errorf("invalid --set edits: %w", err)
+	}
+	indices := make([]int, 0, len(edits))
+	for key := range edits {
+		index, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("invalid --set edit %q", key)
+		}
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	raw := conf.ToStringMap()
+	delete(raw, setEditsKey)
+	var root any = raw
+	for _, index := range indices {
+		edit := edits[strconv.Itoa(index)]
+		path, err := decodePath(edit.Path)
+		if err != nil {
+			return err
+		}
+		switch edit.Op {
+		case editSet:
+			root, err = setValue(root, path, edit.Value)
+			if err != nil {
+				return fmt.Errorf("--set %s: %w", path, err)
+			}
+		case editUnset:
+			root = unsetValue(root, path)
+		case editFile:
+			value, err := readSetFile(fmt.Sprint(edit.Value))
+			if err != nil {
+				return fmt.Errorf("--set-file %s: %w", path, err)
+			}
+			root, err = setValue(root, path, value)
+			if err != nil {
+				return fmt.Errorf("--set-file %s: %w", path, err)
+			}
+		default:
+			return fmt.Errorf("invalid --set edit %q", edit.Op)
+		}
+	}
+	rootMap, _ := root.(map[string]any)
+	*conf = *confmap.NewFromStringMap(rootMap)
+	return nil
+}
+
+// decodePath converts the path decoded from yaml, where the indices may be of any integer type.
+func decodePath(raw []any) (setPath, error) {
+	path := make(setPath, len(raw))
+	for i, elem := range raw {
+		switch e := elem.(type) {
+		case string:
+			path[i] = e
+		case int:
+			path[i] = e
+		case int64:
+			path[i] = int(e)
+		case uint64:
+			path[i] = int(e)
+		case float64:
+			path[i] = int(e)
+		default:
+			return nil, fmt.Errorf("invalid --set path element %v", elem)
+		}
+	}
+	return path, nil
+}
+
+// setValue sets the value at the path, creating the missing maps. An index equal to the length of
+// the list appends to it.
+func setValue(node any, path setPath, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	switch elem := path[0].(type) {
+	case string:
+		m, ok := node.(map[string]any)
+		if !ok {
+			if node != nil {
+				return nil, fmt.Errorf("cannot set key %q of a %T", elem, node)
+			}
+			m = map[string]any{}
+		}
+		child, err := setValue(m[elem], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		m[elem] = child
+		return m, nil
+	default:
+		index := elem.(int)
+		list, ok := node.([]any)
+		if !ok && node != nil {
+			return nil, fmt.Errorf("cannot set index %d of a %T", index, node)
+		}
+		if index > len(list) {
+			return nil, fmt.Errorf("index %d out of range, the list has %d elements", index, len(list))
+		}
+		if index == len(list) {
+			list = append(list, nil)
+		}
+		child, err := setValue(list[index], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		list[index] = child
+		return list, nil
+	}
+}
+
+// unsetValue removes the value at the path, a missing path is left as is.
+func unsetValue(node any, path setPath) any {
+	switch elem := path[0].(type) {
+	case string:
+		m, ok := node.(map[string]any)
+		if !ok {
+			return node
+		}
+		if len(path) == 1 {
+			delete(m, elem)
+		} else if child, ok := m[elem]; ok {
+			m[elem] = unsetValue(child, path[1:])
+		}
+		return m
+	default:
+		index := elem.(int)
+		list, ok := node.([]any)
+		if !ok || index >= len(list) {
+			return node
+		}
+		if len(path) == 1 {
+			return append(list[:index], list[index+1:]...)
+		}
+		list[index] = unsetValue(list[index], path[1:])
+		return list
+	}
+}
+
+// readSetFile returns the content of a --set-file file, as a map or a list when it holds one in
+// yaml, and as a string otherwise, e.g. for a certificate.
+func readSetFile(file string) (any, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err = yaml.Unmarshal(content, &value); err == nil {
+		switch value.(type) {
+		case map[string]any, []any:
+			return value, nil
+		}
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
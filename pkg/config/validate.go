@@ -0,0 +1,252 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/otelcol"
+)
+
+const (
+	receiversKey  = "receivers"
+	processorsKey = "processors"
+	exportersKey  = "exporters"
+	connectorsKey = "connectors"
+	extensionsKey = "extensions"
+	serviceKey    = "service"
+	pipelinesKey  = "pipelines"
+)
+
+// removeKeys removes the keys of the map at the parent key, e.g. the settings of aws-otel-collector
+// which are unknown to the collector. confmap.Conf cannot delete keys, the configuration is rebuilt
+// without them.
+func removeKeys(conf *confmap.Conf, parent string, keys ...string) {
+	raw := conf.ToStringMap()
+	m := raw
+	for _, key := range strings.Split(parent, confmap.KeyDelimiter) {
+		m, _ = m[key].(map[string]any)
+	}
+	for _, key := range keys {
+		delete(m, key)
+	}
+	*conf = *confmap.NewFromStringMap(raw)
+}
+
+// ValidationResult holds every problem found while validating a configuration.
+type ValidationResult struct {
+	// Errors are the unmarshal and validation errors, each of them makes the configuration unusable.
+	Errors []error
+	// Unused lists the components which are defined but not referenced by the service, e.g. "exporters::logging".
+	Unused []string
+}
+
+// Valid returns true when no errors were found.
+func (r *ValidationResult) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// ValidateConfig resolves the configuration from the provider and checks it against the factories
+// without creating any component. Unlike otelcol.Collector.DryRun it does not stop at the first
+// problem, every component is unmarshalled and validated on its own so all errors are reported at once.
+func ValidateConfig(ctx context.Context, provider otelcol.ConfigProvider, factories otelcol.Factories) *ValidationResult {
+	result := &ValidationResult{}
+
+	cp, ok := provider.(otelcol.ConfmapProvider)
+	if !ok {
+		result.Errors = append(result.Errors, errors.New("config provider does not expose the resolved configuration"))
+		return result
+	}
+	conf, err := cp.GetConfmap(ctx)
+	if err != nil {
+		result.Errors = append(result.Errors, err)
+		return result
+	}
+
+	defined := map[string]map[string]bool{}
+	for _, kind := range []string{receiversKey, processorsKey, exportersKey, connectorsKey, extensionsKey} {
+		defined[kind] = result.validateComponents(conf, kind, factories)
+	}
+	used := result.validateService(conf, defined)
+
+	for _, kind := range []string{receiversKey, processorsKey, exportersKey, connectorsKey, extensionsKey} {
+		for id := range defined[kind] {
+			if !used[kind][id] {
+				result.Unused = append(result.Unused, kind+"::"+id)
+			}
+		}
+	}
+	sort.Strings(result.Unused)
+
+	// Only run the full collector validation when the component level checks passed, otherwise it
+	// would report the first of the errors above a second time.
+	if result.Valid() {
+		cfg, err := provider.Get(ctx, factories)
+		if err == nil {
+			err = cfg.Validate()
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+	}
+	return result
+}
+
+// validateComponents unmarshals and validates every component of the given kind, it returns the ids
+// which are defined in the configuration.
+func (r *ValidationResult) validateComponents(conf *confmap.Conf, kind string, factories otelcol.Factories) map[string]bool {
+	defined := map[string]bool{}
+	section, ok := conf.ToStringMap()[kind].(map[string]any)
+	if !ok {
+		return defined
+	}
+
+	ids := make([]string, 0, len(section))
+	for idStr := range section {
+		ids = append(ids, idStr)
+	}
+	sort.Strings(ids)
+
+	for _, idStr := range ids {
+		defined[idStr] = true
+
+		var id component.ID
+		if err := id.UnmarshalText([]byte(idStr)); err != nil {
+			r.Errors = append(r.Errors, fmt.Errorf("%s::%s: %w", kind, idStr, err))
+			continue
+		}
+		factory := lookupFactory(factories, kind, id.Type())
+		if factory == nil {
+			r.Errors = append(r.Errors, fmt.Errorf("%s::%s: unknown type %q", kind, idStr, id.Type()))
+			continue
+		}
+
+		var compConf *confmap.Conf
+		switch raw := section[idStr].(type) {
+		case nil:
+			compConf = confmap.New()
+		case map[string]any:
+			compConf = confmap.NewFromStringMap(raw)
+		default:
+			r.Errors = append(r.Errors, fmt.Errorf("%s::%s: expected a map, got %T", kind, idStr, raw))
+			continue
+		}
+
+		cfg := factory.CreateDefaultConfig()
+		if err := component.UnmarshalConfig(compConf, cfg); err != nil {
+			r.Errors = append(r.Errors, fmt.Errorf("%s::%s: error reading configuration: %w", kind, idStr, err))
+			continue
+		}
+		if err := component.ValidateConfig(cfg); err != nil {
+			r.Errors = append(r.Errors, fmt.Errorf("%s::%s: invalid configuration: %w", kind, idStr, err))
+		}
+	}
+	return defined
+}
+
+// validateService checks that the pipelines and extensions of the service only reference defined
+// components, it returns the ids referenced for each kind.
+func (r *ValidationResult) validateService(conf *confmap.Conf, defined map[string]map[string]bool) map[string]map[string]bool {
+	used := map[string]map[string]bool{
+		receiversKey:  {},
+		processorsKey: {},
+		exportersKey:  {},
+		connectorsKey: {},
+		extensionsKey: {},
+	}
+	service, _ := conf.ToStringMap()[serviceKey].(map[string]any)
+
+	for _, id := range toStringList(service[extensionsKey]) {
+		used[extensionsKey][id] = true
+		if !defined[extensionsKey][id] {
+			r.Errors = append(r.Errors, fmt.Errorf("service::extensions: references extension %q which is not configured", id))
+		}
+	}
+
+	pipelines, _ := service[pipelinesKey].(map[string]any)
+	if len(pipelines) == 0 {
+		r.Errors = append(r.Errors, errors.New("service must have at least one pipeline"))
+		return used
+	}
+
+	names := make([]string, 0, len(pipelines))
+	for name := range pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pipeline, _ := pipelines[name].(map[string]any)
+		for _, kind := range []string{receiversKey, processorsKey, exportersKey} {
+			for _, id := range toStringList(pipeline[kind]) {
+				// connectors act as exporters in one pipeline and receivers in another
+				if kind != processorsKey && defined[connectorsKey][id] {
+					used[connectorsKey][id] = true
+					continue
+				}
+				used[kind][id] = true
+				if !defined[kind][id] {
+					r.Errors = append(r.Errors, fmt.Errorf("service::pipelines::%s: references %s %q which is not configured", name, kind[:len(kind)-1], id))
+				}
+			}
+		}
+		if len(toStringList(pipeline[receiversKey])) == 0 {
+			r.Errors = append(r.Errors, fmt.Errorf("service::pipelines::%s: must have at least one receiver", name))
+		}
+		if len(toStringList(pipeline[exportersKey])) == 0 {
+			r.Errors = append(r.Errors, fmt.Errorf("service::pipelines::%s: must have at least one exporter", name))
+		}
+	}
+	return used
+}
+
+func lookupFactory(factories otelcol.Factories, kind string, typ component.Type) component.Factory {
+	var (
+		factory component.Factory
+		ok      bool
+	)
+	switch kind {
+	case receiversKey:
+		factory, ok = factories.Receivers[typ]
+	case processorsKey:
+		factory, ok = factories.Processors[typ]
+	case exportersKey:
+		factory, ok = factories.Exporters[typ]
+	case connectorsKey:
+		factory, ok = factories.Connectors[typ]
+	case extensionsKey:
+		factory, ok = factories.Extensions[typ]
+	}
+	if !ok {
+		return nil
+	}
+	return factory
+}
+
+func toStringList(v any) []string {
+	list, _ := v.([]any)
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		out = append(out, fmt.Sprint(item))
+	}
+	return out
+}
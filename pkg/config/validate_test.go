@@ -0,0 +1,112 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/featuregate"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/defaultcomponents"
+)
+
+func TestValidateConfig(t *testing.T) {
+	factories, err := defaultcomponents.Components()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		file           string
+		expectedErrors []string
+		expectedUnused []string
+	}{
+		{
+			name: "valid config",
+			file: "config.yaml",
+		},
+		{
+			name: "invalid config",
+			file: "invalid_config.yaml",
+			expectedErrors: []string{
+				`receivers::notareceiver: unknown type "notareceiver"`,
+				"processors::batch: error reading configuration",
+				`service::pipelines::traces: references exporter "awsemf" which is not configured`,
+			},
+			expectedUnused: []string{"exporters::logging", "extensions::pprof", "receivers::notareceiver"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagSet := Flags(featuregate.NewRegistry())
+			require.NoError(t, flagSet.Parse([]string{"--config=" + filepath.Join("testdata", tt.file)}))
+			provider, err := NewConfigProvider(flagSet)
+			require.NoError(t, err)
+
+			result := ValidateConfig(context.Background(), provider, factories)
+			require.Len(t, result.Errors, len(tt.expectedErrors))
+			for i, expected := range tt.expectedErrors {
+				assert.Contains(t, result.Errors[i].Error(), expected)
+			}
+			assert.Equal(t, len(tt.expectedErrors) == 0, result.Valid())
+			assert.Equal(t, tt.expectedUnused, result.Unused)
+		})
+	}
+}
+
+func TestRemoveKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		parent   string
+		keys     []string
+		expected map[string]any
+	}{
+		{
+			name:   "nested",
+			parent: "service::telemetry::logs",
+			keys:   []string{"file", "components"},
+			expected: map[string]any{
+				"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{"level": "info"}}},
+			},
+		},
+		{
+			name:   "missing parent",
+			parent: "service::aws",
+			keys:   []string{"role_arn"},
+			expected: map[string]any{
+				"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{
+					"level": "info", "file": map[string]any{"max_size": 50}, "components": map[string]any{"awsemf": "debug"},
+				}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := confmap.NewFromStringMap(map[string]any{
+				"service": map[string]any{"telemetry": map[string]any{"logs": map[string]any{
+					"level": "info", "file": map[string]any{"max_size": 50}, "components": map[string]any{"awsemf": "debug"},
+				}}},
+			})
+			removeKeys(conf, tt.parent, tt.keys...)
+			assert.Equal(t, tt.expected, conf.ToStringMap())
+		})
+	}
+}
@@ -0,0 +1,393 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/fsnotify/fsnotify"
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/dirprovider"
+	"github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/s3provider"
+)
+
+const (
+	defaultPollInterval = time.Minute
+	fingerprintTimeout  = 10 * time.Second
+)
+
+// fingerprintClient is used by httpFingerprint, its timeout bounds the requests even when they are
+// made without a deadline, unlike http.DefaultClient.
+var fingerprintClient = &http.Client{Timeout: fingerprintTimeout}
+
+// fingerprintFunc returns a value which changes whenever the content behind the uri changes.
+type fingerprintFunc func(ctx context.Context, uri string) (string, error)
+
+// watchingProvider wraps a confmap.Provider and notifies the resolver when the content behind a
+// retrieved uri changes, so that the collector reloads its configuration.
+//
+// The file provider is notified by the file system, other providers are polled on an interval and
+// compared using their fingerprint, e.g. the ETag of an S3 object.
+type watchingProvider struct {
+	confmap.Provider
+	fingerprint fingerprintFunc
+	// trigger returns the channel signaling when the fingerprint has to be checked again
+	trigger func(ctx context.Context, uri string) (<-chan struct{}, error)
+
+	mu      sync.Mutex
+	watches map[string]*watch
+}
+
+// watch is the watch of a single uri.
+type watch struct {
+	cancel context.CancelFunc
+}
+
+// newWatchingProvider wraps the provider according to its scheme, providers which cannot be
+// watched, e.g. env and yaml, are returned as is.
+func newWatchingProvider(provider confmap.Provider, pollInterval time.Duration) confmap.Provider {
+	p := &watchingProvider{
+		Provider: provider,
+		watches:  map[string]*watch{},
+	}
+	poll := func(ctx context.Context, _ string) (<-chan struct{}, error) {
+		return tick(ctx, pollInterval), nil
+	}
+
+	switch provider.Scheme() {
+	case "file":
+		p.fingerprint = fileFingerprint
+		p.trigger = fileEvents
+	case "dir":
+		p.fingerprint = dirFingerprint
+		p.trigger = dirEvents
+	case "http", "https":
+		p.fingerprint = httpFingerprint
+		p.trigger = poll
+	case "s3":
+		p.fingerprint = newS3Fingerprint()
+		p.trigger = poll
+	case "ssm", "secretsmanager":
+		p.fingerprint = p.contentFingerprint
+		p.trigger = poll
+	default:
+		return provider
+	}
+	return p
+}
+
+func (p *watchingProvider) Retrieve(ctx context.Context, uri string, watcher confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if watcher == nil {
+		return p.Provider.Retrieve(ctx, uri, nil)
+	}
+
+	// the baseline is taken before retrieving, a change in between triggers a reload instead of being missed
+	baseline, err := p.fingerprint(ctx, uri)
+	if err != nil {
+		log.Printf("W! failed to fingerprint config %q, changes will be detected once it succeeds: %v\n", uri, err)
+	}
+	stop, err := p.startWatch(uri, baseline, watcher)
+	if err != nil {
+		log.Printf("W! failed to watch config %q for changes: %v\n", uri, err)
+	}
+
+	// the watch is kept when the retrieval fails, so that fixing the source triggers a reload
+	ret, err := p.Provider.Retrieve(ctx, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ret.AsRaw()
+	if err != nil {
+		return nil, err
+	}
+	return confmap.NewRetrieved(raw, confmap.WithRetrievedClose(func(ctx context.Context) error {
+		stop()
+		return ret.Close(ctx)
+	}))
+}
+
+func (p *watchingProvider) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	for uri, w := range p.watches {
+		w.cancel()
+		delete(p.watches, uri)
+	}
+	p.mu.Unlock()
+	return p.Provider.Shutdown(ctx)
+}
+
+// startWatch replaces any previous watch of the uri, it returns the function stopping the new watch.
+func (p *watchingProvider) startWatch(uri, baseline string, watcher confmap.WatcherFunc) (func(), error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if previous, ok := p.watches[uri]; ok {
+		previous.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	trigger, err := p.trigger(ctx, uri)
+	if err != nil {
+		cancel()
+		return func() {}, err
+	}
+	w := &watch{cancel: cancel}
+	p.watches[uri] = w
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-trigger:
+				if !ok {
+					return
+				}
+			}
+			fpCtx, fpCancel := context.WithTimeout(ctx, fingerprintTimeout)
+			fingerprint, err := p.fingerprint(fpCtx, uri)
+			fpCancel()
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("W! failed to check config %q for changes: %v\n", uri, err)
+				}
+				continue
+			}
+			if fingerprint != baseline {
+				log.Printf("I! config %q changed, reloading\n", uri)
+				cancel()
+				watcher(&confmap.ChangeEvent{})
+				return
+			}
+		}
+	}()
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		cancel()
+		// a newer watch of the same uri may already be registered
+		if p.watches[uri] == w {
+			delete(p.watches, uri)
+		}
+	}, nil
+}
+
+// contentFingerprint retrieves the content again, it is used for the providers without a cheaper
+// way to know if the content changed.
+func (p *watchingProvider) contentFingerprint(ctx context.Context, uri string) (string, error) {
+	ret, err := p.Provider.Retrieve(ctx, uri, nil)
+	if err != nil {
+		return "", err
+	}
+	defer ret.Close(ctx)
+	raw, err := ret.AsRaw()
+	if err != nil {
+		return "", err
+	}
+	// json sorts the map keys, so the same content always gives the same fingerprint
+	content, err := json.Marshal(raw)
+	if err != nil {
+		return "", err
+	}
+	return hash(content), nil
+}
+
+func tick(ctx context.Context, interval time.Duration) <-chan struct{} {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	out := make(chan struct{})
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				select {
+				case out <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// fileEvents watches the directory of the file rather than the file itself, so that atomic
+// replacements, e.g. Kubernetes ConfigMap updates swapping a symlink, are noticed as well.
+func fileEvents(ctx context.Context, uri string) (<-chan struct{}, error) {
+	return dirChanges(ctx, uri, filepath.Dir(filePath(uri)))
+}
+
+// dirEvents watches the directory of a dir uri, files being added, removed or changed.
+func dirEvents(ctx context.Context, uri string) (<-chan struct{}, error) {
+	return dirChanges(ctx, uri, dirprovider.Path(uri))
+}
+
+func dirChanges(ctx context.Context, uri, dir string) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err = watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	out := make(chan struct{}, 1)
+	go func() {
+		defer close(out)
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// coalesce bursts of events, the fingerprint tells if the file really changed
+				select {
+				case out <- struct{}{}:
+				default:
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("W! error watching config %q: %v\n", uri, err)
+			}
+		}
+	}()
+	return out, nil
+}
+
+func filePath(uri string) string {
+	return filepath.Clean(strings.TrimPrefix(uri, "file:"))
+}
+
+func fileFingerprint(_ context.Context, uri string) (string, error) {
+	content, err := os.ReadFile(filePath(uri))
+	if err != nil {
+		return "", err
+	}
+	return hash(content), nil
+}
+
+// dirFingerprint hashes the names and the content of the config files of the directory.
+func dirFingerprint(_ context.Context, uri string) (string, error) {
+	files, err := dirprovider.Files(dirprovider.Path(uri))
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.Base(file), len(content))
+		h.Write(content)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// httpFingerprint uses the ETag or Last-Modified headers of a HEAD request, and falls back to the
+// hash of the body when the server returns neither.
+func httpFingerprint(ctx context.Context, uri string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, uri, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := fingerprintClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			return etag, nil
+		}
+		if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+			return lastModified, nil
+		}
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err = fingerprintClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return hash(content), nil
+}
+
+// newS3Fingerprint returns a fingerprintFunc using the ETag of the object.
+func newS3Fingerprint() fingerprintFunc {
+	clients := s3provider.NewClients("")
+	return func(ctx context.Context, uri string) (string, error) {
+		bucket, region, key, err := s3provider.Parse(uri)
+		if err != nil {
+			return "", err
+		}
+		client, err := clients.Get(region)
+		if err != nil {
+			return "", err
+		}
+		out, err := client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return "", err
+		}
+		return aws.StringValue(out.ETag), nil
+	}
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
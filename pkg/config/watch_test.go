@@ -0,0 +1,209 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/envprovider"
+	"go.opentelemetry.io/collector/confmap/provider/fileprovider"
+	"go.opentelemetry.io/collector/confmap/provider/httpprovider"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/dirprovider"
+)
+
+const waitForChange = 5 * time.Second
+
+// stubProvider returns the current content, or an error when it is empty.
+type stubProvider struct {
+	scheme  string
+	content atomic.Value
+}
+
+func (p *stubProvider) Retrieve(_ context.Context, _ string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	content := p.content.Load().(string)
+	if content == "" {
+		return nil, errors.New("no content")
+	}
+	return confmap.NewRetrieved(map[string]any{"key": content})
+}
+
+func (p *stubProvider) Scheme() string {
+	return p.scheme
+}
+
+func (p *stubProvider) Shutdown(context.Context) error {
+	return nil
+}
+
+func newChangeWatcher() (confmap.WatcherFunc, <-chan struct{}) {
+	changed := make(chan struct{}, 1)
+	return func(event *confmap.ChangeEvent) {
+		changed <- struct{}{}
+	}, changed
+}
+
+func TestWatchingProviderUnwatchedSchemes(t *testing.T) {
+	provider := envprovider.NewWithSettings(confmap.ProviderSettings{})
+	assert.Equal(t, provider, newWatchingProvider(provider, time.Millisecond))
+}
+
+func TestWatchingProviderFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("key: value"), 0600))
+
+	provider := newWatchingProvider(fileprovider.NewWithSettings(confmap.ProviderSettings{}), time.Millisecond)
+	t.Cleanup(func() { assert.NoError(t, provider.Shutdown(context.Background())) })
+	watcher, changed := newChangeWatcher()
+
+	ret, err := provider.Retrieve(context.Background(), "file:"+path, watcher)
+	require.NoError(t, err)
+	conf, err := ret.AsConf()
+	require.NoError(t, err)
+	assert.Equal(t, "value", conf.Get("key"))
+
+	// rewriting the same content is not a change
+	require.NoError(t, os.WriteFile(path, []byte("key: value"), 0600))
+	select {
+	case <-changed:
+		t.Fatal("unexpected change event")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("key: new"), 0600))
+	select {
+	case <-changed:
+	case <-time.After(waitForChange):
+		t.Fatal("expected a change event")
+	}
+	assert.NoError(t, ret.Close(context.Background()))
+}
+
+func TestWatchingProviderDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "10-base.yaml"), []byte("key: value"), 0600))
+
+	provider := newWatchingProvider(dirprovider.New(), time.Millisecond)
+	t.Cleanup(func() { assert.NoError(t, provider.Shutdown(context.Background())) })
+	watcher, changed := newChangeWatcher()
+
+	ret, err := provider.Retrieve(context.Background(), "dir:"+dir, watcher)
+	require.NoError(t, err)
+	conf, err := ret.AsConf()
+	require.NoError(t, err)
+	assert.Equal(t, "value", conf.Get("key"))
+
+	// files which are not merged are not a change
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("notes"), 0600))
+	select {
+	case <-changed:
+		t.Fatal("unexpected change event")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "20-override.yaml"), []byte("key: new"), 0600))
+	select {
+	case <-changed:
+	case <-time.After(waitForChange):
+		t.Fatal("expected a change event")
+	}
+	assert.NoError(t, ret.Close(context.Background()))
+}
+
+func TestWatchingProviderHTTP(t *testing.T) {
+	var etag atomic.Value
+	etag.Store(`"v1"`)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("ETag", etag.Load().(string))
+		if req.Method == http.MethodGet {
+			_, _ = rw.Write([]byte("key: value"))
+		}
+	}))
+	defer server.Close()
+
+	provider := newWatchingProvider(httpprovider.NewWithSettings(confmap.ProviderSettings{}), 10*time.Millisecond)
+	t.Cleanup(func() { assert.NoError(t, provider.Shutdown(context.Background())) })
+	watcher, changed := newChangeWatcher()
+
+	_, err := provider.Retrieve(context.Background(), server.URL, watcher)
+	require.NoError(t, err)
+
+	select {
+	case <-changed:
+		t.Fatal("unexpected change event")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	etag.Store(`"v2"`)
+	select {
+	case <-changed:
+	case <-time.After(waitForChange):
+		t.Fatal("expected a change event")
+	}
+}
+
+func TestHTTPFingerprintTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-done
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+
+	assert.Equal(t, fingerprintTimeout, fingerprintClient.Timeout)
+	client := fingerprintClient
+	t.Cleanup(func() { fingerprintClient = client })
+	fingerprintClient = &http.Client{Timeout: 10 * time.Millisecond}
+
+	// the server never answers, the client times out without a deadline on the context
+	_, err := httpFingerprint(context.Background(), server.URL)
+	assert.ErrorContains(t, err, "Client.Timeout exceeded")
+}
+
+func TestWatchingProviderContent(t *testing.T) {
+	stub := &stubProvider{scheme: "ssm"}
+	stub.content.Store("")
+	provider := newWatchingProvider(stub, 10*time.Millisecond)
+	t.Cleanup(func() { assert.NoError(t, provider.Shutdown(context.Background())) })
+	watcher, changed := newChangeWatcher()
+
+	// the watch is kept even though the retrieval failed, so fixing the source is noticed
+	_, err := provider.Retrieve(context.Background(), "ssm:/aoc/config", watcher)
+	require.Error(t, err)
+
+	stub.content.Store("value")
+	select {
+	case <-changed:
+	case <-time.After(waitForChange):
+		t.Fatal("expected a change event")
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	assert.Equal(t, filepath.Clean("/opt/aws/config.yaml"), filePath("file:/opt/aws/config.yaml"))
+	assert.Equal(t, filepath.Clean("testdata/config.yaml"), filePath("file:testdata/config.yaml"))
+}
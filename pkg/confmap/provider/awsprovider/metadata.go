@@ -0,0 +1,201 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package awsprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+)
+
+const (
+	imdsTokenPath       = "/latest/api/token"
+	imdsIdentityPath    = "/latest/dynamic/instance-identity/document"
+	imdsTokenHeader     = "X-aws-ec2-metadata-token"
+	imdsTokenTTLHeader  = "X-aws-ec2-metadata-token-ttl-seconds"
+	imdsTokenTTLSeconds = "60"
+	// a response larger than this is not metadata
+	maxResponseSize = 1 << 20
+)
+
+var (
+	errNotECS       = errors.New("the ECS task metadata endpoint is not available, " + envKeyECSMetadataURIV4 + " is not set")
+	errIMDSDisabled = errors.New("the EC2 instance metadata service is disabled by " + envKeyIMDSDisabled)
+)
+
+// ecsTask holds the fields of the task metadata used by the variables.
+type ecsTask struct {
+	Cluster          string `json:"Cluster"`
+	TaskARN          string `json:"TaskARN"`
+	Family           string `json:"Family"`
+	Revision         string `json:"Revision"`
+	LaunchType       string `json:"LaunchType"`
+	AvailabilityZone string `json:"AvailabilityZone"`
+}
+
+// ecsContainer holds the fields of the container metadata used by the variables.
+type ecsContainer struct {
+	Name string `json:"Name"`
+}
+
+// instanceIdentity holds the fields of the instance identity document used by the variables.
+type instanceIdentity struct {
+	AccountID        string `json:"accountId"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone"`
+	InstanceID       string `json:"instanceId"`
+	InstanceType     string `json:"instanceType"`
+	ImageID          string `json:"imageId"`
+	PrivateIP        string `json:"privateIp"`
+}
+
+func (t *ecsTask) taskARN() (arn.ARN, bool) {
+	parsed, err := arn.Parse(t.TaskARN)
+	return parsed, err == nil
+}
+
+func (t *ecsTask) region() string {
+	parsed, _ := t.taskARN()
+	return parsed.Region
+}
+
+func (t *ecsTask) accountID() string {
+	parsed, _ := t.taskARN()
+	return parsed.AccountID
+}
+
+// taskID returns the last part of the task ARN, arn:aws:ecs:region:account:task/cluster/id
+func (t *ecsTask) taskID() string {
+	return t.TaskARN[strings.LastIndex(t.TaskARN, "/")+1:]
+}
+
+// clusterName returns the name of the cluster, which is given as an ARN on Fargate and as a name
+// on EC2.
+func (t *ecsTask) clusterName() string {
+	return t.Cluster[strings.LastIndex(t.Cluster, "/")+1:]
+}
+
+func (t *ecsTask) clusterARN() string {
+	if arn.IsARN(t.Cluster) {
+		return t.Cluster
+	}
+	parsed, ok := t.taskARN()
+	if !ok {
+		return ""
+	}
+	parsed.Resource = "cluster/" + t.Cluster
+	return parsed.String()
+}
+
+func (p *provider) getECSTask(ctx context.Context) (*ecsTask, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.task != nil {
+		return p.task, nil
+	}
+	if p.ecsEndpoint == "" {
+		return nil, errNotECS
+	}
+	task := &ecsTask{}
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.ecsEndpoint, "/")+"/task", nil, task); err != nil {
+		return nil, fmt.Errorf("failed to get the ECS task metadata: %w", err)
+	}
+	p.task = task
+	return task, nil
+}
+
+func (p *provider) getECSContainer(ctx context.Context) (*ecsContainer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.container != nil {
+		return p.container, nil
+	}
+	if p.ecsEndpoint == "" {
+		return nil, errNotECS
+	}
+	container := &ecsContainer{}
+	if err := p.getJSON(ctx, p.ecsEndpoint, nil, container); err != nil {
+		return nil, fmt.Errorf("failed to get the ECS container metadata: %w", err)
+	}
+	p.container = container
+	return container, nil
+}
+
+func (p *provider) getInstanceIdentity(ctx context.Context) (*instanceIdentity, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.identity != nil {
+		return p.identity, nil
+	}
+	if p.imdsEndpoint == "" {
+		return nil, errIMDSDisabled
+	}
+	endpoint := strings.TrimSuffix(p.imdsEndpoint, "/")
+	token, err := p.request(ctx, http.MethodPut, endpoint+imdsTokenPath, http.Header{imdsTokenTTLHeader: {imdsTokenTTLSeconds}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get an IMDSv2 token: %w", err)
+	}
+	identity := &instanceIdentity{}
+	if err = p.getJSON(ctx, endpoint+imdsIdentityPath, http.Header{imdsTokenHeader: {string(token)}}, identity); err != nil {
+		return nil, fmt.Errorf("failed to get the EC2 instance identity document: %w", err)
+	}
+	p.identity = identity
+	return identity, nil
+}
+
+func (p *provider) getJSON(ctx context.Context, url string, header http.Header, v any) error {
+	body, err := p.request(ctx, http.MethodGet, url, header)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", url, err)
+	}
+	return nil
+}
+
+func (p *provider) request(ctx context.Context, method, url string, header http.Header) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s returned %s", method, url, resp.Status)
+	}
+	return body, nil
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package awsprovider // import "github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/awsprovider"
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	schemeName = "aws"
+
+	envKeyECSMetadataURIV4 = "ECS_CONTAINER_METADATA_URI_V4"
+	envKeyIMDSEndpoint     = "AWS_EC2_METADATA_SERVICE_ENDPOINT"
+	envKeyIMDSDisabled     = "AWS_EC2_METADATA_DISABLED"
+	defaultIMDSEndpoint    = "http://169.254.169.254"
+	// defaultTimeout bounds each request to the metadata services, IMDS is not reachable outside
+	// of EC2 and requests to it would otherwise hang until the resolution of the configuration fails.
+	defaultTimeout = 2 * time.Second
+)
+
+// variable resolves a variable from the metadata, it fails when its source is not available.
+type variable func(ctx context.Context, p *provider) (string, error)
+
+var variables = map[string]variable{
+	"region":            fromECSOrEC2(func(t *ecsTask) string { return t.region() }, func(i *instanceIdentity) string { return i.Region }),
+	"account_id":        fromECSOrEC2(func(t *ecsTask) string { return t.accountID() }, func(i *instanceIdentity) string { return i.AccountID }),
+	"availability_zone": fromECSOrEC2(func(t *ecsTask) string { return t.AvailabilityZone }, func(i *instanceIdentity) string { return i.AvailabilityZone }),
+	"instance_id":       fromEC2(func(i *instanceIdentity) string { return i.InstanceID }),
+	"instance_type":     fromEC2(func(i *instanceIdentity) string { return i.InstanceType }),
+	"image_id":          fromEC2(func(i *instanceIdentity) string { return i.ImageID }),
+	"private_ip":        fromEC2(func(i *instanceIdentity) string { return i.PrivateIP }),
+	"ecs.cluster":       fromECS(func(t *ecsTask) string { return t.clusterName() }),
+	"ecs.cluster_arn":   fromECS(func(t *ecsTask) string { return t.clusterARN() }),
+	"ecs.task_arn":      fromECS(func(t *ecsTask) string { return t.TaskARN }),
+	"ecs.task_id":       fromECS(func(t *ecsTask) string { return t.taskID() }),
+	"ecs.task_family":   fromECS(func(t *ecsTask) string { return t.Family }),
+	"ecs.task_revision": fromECS(func(t *ecsTask) string { return t.Revision }),
+	"ecs.launch_type":   fromECS(func(t *ecsTask) string { return t.LaunchType }),
+	"ecs.container_name": func(ctx context.Context, p *provider) (string, error) {
+		container, err := p.getECSContainer(ctx)
+		if err != nil {
+			return "", err
+		}
+		return container.Name, nil
+	},
+}
+
+type provider struct {
+	client *http.Client
+	// ecsEndpoint is the ECS task metadata endpoint v4, empty when not running on ECS
+	ecsEndpoint string
+	// imdsEndpoint is the EC2 instance metadata endpoint, empty when disabled
+	imdsEndpoint string
+	timeout      time.Duration
+
+	// the metadata is cached once fetched, failures are not so that they are retried when the
+	// configuration is resolved again
+	mu        sync.Mutex
+	task      *ecsTask
+	container *ecsContainer
+	identity  *instanceIdentity
+}
+
+// New returns a new confmap.Provider that resolves variables from the EC2 instance metadata
+// service (IMDSv2) and the ECS task metadata endpoint v4.
+//
+// This Provider supports "aws" scheme, and can be called with a "uri" that follows:
+//
+//	aws-uri : aws:[VARIABLE]
+//
+// On ECS, the region, account_id and availability_zone come from the task metadata, otherwise
+// from the instance identity document. The ecs.* variables are only available on ECS and the
+// instance variables only where IMDS can be reached. The metadata is fetched once and cached.
+//
+// Examples:
+// `${aws:region}`
+// `/aws/ecs/${aws:ecs.cluster}/metrics`
+func New() confmap.Provider {
+	p := &provider{
+		client:       &http.Client{},
+		ecsEndpoint:  os.Getenv(envKeyECSMetadataURIV4),
+		imdsEndpoint: defaultIMDSEndpoint,
+		timeout:      defaultTimeout,
+	}
+	if endpoint := os.Getenv(envKeyIMDSEndpoint); endpoint != "" {
+		p.imdsEndpoint = endpoint
+	}
+	if strings.EqualFold(os.Getenv(envKeyIMDSDisabled), "true") {
+		p.imdsEndpoint = ""
+	}
+	return p
+}
+
+func (p *provider) Retrieve(ctx context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if !strings.HasPrefix(uri, schemeName+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+	name := uri[len(schemeName)+1:]
+	resolve, ok := variables[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown AWS variable %q, available: %s", name, strings.Join(Variables(), ", "))
+	}
+	value, err := resolve(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve AWS variable %q: %w", name, err)
+	}
+	if value == "" {
+		return nil, fmt.Errorf("AWS variable %q is empty in the metadata", name)
+	}
+	return confmap.NewRetrieved(value)
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+func (*provider) Shutdown(context.Context) error {
+	return nil
+}
+
+// Variables returns the sorted names of the supported variables.
+func Variables() []string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func fromECS(get func(*ecsTask) string) variable {
+	return func(ctx context.Context, p *provider) (string, error) {
+		task, err := p.getECSTask(ctx)
+		if err != nil {
+			return "", err
+		}
+		return get(task), nil
+	}
+}
+
+func fromEC2(get func(*instanceIdentity) string) variable {
+	return func(ctx context.Context, p *provider) (string, error) {
+		identity, err := p.getInstanceIdentity(ctx)
+		if err != nil {
+			return "", err
+		}
+		return get(identity), nil
+	}
+}
+
+// fromECSOrEC2 uses the task metadata when running on ECS, since IMDS is not available on Fargate
+// and may be blocked for the tasks on EC2.
+func fromECSOrEC2(ecs func(*ecsTask) string, ec2 func(*instanceIdentity) string) variable {
+	return func(ctx context.Context, p *provider) (string, error) {
+		if p.ecsEndpoint != "" {
+			return fromECS(ecs)(ctx, p)
+		}
+		return fromEC2(ec2)(ctx, p)
+	}
+}
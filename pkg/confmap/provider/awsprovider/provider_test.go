@@ -0,0 +1,268 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package awsprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/yamlprovider"
+)
+
+const (
+	testToken    = "AQAEAFTNrA4eEGx0AQgJ1arIq_Cc-t4tWt3fB0Hd8RKhXlKc5ccvhg=="
+	testIdentity = `{
+  "accountId" : "123456789012",
+  "architecture" : "x86_64",
+  "availabilityZone" : "us-west-2b",
+  "imageId" : "ami-0123456789abcdef0",
+  "instanceId" : "i-0123456789abcdef0",
+  "instanceType" : "m5.large",
+  "privateIp" : "10.0.1.25",
+  "region" : "us-west-2"
+}`
+	testFargateTask = `{
+  "Cluster": "arn:aws:ecs:us-east-1:111122223333:cluster/prod",
+  "TaskARN": "arn:aws:ecs:us-east-1:111122223333:task/prod/5ef0a9cbb3a84ab5b0b5e45e2c58fb9a",
+  "Family": "checkout",
+  "Revision": "7",
+  "LaunchType": "FARGATE",
+  "AvailabilityZone": "us-east-1a"
+}`
+	testEC2Task = `{
+  "Cluster": "default",
+  "TaskARN": "arn:aws:ecs:us-west-2:123456789012:task/default/158d1c8083dd49d6b527399fd6414f5c",
+  "Family": "otel",
+  "Revision": "2",
+  "LaunchType": "EC2",
+  "AvailabilityZone": "us-west-2b"
+}`
+	testContainer = `{"DockerId": "cd189a933e5849daa93386466019ab50", "Name": "aws-otel-collector"}`
+)
+
+// newIMDS serves the IMDSv2 token and the instance identity document, the requests are counted.
+func newIMDS(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		switch {
+		case req.Method == http.MethodPut && req.URL.Path == imdsTokenPath:
+			assert.NotEmpty(t, req.Header.Get(imdsTokenTTLHeader))
+			_, _ = rw.Write([]byte(testToken))
+		case req.Method == http.MethodGet && req.URL.Path == imdsIdentityPath:
+			if req.Header.Get(imdsTokenHeader) != testToken {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = rw.Write([]byte(testIdentity))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newECS serves the task and container metadata of the ECS task metadata endpoint v4.
+func newECS(t *testing.T, task string, requests *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		switch req.URL.Path {
+		case "/v4/abc/task":
+			_, _ = rw.Write([]byte(task))
+		case "/v4/abc":
+			_, _ = rw.Write([]byte(testContainer))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestProvider(ecsEndpoint, imdsEndpoint string) *provider {
+	return &provider{
+		client:       &http.Client{},
+		ecsEndpoint:  ecsEndpoint,
+		imdsEndpoint: imdsEndpoint,
+		timeout:      defaultTimeout,
+	}
+}
+
+func TestRetrieve(t *testing.T) {
+	var requests atomic.Int32
+	imds := newIMDS(t, &requests)
+	fargate := newECS(t, testFargateTask, &requests)
+	ecsOnEC2 := newECS(t, testEC2Task, &requests)
+
+	tests := []struct {
+		name        string
+		ecs         string
+		imds        string
+		expected    map[string]string
+		expectedErr map[string]string
+	}{
+		{
+			name: "ec2",
+			imds: imds.URL,
+			expected: map[string]string{
+				"region":            "us-west-2",
+				"account_id":        "123456789012",
+				"availability_zone": "us-west-2b",
+				"instance_id":       "i-0123456789abcdef0",
+				"instance_type":     "m5.large",
+				"image_id":          "ami-0123456789abcdef0",
+				"private_ip":        "10.0.1.25",
+			},
+			expectedErr: map[string]string{
+				"ecs.cluster": "ECS_CONTAINER_METADATA_URI_V4 is not set",
+			},
+		},
+		{
+			name: "fargate",
+			ecs:  fargate.URL + "/v4/abc",
+			expected: map[string]string{
+				"region":             "us-east-1",
+				"account_id":         "111122223333",
+				"availability_zone":  "us-east-1a",
+				"ecs.cluster":        "prod",
+				"ecs.cluster_arn":    "arn:aws:ecs:us-east-1:111122223333:cluster/prod",
+				"ecs.task_arn":       "arn:aws:ecs:us-east-1:111122223333:task/prod/5ef0a9cbb3a84ab5b0b5e45e2c58fb9a",
+				"ecs.task_id":        "5ef0a9cbb3a84ab5b0b5e45e2c58fb9a",
+				"ecs.task_family":    "checkout",
+				"ecs.task_revision":  "7",
+				"ecs.launch_type":    "FARGATE",
+				"ecs.container_name": "aws-otel-collector",
+			},
+			expectedErr: map[string]string{
+				"instance_id": "disabled",
+			},
+		},
+		{
+			name: "ecs on ec2",
+			ecs:  ecsOnEC2.URL + "/v4/abc",
+			imds: imds.URL,
+			expected: map[string]string{
+				"region":          "us-west-2",
+				"ecs.cluster":     "default",
+				"ecs.cluster_arn": "arn:aws:ecs:us-west-2:123456789012:cluster/default",
+				"instance_id":     "i-0123456789abcdef0",
+			},
+		},
+		{
+			name: "errors",
+			ecs:  fargate.URL + "/v4/missing",
+			imds: imds.URL + "/missing",
+			expectedErr: map[string]string{
+				"hostname":    `unknown AWS variable "hostname", available: account_id, availability_zone`,
+				"ecs.cluster": "failed to get the ECS task metadata",
+				"instance_id": "failed to get an IMDSv2 token",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProvider(tt.ecs, tt.imds)
+			for name, expected := range tt.expected {
+				ret, err := p.Retrieve(context.Background(), "aws:"+name, nil)
+				require.NoError(t, err, name)
+				raw, err := ret.AsRaw()
+				require.NoError(t, err)
+				assert.Equal(t, expected, raw, name)
+			}
+			for name, expectedErr := range tt.expectedErr {
+				_, err := p.Retrieve(context.Background(), "aws:"+name, nil)
+				assert.ErrorContains(t, err, expectedErr, name)
+			}
+		})
+	}
+}
+
+func TestRetrieveCaches(t *testing.T) {
+	var imdsRequests, ecsRequests atomic.Int32
+	imds := newIMDS(t, &imdsRequests)
+	ecs := newECS(t, testEC2Task, &ecsRequests)
+
+	p := newTestProvider(ecs.URL+"/v4/abc", imds.URL)
+	for i := 0; i < 3; i++ {
+		for _, name := range []string{"region", "ecs.task_arn", "ecs.container_name", "instance_id", "instance_type"} {
+			_, err := p.Retrieve(context.Background(), "aws:"+name, nil)
+			require.NoError(t, err)
+		}
+	}
+	// one token and one identity document request, one task and one container request
+	assert.Equal(t, int32(2), imdsRequests.Load())
+	assert.Equal(t, int32(2), ecsRequests.Load())
+}
+
+func TestRetrieveTimeout(t *testing.T) {
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-blocked:
+		case <-req.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		close(blocked)
+		server.Close()
+	})
+
+	p := newTestProvider("", server.URL)
+	p.timeout = 50 * time.Millisecond
+	start := time.Now()
+	_, err := p.Retrieve(context.Background(), "aws:instance_id", nil)
+	assert.ErrorContains(t, err, "failed to get an IMDSv2 token")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRetrieveEmbedded(t *testing.T) {
+	var requests atomic.Int32
+	ecs := newECS(t, testFargateTask, &requests)
+
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"yaml:exporters::awsemf::log_group_name: /aws/ecs/${aws:ecs.cluster}/${aws:ecs.task_family}"},
+		Providers: map[string]confmap.Provider{
+			"yaml": yamlprovider.NewWithSettings(confmap.ProviderSettings{}),
+			"aws":  newTestProvider(ecs.URL+"/v4/abc", ""),
+		},
+	})
+	require.NoError(t, err)
+	conf, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "/aws/ecs/prod/checkout", conf.Get("exporters::awsemf::log_group_name"))
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv(envKeyECSMetadataURIV4, "http://169.254.170.2/v4/abc")
+	t.Setenv(envKeyIMDSEndpoint, "http://[fd00:ec2::254]")
+	p := New().(*provider)
+	assert.Equal(t, "http://169.254.170.2/v4/abc", p.ecsEndpoint)
+	assert.Equal(t, "http://[fd00:ec2::254]", p.imdsEndpoint)
+
+	t.Setenv(envKeyIMDSDisabled, "true")
+	assert.Empty(t, New().(*provider).imdsEndpoint)
+
+	assert.Equal(t, "aws", New().Scheme())
+	assert.NoError(t, New().Shutdown(context.Background()))
+}
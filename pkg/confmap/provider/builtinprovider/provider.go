@@ -0,0 +1,100 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package builtinprovider // import "github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/builtinprovider"
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+	"gopkg.in/yaml.v3"
+
+	builtinconfig "github.com/aws-observability/aws-otel-collector/config"
+)
+
+const (
+	schemeName = "builtin"
+	extension  = ".yaml"
+)
+
+type provider struct {
+	fsys fs.FS
+}
+
+// New returns a new confmap.Provider that reads the configurations embedded in the collector.
+//
+// This Provider supports "builtin" scheme, and can be called with a "uri" that follows:
+//
+//	builtin-uri : builtin:[NAME]
+//
+// The name is the path of the configuration in the config directory of the repository, without
+// the .yaml extension.
+//
+// Examples:
+// `builtin:ecs/ecs-amp-xray`
+// `builtin:eks/prometheus/config-all`
+func New() confmap.Provider {
+	return &provider{fsys: builtinconfig.FS}
+}
+
+func (p *provider) Retrieve(_ context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if !strings.HasPrefix(uri, schemeName+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+	name := strings.TrimPrefix(uri, schemeName+":")
+	if !fs.ValidPath(name) || path.Ext(name) != "" {
+		return nil, fmt.Errorf("invalid built-in config name %q", name)
+	}
+
+	content, err := fs.ReadFile(p.fsys, name+extension)
+	if err != nil {
+		return nil, fmt.Errorf("unknown built-in config %q, available: %s", name, strings.Join(names(p.fsys), ", "))
+	}
+	var rawConf any
+	if err = yaml.Unmarshal(content, &rawConf); err != nil {
+		return nil, fmt.Errorf("failed to parse built-in config %q: %w", name, err)
+	}
+	return confmap.NewRetrieved(rawConf)
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+func (*provider) Shutdown(context.Context) error {
+	return nil
+}
+
+// Names returns the sorted names of the built-in configurations, e.g. ecs/ecs-amp-xray.
+func Names() []string {
+	return names(builtinconfig.FS)
+}
+
+func names(fsys fs.FS) []string {
+	var names []string
+	_ = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && path.Ext(p) == extension {
+			names = append(names, strings.TrimSuffix(p, extension))
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names
+}
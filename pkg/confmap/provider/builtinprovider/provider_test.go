@@ -0,0 +1,108 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package builtinprovider
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRetrieve(t *testing.T) {
+	p := &provider{fsys: fstest.MapFS{
+		"ecs/default.yaml":           {Data: []byte("receivers:\n  otlp:\n")},
+		"eks/prometheus/config.yaml": {Data: []byte("exporters:\n  awsemf:\n")},
+		"eks/README.md":              {Data: []byte("not a config")},
+	}}
+
+	testCases := []struct {
+		name     string
+		uri      string
+		expected map[string]any
+		errMsg   string
+	}{
+		{
+			name:     "Config",
+			uri:      "builtin:ecs/default",
+			expected: map[string]any{"receivers": map[string]any{"otlp": nil}},
+		},
+		{
+			name:     "NestedConfig",
+			uri:      "builtin:eks/prometheus/config",
+			expected: map[string]any{"exporters": map[string]any{"awsemf": nil}},
+		},
+		{
+			name:   "Unknown",
+			uri:    "builtin:ecs/unknown",
+			errMsg: `unknown built-in config "ecs/unknown", available: ecs/default, eks/prometheus/config`,
+		},
+		{
+			name:   "WithExtension",
+			uri:    "builtin:ecs/default.yaml",
+			errMsg: `invalid built-in config name "ecs/default.yaml"`,
+		},
+		{
+			name:   "OutsideOfTheConfigs",
+			uri:    "builtin:../go.mod",
+			errMsg: `invalid built-in config name "../go.mod"`,
+		},
+		{
+			name:   "WrongScheme",
+			uri:    "file:ecs/default",
+			errMsg: `"file:ecs/default" uri is not supported by "builtin" provider`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ret, err := p.Retrieve(context.Background(), tc.uri, nil)
+			if tc.errMsg != "" {
+				assert.EqualError(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			raw, err := ret.AsRaw()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, raw)
+		})
+	}
+}
+
+func TestBuiltinConfigs(t *testing.T) {
+	p := New()
+	names := Names()
+	assert.Contains(t, names, "ecs/ecs-amp-xray")
+	assert.Contains(t, names, "eks/prometheus/config-all")
+	for _, name := range names {
+		_, err := p.Retrieve(context.Background(), "builtin:"+name, nil)
+		assert.NoError(t, err, name)
+	}
+
+	// the EC2 default is the config.yaml installed by the packages
+	packaged, err := os.ReadFile("../../../../config.yaml")
+	require.NoError(t, err)
+	ret, err := p.Retrieve(context.Background(), "builtin:ec2/ec2-default-config", nil)
+	require.NoError(t, err)
+	builtin, err := ret.AsRaw()
+	require.NoError(t, err)
+	var expected any
+	require.NoError(t, yaml.Unmarshal(packaged, &expected))
+	assert.Equal(t, expected, builtin)
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package dirprovider // import "github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/dirprovider"
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/collector/confmap"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	schemeName = "dir"
+	extension  = ".yaml"
+)
+
+type provider struct{}
+
+// New returns a new confmap.Provider that merges the configuration files of a directory.
+//
+// This Provider supports "dir" scheme, and can be called with a "uri" that follows:
+//
+//	dir-uri : dir:[PATH]
+//
+// Every *.yaml file of the directory is merged in the lexical order of the file names, later
+// files taking precedence like later --config locations. Sub directories and other files are
+// ignored, an empty directory gives an empty configuration.
+//
+// Examples:
+// `dir:/opt/aws/aws-otel-collector/etc/conf.d`
+// `dir:C:\ProgramData\Amazon\AWSOTelCollector\Configs\conf.d` - (Windows)
+func New() confmap.Provider {
+	return &provider{}
+}
+
+func (*provider) Retrieve(_ context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if !strings.HasPrefix(uri, schemeName+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+	dir := Path(uri)
+	files, err := Files(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := confmap.New()
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %q: %w", file, err)
+		}
+		var rawConf map[string]any
+		if err = yaml.Unmarshal(content, &rawConf); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %q: %w", file, err)
+		}
+		if err = conf.Merge(confmap.NewFromStringMap(rawConf)); err != nil {
+			return nil, fmt.Errorf("failed to merge config file %q: %w", file, err)
+		}
+	}
+	return confmap.NewRetrieved(conf.ToStringMap())
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+func (*provider) Shutdown(context.Context) error {
+	return nil
+}
+
+// Path returns the path of the directory of a dir uri.
+func Path(uri string) string {
+	return filepath.Clean(strings.TrimPrefix(uri, schemeName+":"))
+}
+
+// Files returns the paths of the configuration files of the directory, sorted by file name.
+func Files(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory %q: %w", dir, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != extension {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// entries may be symlinks, e.g. in a Kubernetes ConfigMap volume
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %q: %w", path, err)
+		}
+		if info.IsDir() {
+			continue
+		}
+		// the entries are sorted by file name
+		files = append(files, path)
+	}
+	return files, nil
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package dirprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+	return dir
+}
+
+func TestRetrieve(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		expected    map[string]any
+		expectedErr string
+	}{
+		{
+			name: "lexical order",
+			files: map[string]string{
+				"20-exporters.yaml": "exporters:\n  awsxray:\n    region: us-east-1\n",
+				"10-receivers.yaml": "receivers:\n  otlp:\nexporters:\n  awsxray:\n    region: us-west-2\n    local_mode: true\n",
+				"30-empty.yaml":     "",
+			},
+			expected: map[string]any{
+				"receivers": map[string]any{"otlp": nil},
+				"exporters": map[string]any{"awsxray": map[string]any{"region": "us-east-1", "local_mode": true}},
+			},
+		},
+		{
+			name: "other files are ignored",
+			files: map[string]string{
+				"config.yaml":         "receivers:\n  otlp:\n",
+				"config.yaml.rpmsave": "receivers:\n  awsxray:\n",
+				"config.yml":          "receivers:\n  statsd:\n",
+				"sub/nested.yaml":     "receivers:\n  zipkin:\n",
+			},
+			expected: map[string]any{"receivers": map[string]any{"otlp": nil}},
+		},
+		{
+			name:     "empty directory",
+			expected: map[string]any{},
+		},
+		{
+			name: "invalid file",
+			files: map[string]string{
+				"10-valid.yaml":   "receivers:\n  otlp:\n",
+				"20-invalid.yaml": "receivers:\n  otlp\n  awsxray:\n",
+			},
+			expectedErr: `failed to parse config file "DIR/20-invalid.yaml"`,
+		},
+		{
+			name:        "not a map",
+			files:       map[string]string{"list.yaml": "- otlp\n- awsxray\n"},
+			expectedErr: `failed to parse config file "DIR/list.yaml"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, tt.files)
+			ret, err := New().Retrieve(context.Background(), "dir:"+dir, nil)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, filepath.FromSlash(strings.ReplaceAll(tt.expectedErr, "DIR", dir)))
+				return
+			}
+			require.NoError(t, err)
+			raw, err := ret.AsRaw()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, raw)
+		})
+	}
+}
+
+func TestRetrieveErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	_, err := New().Retrieve(context.Background(), "dir:"+missing, nil)
+	assert.ErrorContains(t, err, `failed to read config directory "`+missing+`"`)
+
+	dir := t.TempDir()
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing.yaml"), filepath.Join(dir, "dangling.yaml")))
+	_, err = New().Retrieve(context.Background(), "dir:"+dir, nil)
+	assert.ErrorContains(t, err, `failed to read config file "`+filepath.Join(dir, "dangling.yaml")+`"`)
+
+	_, err = New().Retrieve(context.Background(), "file:"+dir, nil)
+	assert.ErrorContains(t, err, "is not supported")
+}
+
+func TestScheme(t *testing.T) {
+	assert.Equal(t, "dir", New().Scheme())
+	assert.NoError(t, New().Shutdown(context.Background()))
+}
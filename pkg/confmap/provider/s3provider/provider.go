@@ -0,0 +1,154 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package s3provider // import "github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/s3provider"
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"go.opentelemetry.io/collector/confmap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+)
+
+const schemeName = "s3"
+
+// s3Regexp matches the supported uris, s3://[BUCKET].s3.[REGION].amazonaws.com/[KEY]
+var s3Regexp = regexp.MustCompile(`^s3:\/\/([a-z0-9\.\-]{3,63})\.s3\.([a-z0-9\-]+)\.amazonaws\.com\/(.+)$`)
+
+// Client is the part of the S3 API used by the provider and the watch of the configuration.
+type Client interface {
+	GetObjectWithContext(aws.Context, *s3.GetObjectInput, ...request.Option) (*s3.GetObjectOutput, error)
+	HeadObjectWithContext(aws.Context, *s3.HeadObjectInput, ...request.Option) (*s3.HeadObjectOutput, error)
+}
+
+type provider struct {
+	clients *Clients
+}
+
+// New returns a new confmap.Provider that reads the configuration from a file in S3.
+//
+// This Provider supports "s3" scheme, and can be called with a "uri" that follows:
+//
+//	s3-uri : s3://[BUCKET].s3.[REGION].amazonaws.com/[KEY]
+//
+// It replaces the s3provider of opentelemetry-collector-contrib, with the same uris, so that the
+// objects are read with the sessions of awssession and their credential settings.
+//
+// Examples:
+// `s3://DOC-EXAMPLE-BUCKET.s3.us-west-2.amazonaws.com/photos/puppy.jpg` - (unix, windows)
+func New() confmap.Provider {
+	return &provider{clients: NewClients("")}
+}
+
+func (p *provider) Retrieve(ctx context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if !strings.HasPrefix(uri, schemeName+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+	bucket, region, key, err := Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.clients.Get(region)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("file in S3 failed to fetch uri %q: %w", uri, err)
+	}
+	defer out.Body.Close()
+
+	content, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("file in S3 failed to read uri %q: %w", uri, err)
+	}
+	// a reference within a value, e.g. ${s3:...}, retrieves a scalar
+	var rawConf any
+	if err = yaml.Unmarshal(content, &rawConf); err != nil {
+		return nil, fmt.Errorf("file in S3 %q is not a valid configuration: %w", uri, err)
+	}
+	return confmap.NewRetrieved(rawConf)
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+func (*provider) Shutdown(context.Context) error {
+	return nil
+}
+
+// Parse returns the bucket, the region and the key of an s3 uri.
+func Parse(uri string) (bucket, region, key string, err error) {
+	matches := s3Regexp.FindStringSubmatch(uri)
+	if matches == nil {
+		return "", "", "", fmt.Errorf("%q is not a valid s3 uri", uri)
+	}
+	key, err = url.PathUnescape(matches[3])
+	if err != nil {
+		return "", "", "", fmt.Errorf("%q is not a valid s3 uri: %w", uri, err)
+	}
+	return matches[1], matches[2], key, nil
+}
+
+// Clients creates the S3 clients on first use, one per region.
+type Clients struct {
+	// endpoint overrides the S3 endpoint, it is only set by tests
+	endpoint string
+
+	mu      sync.Mutex
+	clients map[string]Client
+	// settings are the credential settings of the clients, which are created again when they change
+	settings awssession.CredentialSettings
+}
+
+// NewClients returns the clients of the endpoint, empty for the endpoint of each region.
+func NewClients(endpoint string) *Clients {
+	return &Clients{endpoint: endpoint, clients: map[string]Client{}}
+}
+
+// Get returns the client of the region.
+func (c *Clients) Get(region string) (Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if settings := awssession.GetCredentialSettings(); settings != c.settings {
+		c.clients, c.settings = map[string]Client{}, settings
+	}
+	if client, ok := c.clients[region]; ok {
+		return client, nil
+	}
+	sess, err := awssession.New(region, c.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	// the endpoints of the tests do not resolve the bucket as a sub domain
+	client := s3.New(sess, aws.NewConfig().WithS3ForcePathStyle(c.endpoint != ""))
+	c.clients[region] = client
+	return client, nil
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package s3provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+)
+
+// setUpMockS3 serves GetObject requests from the given objects, keyed by the requested path.
+func setUpMockS3(t *testing.T, objects map[string]string) *httptest.Server {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodGet, req.Method)
+		object, ok := objects[req.URL.Path]
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			_, _ = rw.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`))
+			return
+		}
+		_, _ = rw.Write([]byte(object))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRetrieve(t *testing.T) {
+	server := setUpMockS3(t, map[string]string{
+		"/aoc-bucket/config.yaml":        "receivers:\n  otlp:\n",
+		"/aoc-bucket/configs/a b.yaml":   "exporters:\n  awsxray:\n",
+		"/aoc-bucket/invalid/config.yml": "receivers: [",
+		"/aoc-bucket/endpoint":           "collector.example.com:4317\n",
+	})
+
+	tests := []struct {
+		name        string
+		uri         string
+		expected    any
+		expectedErr string
+	}{
+		{
+			name:     "object",
+			uri:      "s3://aoc-bucket.s3.us-west-2.amazonaws.com/config.yaml",
+			expected: map[string]any{"receivers": map[string]any{"otlp": nil}},
+		},
+		{
+			name:     "escaped key",
+			uri:      "s3://aoc-bucket.s3.us-west-2.amazonaws.com/configs/a%20b.yaml",
+			expected: map[string]any{"exporters": map[string]any{"awsxray": nil}},
+		},
+		{
+			name:     "scalar",
+			uri:      "s3://aoc-bucket.s3.us-west-2.amazonaws.com/endpoint",
+			expected: "collector.example.com:4317",
+		},
+		{
+			name:        "missing object",
+			uri:         "s3://aoc-bucket.s3.us-west-2.amazonaws.com/missing.yaml",
+			expectedErr: `file in S3 failed to fetch uri`,
+		},
+		{
+			name:        "invalid yaml",
+			uri:         "s3://aoc-bucket.s3.us-west-2.amazonaws.com/invalid/config.yml",
+			expectedErr: "is not a valid configuration",
+		},
+		{
+			name:        "invalid uri",
+			uri:         "s3://aoc-bucket/config.yaml",
+			expectedErr: "is not a valid s3 uri",
+		},
+		{
+			name:        "wrong scheme",
+			uri:         "ssm:/aoc/config",
+			expectedErr: "is not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &provider{clients: NewClients(server.URL)}
+			ret, err := p.Retrieve(context.Background(), tt.uri, nil)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			raw, err := ret.AsRaw()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, raw)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	bucket, region, key, err := Parse("s3://doc-example-bucket.s3.eu-west-1.amazonaws.com/photos/puppy%2B1.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "doc-example-bucket", bucket)
+	assert.Equal(t, "eu-west-1", region)
+	assert.Equal(t, "photos/puppy+1.yaml", key)
+
+	_, _, _, err = Parse("s3://doc-example-bucket.s3.eu-west-1.amazonaws.com/")
+	assert.ErrorContains(t, err, "is not a valid s3 uri")
+}
+
+func TestClients(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	clients := NewClients("")
+	west, err := clients.Get("us-west-2")
+	require.NoError(t, err)
+	again, err := clients.Get("us-west-2")
+	require.NoError(t, err)
+	east, err := clients.Get("us-east-1")
+	require.NoError(t, err)
+	assert.Same(t, west, again)
+	assert.NotSame(t, west, east)
+
+	// the clients are created again with the new credential settings
+	require.NoError(t, awssession.SetCredentialSettings(awssession.CredentialSettings{RoleARN: "arn:aws:iam::123456789012:role/adot"}))
+	t.Cleanup(func() { require.NoError(t, awssession.SetCredentialSettings(awssession.CredentialSettings{})) })
+	withRole, err := clients.Get("us-west-2")
+	require.NoError(t, err)
+	assert.NotSame(t, west, withRole)
+	again, err = clients.Get("us-west-2")
+	require.NoError(t, err)
+	assert.Same(t, withRole, again)
+}
+
+func TestScheme(t *testing.T) {
+	assert.Equal(t, "s3", New().Scheme())
+	assert.NoError(t, New().Shutdown(context.Background()))
+}
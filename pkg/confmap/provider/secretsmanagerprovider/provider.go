@@ -0,0 +1,232 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package secretsmanagerprovider // import "github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/secretsmanagerprovider"
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"go.opentelemetry.io/collector/confmap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+)
+
+const (
+	schemeName = "secretsmanager"
+
+	versionIDParam    = "version_id"
+	versionStageParam = "version_stage"
+	formatParam       = "format"
+
+	// formatYAML parses the value as a YAML or JSON map, e.g. to use it as a whole configuration
+	formatYAML = "yaml"
+)
+
+type secretsManagerClient interface {
+	GetSecretValueWithContext(aws.Context, *secretsmanager.GetSecretValueInput, ...request.Option) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+type provider struct {
+	// endpoint overrides the Secrets Manager endpoint, it is only set by tests
+	endpoint string
+	// mu guards clients, which are created on first use and keyed by region, the watch of the
+	// configuration retrieves the values concurrently
+	mu      sync.Mutex
+	clients map[string]secretsManagerClient
+	// settings are the credential settings of the clients, which are created again when they change
+	settings awssession.CredentialSettings
+}
+
+// New returns a new confmap.Provider that reads the configuration from AWS Secrets Manager.
+//
+// This Provider supports "secretsmanager" scheme, and can be called with a "uri" that follows:
+//
+//	secretsmanager-uri : secretsmanager:[SECRET-NAME-OR-ARN][?version_id=ID|?version_stage=STAGE][&format=yaml][#JSON-KEY]
+//
+// When a JSON key is given, the secret string must be a JSON object and only the value of the key
+// is returned. When the secret is referenced by ARN, the region of the ARN is used, otherwise the
+// region is resolved from the environment.
+//
+// The secret, or the string value of its JSON key, is returned unchanged so it can be embedded in a
+// configuration, even when it looks like YAML. With format=yaml, it must hold a YAML or JSON map,
+// which can be used as a whole configuration.
+//
+// Examples:
+// `secretsmanager:aoc/config?format=yaml`
+// `${secretsmanager:aoc/datadog#api_key}`
+// `${secretsmanager:aoc/datadog?version_stage=AWSPREVIOUS#api_key}`
+// `secretsmanager:arn:aws:secretsmanager:us-west-2:123456789012:secret:aoc/config-AbCdEf`
+func New() confmap.Provider {
+	return &provider{clients: map[string]secretsManagerClient{}}
+}
+
+func (p *provider) Retrieve(ctx context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	ref, err := parseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	out, err := p.getSecretValue(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	value := aws.StringValue(out.SecretString)
+	if out.SecretString == nil {
+		value = string(out.SecretBinary)
+	}
+	if ref.jsonKey == "" {
+		return ref.newRetrieved(value)
+	}
+
+	var fields map[string]any
+	if err = json.Unmarshal([]byte(value), &fields); err != nil {
+		return nil, fmt.Errorf("secret %q is not a JSON object: %w", ref.secretID, err)
+	}
+	field, ok := fields[ref.jsonKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %q has no key %q", ref.secretID, ref.jsonKey)
+	}
+	if s, ok := field.(string); ok {
+		return ref.newRetrieved(s)
+	}
+	if _, ok := field.(map[string]any); !ok && ref.format == formatYAML {
+		return nil, fmt.Errorf("key %q of secret %q is not a map", ref.jsonKey, ref.secretID)
+	}
+	return confmap.NewRetrieved(field)
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+func (*provider) Shutdown(context.Context) error {
+	return nil
+}
+
+func (p *provider) getSecretValue(ctx context.Context, ref secretRef) (*secretsmanager.GetSecretValueOutput, error) {
+	client, err := p.client(ref.region())
+	if err != nil {
+		return nil, err
+	}
+	input := &secretsmanager.GetSecretValueInput{SecretId: aws.String(ref.secretID)}
+	if ref.versionID != "" {
+		input.VersionId = aws.String(ref.versionID)
+	}
+	if ref.versionStage != "" {
+		input.VersionStage = aws.String(ref.versionStage)
+	}
+	out, err := client.GetSecretValueWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secret %q: %w", ref.secretID, err)
+	}
+	return out, nil
+}
+
+func (p *provider) client(region string) (secretsManagerClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if settings := awssession.GetCredentialSettings(); settings != p.settings {
+		p.clients, p.settings = map[string]secretsManagerClient{}, settings
+	}
+	if client, ok := p.clients[region]; ok {
+		return client, nil
+	}
+	sess, err := awssession.New(region, p.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	client := secretsmanager.New(sess)
+	p.clients[region] = client
+	return client, nil
+}
+
+type secretRef struct {
+	secretID     string
+	versionID    string
+	versionStage string
+	format       string
+	jsonKey      string
+}
+
+// parseURI splits the uri into the secret id, the version selectors, the format and the JSON key.
+func parseURI(uri string) (secretRef, error) {
+	if !strings.HasPrefix(uri, schemeName+":") {
+		return secretRef{}, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+	ref := secretRef{}
+	rest := uri[len(schemeName)+1:]
+	if idx := strings.LastIndex(rest, "#"); idx >= 0 {
+		ref.jsonKey = rest[idx+1:]
+		rest = rest[:idx]
+	}
+	if idx := strings.Index(rest, "?"); idx >= 0 {
+		query, err := url.ParseQuery(rest[idx+1:])
+		if err != nil {
+			return secretRef{}, fmt.Errorf("%q uri has an invalid query: %w", uri, err)
+		}
+		for key := range query {
+			if key != versionIDParam && key != versionStageParam && key != formatParam {
+				return secretRef{}, fmt.Errorf("%q uri has an unsupported query parameter %q", uri, key)
+			}
+		}
+		ref.versionID = query.Get(versionIDParam)
+		ref.versionStage = query.Get(versionStageParam)
+		ref.format = query.Get(formatParam)
+		if query.Has(formatParam) && ref.format != formatYAML {
+			return secretRef{}, fmt.Errorf("%q uri has an unsupported format %q, must be %q", uri, ref.format, formatYAML)
+		}
+		rest = rest[:idx]
+	}
+	if rest == "" {
+		return secretRef{}, fmt.Errorf("%q uri is missing the secret id", uri)
+	}
+	ref.secretID = rest
+	return ref, nil
+}
+
+// region returns the region of a secret referenced by ARN, or an empty string.
+func (r secretRef) region() string {
+	if !arn.IsARN(r.secretID) {
+		return ""
+	}
+	parsed, err := arn.Parse(r.secretID)
+	if err != nil {
+		return ""
+	}
+	return parsed.Region
+}
+
+// newRetrieved returns the value unchanged, so that e.g. a password which parses as YAML stays a
+// string, or as a map when the uri has format=yaml.
+func (r secretRef) newRetrieved(value string) (*confmap.Retrieved, error) {
+	if r.format != formatYAML {
+		return confmap.NewRetrieved(value)
+	}
+	var conf map[string]any
+	if err := yaml.Unmarshal([]byte(value), &conf); err != nil || conf == nil {
+		return nil, fmt.Errorf("secret %q is not a YAML or JSON map", r.secretID)
+	}
+	return confmap.NewRetrieved(conf)
+}
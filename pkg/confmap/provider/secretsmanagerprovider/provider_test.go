@@ -0,0 +1,230 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package secretsmanagerprovider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+)
+
+type getSecretValueInput struct {
+	SecretId     string //nolint:revive // matches the API field name
+	VersionId    string //nolint:revive // matches the API field name
+	VersionStage string
+}
+
+// setUpMockSecretsManager serves GetSecretValue requests, the secrets are keyed by id and version stage.
+func setUpMockSecretsManager(t *testing.T, secrets map[getSecretValueInput]string) *httptest.Server {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-west-2")
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", req.Header.Get("X-Amz-Target"))
+		var input getSecretValueInput
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&input))
+
+		value, ok := secrets[input]
+		if !ok {
+			rw.WriteHeader(http.StatusBadRequest)
+			_, _ = rw.Write([]byte(`{"__type":"ResourceNotFoundException","message":"not found"}`))
+			return
+		}
+		_ = json.NewEncoder(rw).Encode(map[string]any{"Name": input.SecretId, "SecretString": value, "VersionId": "v1"})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRetrieve(t *testing.T) {
+	server := setUpMockSecretsManager(t, map[getSecretValueInput]string{
+		{SecretId: "aoc/config"}:                               "receivers:\n  otlp:\n",
+		{SecretId: "aoc/datadog"}:                              `{"api_key":"0123","port":8126}`,
+		{SecretId: "aoc/datadog", VersionStage: "AWSPREVIOUS"}: `{"api_key":"old"}`,
+		{SecretId: "aoc/password"}:                             "a: b",
+		{SecretId: "aoc/token", VersionId: "EXAMPLE1-90ab-cdef-fedc-ba987EXAMPLE"}: "token",
+		{SecretId: "arn:aws:secretsmanager:us-east-1:1:secret:x"}:                  "arn",
+	})
+
+	tests := []struct {
+		name        string
+		uri         string
+		expected    any
+		expectedErr string
+	}{
+		{
+			name:     "map value",
+			uri:      "secretsmanager:aoc/config?format=yaml",
+			expected: map[string]any{"receivers": map[string]any{"otlp": nil}},
+		},
+		{
+			name:     "map value is kept as string",
+			uri:      "secretsmanager:aoc/config",
+			expected: "receivers:\n  otlp:\n",
+		},
+		{
+			name:     "yaml like password is kept as string",
+			uri:      "secretsmanager:aoc/password",
+			expected: "a: b",
+		},
+		{
+			name:     "json key is kept as string",
+			uri:      "secretsmanager:aoc/datadog#api_key",
+			expected: "0123",
+		},
+		{
+			name:     "json key with number",
+			uri:      "secretsmanager:aoc/datadog#port",
+			expected: float64(8126),
+		},
+		{
+			name:     "json object",
+			uri:      "secretsmanager:aoc/datadog?format=yaml",
+			expected: map[string]any{"api_key": "0123", "port": 8126},
+		},
+		{
+			name:     "json object is kept as string",
+			uri:      "secretsmanager:aoc/datadog",
+			expected: `{"api_key":"0123","port":8126}`,
+		},
+		{
+			name:     "version stage",
+			uri:      "secretsmanager:aoc/datadog?version_stage=AWSPREVIOUS#api_key",
+			expected: "old",
+		},
+		{
+			name:     "version id",
+			uri:      "secretsmanager:aoc/token?version_id=EXAMPLE1-90ab-cdef-fedc-ba987EXAMPLE",
+			expected: "token",
+		},
+		{
+			name:     "arn",
+			uri:      "secretsmanager:arn:aws:secretsmanager:us-east-1:1:secret:x",
+			expected: "arn",
+		},
+		{
+			name:        "missing json key",
+			uri:         "secretsmanager:aoc/datadog#password",
+			expectedErr: `secret "aoc/datadog" has no key "password"`,
+		},
+		{
+			name:        "not a json object",
+			uri:         "secretsmanager:aoc/token?version_id=EXAMPLE1-90ab-cdef-fedc-ba987EXAMPLE#key",
+			expectedErr: `secret "aoc/token" is not a JSON object`,
+		},
+		{
+			name:        "not a map",
+			uri:         "secretsmanager:aoc/token?version_id=EXAMPLE1-90ab-cdef-fedc-ba987EXAMPLE&format=yaml",
+			expectedErr: `secret "aoc/token" is not a YAML or JSON map`,
+		},
+		{
+			name:        "json key is not a map",
+			uri:         "secretsmanager:aoc/datadog?format=yaml#port",
+			expectedErr: `key "port" of secret "aoc/datadog" is not a map`,
+		},
+		{
+			name:        "unsupported format",
+			uri:         "secretsmanager:aoc/config?format=json",
+			expectedErr: `unsupported format "json"`,
+		},
+		{
+			name:        "missing secret",
+			uri:         "secretsmanager:aoc/missing",
+			expectedErr: `failed to fetch secret "aoc/missing"`,
+		},
+		{
+			name:        "unsupported query",
+			uri:         "secretsmanager:aoc/token?version=1",
+			expectedErr: `unsupported query parameter "version"`,
+		},
+		{
+			name:        "empty id",
+			uri:         "secretsmanager:#key",
+			expectedErr: "missing the secret id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &provider{endpoint: server.URL, clients: map[string]secretsManagerClient{}}
+			ret, err := p.Retrieve(context.Background(), tt.uri, nil)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			raw, err := ret.AsRaw()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, raw)
+		})
+	}
+}
+
+// TestRetrieveConcurrent retrieves the secrets of several regions concurrently, as the watch of the
+// configuration does, run it with -race.
+func TestRetrieveConcurrent(t *testing.T) {
+	server := setUpMockSecretsManager(t, map[getSecretValueInput]string{
+		{SecretId: "aoc/token"}:                                   "token",
+		{SecretId: "arn:aws:secretsmanager:us-east-1:1:secret:x"}: "arn",
+	})
+
+	p := &provider{endpoint: server.URL, clients: map[string]secretsManagerClient{}}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, uri := range []string{"secretsmanager:aoc/token", "secretsmanager:arn:aws:secretsmanager:us-east-1:1:secret:x"} {
+			wg.Add(1)
+			go func(uri string) {
+				defer wg.Done()
+				_, err := p.Retrieve(context.Background(), uri, nil)
+				assert.NoError(t, err)
+			}(uri)
+		}
+	}
+	wg.Wait()
+	assert.Len(t, p.clients, 2)
+}
+
+func TestClientCredentialSettings(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	p := &provider{clients: map[string]secretsManagerClient{}}
+	client, err := p.client("us-west-2")
+	require.NoError(t, err)
+
+	// the clients are created again with the new credential settings
+	require.NoError(t, awssession.SetCredentialSettings(awssession.CredentialSettings{RoleARN: "arn:aws:iam::123456789012:role/adot"}))
+	t.Cleanup(func() { require.NoError(t, awssession.SetCredentialSettings(awssession.CredentialSettings{})) })
+	withRole, err := p.client("us-west-2")
+	require.NoError(t, err)
+	assert.NotSame(t, client, withRole)
+	again, err := p.client("us-west-2")
+	require.NoError(t, err)
+	assert.Same(t, withRole, again)
+}
+
+func TestScheme(t *testing.T) {
+	assert.Equal(t, "secretsmanager", New().Scheme())
+	assert.NoError(t, New().Shutdown(context.Background()))
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ssmprovider // import "github.com/aws-observability/aws-otel-collector/pkg/confmap/provider/ssmprovider"
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"go.opentelemetry.io/collector/confmap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+)
+
+const schemeName = "ssm"
+
+type ssmClient interface {
+	GetParameterWithContext(aws.Context, *ssm.GetParameterInput, ...request.Option) (*ssm.GetParameterOutput, error)
+}
+
+type provider struct {
+	// endpoint overrides the SSM endpoint, it is only set by tests
+	endpoint string
+	// mu guards clients, which are created on first use and keyed by region, the watch of the
+	// configuration retrieves the values concurrently
+	mu      sync.Mutex
+	clients map[string]ssmClient
+	// settings are the credential settings of the clients, which are created again when they change
+	settings awssession.CredentialSettings
+}
+
+// New returns a new confmap.Provider that reads the configuration from AWS Systems Manager Parameter Store.
+//
+// This Provider supports "ssm" scheme, and can be called with a "uri" that follows:
+//
+//	ssm-uri : ssm:[PARAMETER-NAME-OR-ARN][:VERSION-OR-LABEL]
+//
+// The version or label selector is passed as is to the GetParameter API. When the parameter is
+// referenced by ARN, the region of the ARN is used, otherwise the region is resolved from the
+// environment. SecureString parameters are decrypted.
+//
+// A value holding a YAML map can be used as a whole configuration, any other value is returned as
+// a string so it can be embedded in a configuration.
+//
+// Examples:
+// `ssm:/aoc/config` - (latest version)
+// `ssm:/aoc/config:3` - (version 3)
+// `${ssm:/aoc/datadog-api-key:prod}` - (label prod)
+// `ssm:arn:aws:ssm:us-west-2:123456789012:parameter/aoc/config`
+func New() confmap.Provider {
+	return &provider{clients: map[string]ssmClient{}}
+}
+
+func (p *provider) Retrieve(ctx context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	param, err := p.getParameter(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return newRetrieved(aws.StringValue(param.Value))
+}
+
+func (*provider) Scheme() string {
+	return schemeName
+}
+
+func (*provider) Shutdown(context.Context) error {
+	return nil
+}
+
+func (p *provider) getParameter(ctx context.Context, uri string) (*ssm.Parameter, error) {
+	if !strings.HasPrefix(uri, schemeName+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %q provider", uri, schemeName)
+	}
+	name := uri[len(schemeName)+1:]
+	if name == "" {
+		return nil, fmt.Errorf("%q uri is missing the parameter name", uri)
+	}
+
+	client, err := p.client(regionOf(name))
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch SSM parameter %q: %w", name, err)
+	}
+	if out.Parameter == nil {
+		return nil, fmt.Errorf("SSM parameter %q has no value", name)
+	}
+	return out.Parameter, nil
+}
+
+func (p *provider) client(region string) (ssmClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if settings := awssession.GetCredentialSettings(); settings != p.settings {
+		p.clients, p.settings = map[string]ssmClient{}, settings
+	}
+	if client, ok := p.clients[region]; ok {
+		return client, nil
+	}
+	sess, err := awssession.New(region, p.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	client := ssm.New(sess)
+	p.clients[region] = client
+	return client, nil
+}
+
+// regionOf returns the region of a parameter referenced by ARN, or an empty string.
+func regionOf(name string) string {
+	if !arn.IsARN(name) {
+		return ""
+	}
+	parsed, err := arn.Parse(name)
+	if err != nil {
+		return ""
+	}
+	return parsed.Region
+}
+
+// newRetrieved returns the value as a map when it holds a YAML map, and as a string otherwise
+// so that e.g. a numeric password is not turned into a number.
+func newRetrieved(value string) (*confmap.Retrieved, error) {
+	var conf map[string]any
+	if err := yaml.Unmarshal([]byte(value), &conf); err == nil && conf != nil {
+		return confmap.NewRetrieved(conf)
+	}
+	return confmap.NewRetrieved(value)
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ssmprovider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/yamlprovider"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+)
+
+// setUpMockSSM serves GetParameter requests from the given parameters, keyed by the requested name.
+func setUpMockSSM(t *testing.T, params map[string]string) *httptest.Server {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-west-2")
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "AmazonSSM.GetParameter", req.Header.Get("X-Amz-Target"))
+		var input struct {
+			Name           string
+			WithDecryption bool
+		}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&input))
+		assert.True(t, input.WithDecryption)
+
+		value, ok := params[input.Name]
+		if !ok {
+			rw.WriteHeader(http.StatusBadRequest)
+			_, _ = rw.Write([]byte(`{"__type":"ParameterNotFound","message":"not found"}`))
+			return
+		}
+		_ = json.NewEncoder(rw).Encode(map[string]any{
+			"Parameter": map[string]any{"Name": input.Name, "Value": value, "Version": 1},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRetrieve(t *testing.T) {
+	server := setUpMockSSM(t, map[string]string{
+		"/aoc/config":     "receivers:\n  otlp:\n",
+		"/aoc/password:2": "0123",
+		"arn:aws:ssm:us-east-1:123456789012:parameter/aoc/region": "us-east-1",
+	})
+
+	tests := []struct {
+		name        string
+		uri         string
+		expected    any
+		expectedErr string
+	}{
+		{
+			name:     "map value",
+			uri:      "ssm:/aoc/config",
+			expected: map[string]any{"receivers": map[string]any{"otlp": nil}},
+		},
+		{
+			name:     "versioned scalar value is kept as string",
+			uri:      "ssm:/aoc/password:2",
+			expected: "0123",
+		},
+		{
+			name:     "arn",
+			uri:      "ssm:arn:aws:ssm:us-east-1:123456789012:parameter/aoc/region",
+			expected: "us-east-1",
+		},
+		{
+			name:        "missing parameter",
+			uri:         "ssm:/aoc/missing",
+			expectedErr: `failed to fetch SSM parameter "/aoc/missing"`,
+		},
+		{
+			name:        "empty name",
+			uri:         "ssm:",
+			expectedErr: "missing the parameter name",
+		},
+		{
+			name:        "wrong scheme",
+			uri:         "s3:/aoc/config",
+			expectedErr: "is not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &provider{endpoint: server.URL, clients: map[string]ssmClient{}}
+			ret, err := p.Retrieve(context.Background(), tt.uri, nil)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			raw, err := ret.AsRaw()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, raw)
+		})
+	}
+}
+
+func TestRetrieveEmbedded(t *testing.T) {
+	server := setUpMockSSM(t, map[string]string{"/aoc/region": "us-west-2"})
+
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs: []string{"yaml:exporters::awsemf::region: ${ssm:/aoc/region}"},
+		Providers: map[string]confmap.Provider{
+			"yaml": yamlprovider.NewWithSettings(confmap.ProviderSettings{}),
+			"ssm":  &provider{endpoint: server.URL, clients: map[string]ssmClient{}},
+		},
+	})
+	require.NoError(t, err)
+	conf, err := resolver.Resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "us-west-2", conf.Get("exporters::awsemf::region"))
+}
+
+// TestRetrieveConcurrent retrieves the parameters of several regions concurrently, as the watch of
+// the configuration does, run it with -race.
+func TestRetrieveConcurrent(t *testing.T) {
+	server := setUpMockSSM(t, map[string]string{
+		"/aoc/region": "us-west-2",
+		"arn:aws:ssm:us-east-1:123456789012:parameter/aoc/region": "us-east-1",
+	})
+
+	p := &provider{endpoint: server.URL, clients: map[string]ssmClient{}}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, uri := range []string{"ssm:/aoc/region", "ssm:arn:aws:ssm:us-east-1:123456789012:parameter/aoc/region"} {
+			wg.Add(1)
+			go func(uri string) {
+				defer wg.Done()
+				_, err := p.Retrieve(context.Background(), uri, nil)
+				assert.NoError(t, err)
+			}(uri)
+		}
+	}
+	wg.Wait()
+	assert.Len(t, p.clients, 2)
+}
+
+func TestClientCredentialSettings(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	p := &provider{clients: map[string]ssmClient{}}
+	client, err := p.client("us-west-2")
+	require.NoError(t, err)
+
+	// the clients are created again with the new credential settings
+	require.NoError(t, awssession.SetCredentialSettings(awssession.CredentialSettings{RoleARN: "arn:aws:iam::123456789012:role/adot"}))
+	t.Cleanup(func() { require.NoError(t, awssession.SetCredentialSettings(awssession.CredentialSettings{})) })
+	withRole, err := p.client("us-west-2")
+	require.NoError(t, err)
+	assert.NotSame(t, client, withRole)
+	again, err := p.client("us-west-2")
+	require.NoError(t, err)
+	assert.Same(t, withRole, again)
+}
+
+func TestScheme(t *testing.T) {
+	assert.Equal(t, "ssm", New().Scheme())
+	assert.NoError(t, New().Shutdown(context.Background()))
+}
@@ -70,6 +70,8 @@ import (
 	"go.opentelemetry.io/collector/receiver"
 	"go.opentelemetry.io/collector/receiver/otlpreceiver"
 	"go.uber.org/multierr"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/extension/pipelinehealthextension"
 )
 
 // Components register OTel components for ADOT-collector distribution
@@ -84,6 +86,7 @@ func Components() (otelcol.Factories, error) {
 		zpagesextension.NewFactory(),
 		ballastextension.NewFactory(),
 		filestorage.NewFactory(),
+		pipelinehealthextension.NewFactory(),
 	}
 
 	extensions, err := extension.MakeFactoryMap(extensionsList...)
@@ -24,7 +24,7 @@ import (
 const (
 	exportersCount  = 15
 	receiversCount  = 10
-	extensionsCount = 8
+	extensionsCount = 9
 	processorCount  = 15
 )
 
@@ -76,6 +76,7 @@ func TestComponents(t *testing.T) {
 	assert.NotNil(t, extensions["awsproxy"])
 	assert.NotNil(t, extensions["ecs_observer"])
 	assert.NotNil(t, extensions["sigv4auth"])
+	assert.NotNil(t, extensions["pipeline_health"])
 	// core extensions
 	assert.NotNil(t, extensions["zpages"])
 	assert.NotNil(t, extensions["memory_ballast"])
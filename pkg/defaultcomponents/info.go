@@ -0,0 +1,167 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package defaultcomponents
+
+import (
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/otelcol"
+)
+
+const unknownVersion = "unknown"
+
+// ComponentInfo describes a component factory built into the distribution.
+type ComponentInfo struct {
+	Type string `json:"type"`
+	Kind string `json:"kind"`
+	// Stability maps each supported signal to its stability level, signals the component
+	// does not support are left out.
+	Stability map[string]string `json:"stability"`
+	Module    string            `json:"module"`
+	Version   string            `json:"version"`
+}
+
+// Describe returns the information of every factory, ordered by kind and type.
+func Describe(factories otelcol.Factories) []ComponentInfo {
+	modules := buildModules()
+	var infos []ComponentInfo
+
+	add := func(kind string, typ component.Type, factory component.Factory, stability map[string]component.StabilityLevel) {
+		info := ComponentInfo{
+			Type:      string(typ),
+			Kind:      kind,
+			Stability: map[string]string{},
+		}
+		for signal, level := range stability {
+			if level != component.StabilityLevelUndefined {
+				info.Stability[signal] = level.String()
+			}
+		}
+		info.Module, info.Version = modules.lookup(configPackage(factory))
+		infos = append(infos, info)
+	}
+
+	for _, typ := range sortedTypes(factories.Receivers) {
+		f := factories.Receivers[typ]
+		add("receiver", typ, f, map[string]component.StabilityLevel{
+			"logs":    f.LogsReceiverStability(),
+			"metrics": f.MetricsReceiverStability(),
+			"traces":  f.TracesReceiverStability(),
+		})
+	}
+	for _, typ := range sortedTypes(factories.Processors) {
+		f := factories.Processors[typ]
+		add("processor", typ, f, map[string]component.StabilityLevel{
+			"logs":    f.LogsProcessorStability(),
+			"metrics": f.MetricsProcessorStability(),
+			"traces":  f.TracesProcessorStability(),
+		})
+	}
+	for _, typ := range sortedTypes(factories.Exporters) {
+		f := factories.Exporters[typ]
+		add("exporter", typ, f, map[string]component.StabilityLevel{
+			"logs":    f.LogsExporterStability(),
+			"metrics": f.MetricsExporterStability(),
+			"traces":  f.TracesExporterStability(),
+		})
+	}
+	for _, typ := range sortedTypes(factories.Connectors) {
+		f := factories.Connectors[typ]
+		add("connector", typ, f, map[string]component.StabilityLevel{
+			"logs-to-logs":       f.LogsToLogsStability(),
+			"logs-to-metrics":    f.LogsToMetricsStability(),
+			"logs-to-traces":     f.LogsToTracesStability(),
+			"metrics-to-logs":    f.MetricsToLogsStability(),
+			"metrics-to-metrics": f.MetricsToMetricsStability(),
+			"metrics-to-traces":  f.MetricsToTracesStability(),
+			"traces-to-logs":     f.TracesToLogsStability(),
+			"traces-to-metrics":  f.TracesToMetricsStability(),
+			"traces-to-traces":   f.TracesToTracesStability(),
+		})
+	}
+	for _, typ := range sortedTypes(factories.Extensions) {
+		f := factories.Extensions[typ]
+		add("extension", typ, f, map[string]component.StabilityLevel{
+			"extension": f.ExtensionStability(),
+		})
+	}
+	return infos
+}
+
+func sortedTypes[F any](factories map[component.Type]F) []component.Type {
+	types := make([]component.Type, 0, len(factories))
+	for typ := range factories {
+		types = append(types, typ)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// configPackage returns the import path of the package defining the default config of the factory.
+// The factories themselves are generic types from the collector core, but every component declares
+// its own Config struct, which makes it a reliable way to find where the component comes from.
+func configPackage(factory component.Factory) string {
+	t := reflect.TypeOf(factory.CreateDefaultConfig())
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return t.PkgPath()
+}
+
+type moduleVersions map[string]string
+
+// buildModules returns the versions of the modules compiled into the binary keyed by module path.
+func buildModules() moduleVersions {
+	modules := moduleVersions{}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return modules
+	}
+	modules[bi.Main.Path] = bi.Main.Version
+	for _, dep := range bi.Deps {
+		version := dep.Version
+		if dep.Replace != nil {
+			version = dep.Replace.Version
+		}
+		modules[dep.Path] = version
+	}
+	return modules
+}
+
+// lookup returns the module containing the package and its version, the package path is
+// returned with an unknown version when the build information is not available.
+func (m moduleVersions) lookup(pkg string) (string, string) {
+	module := ""
+	for path := range m {
+		if path != "" && (pkg == path || strings.HasPrefix(pkg, path+"/")) && len(path) > len(module) {
+			module = path
+		}
+	}
+	if module == "" {
+		return pkg, unknownVersion
+	}
+	if m[module] == "" {
+		return module, unknownVersion
+	}
+	return module, m[module]
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package defaultcomponents
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	factories, err := Components()
+	require.NoError(t, err)
+
+	infos := Describe(factories)
+	assert.Len(t, infos, exportersCount+receiversCount+extensionsCount+processorCount)
+
+	byKind := map[string]map[string]ComponentInfo{}
+	for _, info := range infos {
+		if byKind[info.Kind] == nil {
+			byKind[info.Kind] = map[string]ComponentInfo{}
+		}
+		byKind[info.Kind][info.Type] = info
+	}
+
+	awsemf := byKind["exporter"]["awsemf"]
+	assert.Equal(t, "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/awsemfexporter", awsemf.Module)
+	assert.Contains(t, awsemf.Stability, "metrics")
+	assert.NotContains(t, awsemf.Stability, "traces")
+
+	batch := byKind["processor"]["batch"]
+	assert.Equal(t, "go.opentelemetry.io/collector/processor/batchprocessor", batch.Module)
+	assert.Len(t, batch.Stability, 3)
+
+	healthCheck := byKind["extension"]["health_check"]
+	assert.Contains(t, healthCheck.Stability, "extension")
+}
+
+func TestModuleVersionsLookup(t *testing.T) {
+	modules := moduleVersions{
+		"go.opentelemetry.io/collector":                   "v0.94.1",
+		"go.opentelemetry.io/collector/processor":         "v0.94.1",
+		"go.opentelemetry.io/collector/processor/batch":   "",
+		"github.com/aws-observability/aws-otel-collector": "(devel)",
+	}
+
+	module, version := modules.lookup("go.opentelemetry.io/collector/processor/memorylimiterprocessor")
+	assert.Equal(t, "go.opentelemetry.io/collector/processor", module)
+	assert.Equal(t, "v0.94.1", version)
+
+	module, version = modules.lookup("go.opentelemetry.io/collector/processor/batch")
+	assert.Equal(t, "go.opentelemetry.io/collector/processor/batch", module)
+	assert.Equal(t, unknownVersion, version)
+
+	module, version = modules.lookup("example.com/unknown")
+	assert.Equal(t, "example.com/unknown", module)
+	assert.Equal(t, unknownVersion, version)
+}
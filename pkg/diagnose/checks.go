@@ -0,0 +1,310 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package diagnose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	imdsTokenPath       = "/latest/api/token"
+	imdsRegionPath      = "/latest/meta-data/placement/region"
+	imdsTokenHeader     = "X-aws-ec2-metadata-token"
+	imdsTokenTTLHeader  = "X-aws-ec2-metadata-token-ttl-seconds"
+	imdsTokenTTLSeconds = "60"
+	// imdsTimeout is short, outside of EC2 the requests to the metadata service never get a response
+	imdsTimeout = time.Second
+	// a response larger than this is not metadata
+	maxResponseSize = 1 << 20
+)
+
+type diagnoser struct {
+	settings Settings
+}
+
+// checkIMDS gets the region from the instance metadata, with IMDSv2 and then IMDSv1. A token
+// request failing while IMDSv1 answers is the sign of a hop limit too low for a container: the
+// response to the PUT request does not make it through the extra hop of the container network. When
+// IMDSv1 is disabled, the same happens as a token request timing out while the metadata service
+// answers the requests without a token.
+func (d *diagnoser) checkIMDS(ctx context.Context) IMDSResult {
+	result := IMDSResult{Endpoint: d.settings.IMDSEndpoint}
+	if d.settings.IMDSEndpoint == "" {
+		return result
+	}
+	endpoint := strings.TrimSuffix(d.settings.IMDSEndpoint, "/")
+	client := &http.Client{}
+
+	timeout := imdsTimeout
+	if d.settings.Timeout < timeout {
+		timeout = d.settings.Timeout
+	}
+	token, tokenErr := request(ctx, client, timeout, http.MethodPut, endpoint+imdsTokenPath, http.Header{imdsTokenTTLHeader: {imdsTokenTTLSeconds}})
+	header := http.Header{}
+	if tokenErr == nil {
+		result.IMDSv2 = true
+		header.Set(imdsTokenHeader, string(token))
+	}
+	region, err := request(ctx, client, timeout, http.MethodGet, endpoint+imdsRegionPath, header)
+	if err != nil {
+		// not running on EC2, or IMDSv1 is disabled and the token cannot be retrieved
+		var status *statusError
+		if isTimeout(tokenErr) && errors.As(err, &status) {
+			result.Problems = append(result.Problems, fmt.Sprintf("IMDSv2 token request timed out while the instance "+
+				"metadata answered %s without a token, IMDSv1 is disabled and the hop limit of the instance is likely "+
+				"too low for a container, set HttpPutResponseHopLimit to 2: %v", status.status, tokenErr))
+		}
+		return result
+	}
+	result.Available = true
+	result.Region = strings.TrimSpace(string(region))
+	if tokenErr != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("IMDSv2 token request failed while IMDSv1 answered, "+
+			"the hop limit of the instance is likely too low for a container, set HttpPutResponseHopLimit to 2: %v", tokenErr))
+	}
+	return result
+}
+
+// checkTarget resolves the region and the credentials of the component the way the AWS components
+// do, and checks its endpoint.
+func (d *diagnoser) checkTarget(ctx context.Context, t target, imds IMDSResult) ComponentResult {
+	result := ComponentResult{ID: t.id}
+
+	result.Region, result.RegionSource = d.resolveRegion(t, imds)
+	if result.Region == "" {
+		result.Problems = append(result.Problems, "no region: set region in the configuration or "+envKeyRegion+
+			", or run on EC2 with the instance metadata available")
+		return result
+	}
+
+	client, proxy, err := d.httpClient(t.proxyAddress)
+	if err != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("invalid proxy_address %q: %v", t.proxyAddress, err))
+		return result
+	}
+	result.Proxy = proxy
+
+	d.checkCredentials(ctx, t, result.Region, client, &result)
+
+	endpoint, err := serviceEndpoint(t, result.Region)
+	if err != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("no endpoint: %v", err))
+		return result
+	}
+	result.Endpoint = endpoint
+	d.checkEndpoint(ctx, endpoint, client, &result)
+	return result
+}
+
+// resolveRegion follows the order of the AWS components: the configuration, AWS_REGION and the
+// instance metadata, which is skipped in local mode. The region of a Prometheus workspace is
+// known from its endpoint.
+func (d *diagnoser) resolveRegion(t target, imds IMDSResult) (string, string) {
+	if t.region != "" {
+		return t.region, "config"
+	}
+	if region, ok := d.settings.LookupEnv(envKeyRegion); ok && region != "" {
+		return region, "env:" + envKeyRegion
+	}
+	if region := regionFromEndpoint(t.endpoint); t.service == "aps" && region != "" {
+		return region, "endpoint"
+	}
+	if !t.localMode && imds.Region != "" {
+		return imds.Region, "imds"
+	}
+	return "", ""
+}
+
+// httpClient returns the client of the component requests, with the proxy of its configuration
+// or of the HTTPS_PROXY and NO_PROXY variables.
+func (d *diagnoser) httpClient(proxyAddress string) (*http.Client, string, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyAddress == "" {
+		for _, key := range []string{"HTTPS_PROXY", "https_proxy"} {
+			if proxy, ok := d.settings.LookupEnv(key); ok && proxy != "" {
+				return &http.Client{Transport: transport}, proxy, nil
+			}
+		}
+		return &http.Client{Transport: transport}, "", nil
+	}
+	proxyURL, err := url.Parse(proxyAddress)
+	if err != nil {
+		return nil, "", err
+	}
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return &http.Client{Transport: transport}, proxyAddress, nil
+}
+
+// checkCredentials retrieves the credentials through the default chain, assuming the role of the
+// configuration when set, and asks STS who they belong to.
+func (d *diagnoser) checkCredentials(ctx context.Context, t target, region string, client *http.Client, result *ComponentResult) {
+	// the same chain as awssession.New, with the error of every provider when none succeeds
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *aws.NewConfig().WithRegion(region).WithCredentialsChainVerboseErrors(true),
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("failed to create AWS session: %v", err))
+		return
+	}
+	stsConfig := aws.NewConfig().WithHTTPClient(client)
+	if d.settings.STSEndpoint != "" {
+		stsConfig = stsConfig.WithEndpoint(d.settings.STSEndpoint)
+	}
+
+	creds := sess.Config.Credentials
+	if t.roleARN != "" {
+		assumeConfig := stsConfig.Copy()
+		if t.stsRegion != "" {
+			assumeConfig = assumeConfig.WithRegion(t.stsRegion)
+		}
+		creds = stscreds.NewCredentials(sess.Copy(assumeConfig), t.roleARN)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.settings.Timeout)
+	defer cancel()
+	value, err := creds.GetWithContext(ctx)
+	if err != nil {
+		if t.roleARN != "" {
+			result.Problems = append(result.Problems, fmt.Sprintf("failed to assume role %s: %s", t.roleARN, oneLine(err)))
+		} else {
+			result.Problems = append(result.Problems, fmt.Sprintf("no credentials found: %s", oneLine(err)))
+		}
+		return
+	}
+	result.Credentials = value.ProviderName
+
+	out, err := sts.New(sess.Copy(stsConfig.Copy().WithCredentials(credentials.NewStaticCredentialsFromCreds(value)))).
+		GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("STS rejected the credentials of %s: %s", value.ProviderName, oneLine(err)))
+		return
+	}
+	result.Identity = aws.StringValue(out.Arn)
+}
+
+// serviceEndpoint returns the endpoint of the configuration, or the one of the service in the region.
+func serviceEndpoint(t target, region string) (string, error) {
+	if t.endpoint != "" {
+		return t.endpoint, nil
+	}
+	resolved, err := endpoints.DefaultResolver().EndpointFor(t.service, region)
+	if err != nil {
+		return "", err
+	}
+	return resolved.URL, nil
+}
+
+// checkEndpoint sends an unsigned request to the endpoint, any response means it is reachable. The
+// clock skew is measured with the Date header of the response, compared to the middle of the request.
+func (d *diagnoser) checkEndpoint(ctx context.Context, endpoint string, client *http.Client, result *ComponentResult) {
+	ctx, cancel := context.WithTimeout(ctx, d.settings.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("invalid endpoint %q: %v", endpoint, err))
+		return
+	}
+	start := d.settings.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("endpoint %s is not reachable: %v", endpoint, err))
+		return
+	}
+	end := d.settings.Now()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+	resp.Body.Close()
+	result.Reachable = true
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	// the Date header is truncated to the second, the time of the server is on average half a second
+	// later, without it a host in sync would be off by a second about half of the time
+	skew := date.Add(time.Second / 2).Sub(start.Add(end.Sub(start) / 2)).Round(time.Second)
+	result.ClockSkew = skew.String()
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		result.Problems = append(result.Problems, fmt.Sprintf("the clock is off by %s from %s, "+
+			"signed requests are rejected beyond %s, check the time synchronization of the host", skew, endpoint, maxClockSkew))
+	}
+}
+
+func request(ctx context.Context, client *http.Client, timeout time.Duration, method, url string, header http.Header) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{method: method, url: url, status: resp.Status}
+	}
+	return body, nil
+}
+
+// statusError is returned by request when the server answers with another status than 200 OK.
+type statusError struct {
+	method string
+	url    string
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s %s returned %s", e.method, e.url, e.status)
+}
+
+// isTimeout returns whether the request got no response in time, as opposed to e.g. a refused
+// connection.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// oneLine joins the lines of the errors of the AWS SDK, which nests the errors of the credential
+// providers on their own lines.
+func oneLine(err error) string {
+	return strings.Join(strings.Fields(err.Error()), " ")
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package diagnose checks the AWS connectivity of the components of a configuration: the region
+// and the credentials they resolve, whether their endpoint is reachable and the clock skew with it.
+package diagnose
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	envKeyRegion       = "AWS_REGION"
+	envKeyIMDSEndpoint = "AWS_EC2_METADATA_SERVICE_ENDPOINT"
+	envKeyIMDSDisabled = "AWS_EC2_METADATA_DISABLED"
+
+	defaultIMDSEndpoint = "http://169.254.169.254"
+	defaultTimeout      = 5 * time.Second
+	// maxClockSkew is the skew tolerated by SigV4, requests signed further off are rejected
+	maxClockSkew = 5 * time.Minute
+)
+
+// apsEndpointRegexp matches the remote write endpoints of Amazon Managed Service for Prometheus,
+// e.g. https://aps-workspaces.us-west-2.amazonaws.com/workspaces/ws-id/api/v1/remote_write
+var apsEndpointRegexp = regexp.MustCompile(`^aps-workspaces\.([a-z0-9-]+)\.amazonaws\.com`)
+
+// Settings are the endpoints the checks use, the tests point them at local stand-ins.
+type Settings struct {
+	// IMDSEndpoint is the EC2 instance metadata endpoint, empty when it is disabled
+	IMDSEndpoint string
+	// STSEndpoint overrides the endpoint of STS, empty uses the regional endpoint
+	STSEndpoint string
+	// Timeout bounds each request
+	Timeout   time.Duration
+	LookupEnv func(string) (string, bool)
+	Now       func() time.Time
+}
+
+// NewSettings returns the settings of the environment, the IMDS endpoint follows the variables of
+// the AWS SDK.
+func NewSettings() Settings {
+	settings := Settings{
+		IMDSEndpoint: defaultIMDSEndpoint,
+		Timeout:      defaultTimeout,
+		LookupEnv:    os.LookupEnv,
+		Now:          time.Now,
+	}
+	if endpoint := os.Getenv(envKeyIMDSEndpoint); endpoint != "" {
+		settings.IMDSEndpoint = endpoint
+	}
+	if strings.EqualFold(os.Getenv(envKeyIMDSDisabled), "true") {
+		settings.IMDSEndpoint = ""
+	}
+	return settings
+}
+
+// Report is the result of the checks.
+type Report struct {
+	IMDS       IMDSResult        `json:"imds"`
+	Components []ComponentResult `json:"components"`
+}
+
+// IMDSResult is the result of the EC2 instance metadata check.
+type IMDSResult struct {
+	Endpoint  string `json:"endpoint,omitempty"`
+	Available bool   `json:"available"`
+	// IMDSv2 is whether a session token was issued, IMDSv1 is used otherwise
+	IMDSv2   bool     `json:"imdsv2"`
+	Region   string   `json:"region,omitempty"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// ComponentResult is the result of the checks of a component.
+type ComponentResult struct {
+	// ID is the component as in the configuration, e.g. exporters::awsemf/app
+	ID     string `json:"id"`
+	Region string `json:"region,omitempty"`
+	// RegionSource tells where the region comes from: config, env:AWS_REGION, endpoint or imds
+	RegionSource string `json:"regionSource,omitempty"`
+	// Credentials is the name of the credential provider which succeeded, e.g. EnvConfigCredentials
+	Credentials string `json:"credentials,omitempty"`
+	// Identity is the ARN STS returns for the credentials
+	Identity  string `json:"identity,omitempty"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	Proxy     string `json:"proxy,omitempty"`
+	Reachable bool   `json:"reachable"`
+	// ClockSkew is how far the clock of the endpoint is ahead of the local one, empty when unknown
+	ClockSkew string   `json:"clockSkew,omitempty"`
+	Problems  []string `json:"problems,omitempty"`
+}
+
+// Problems returns the number of problems found.
+func (r *Report) Problems() int {
+	n := len(r.IMDS.Problems)
+	for _, c := range r.Components {
+		n += len(c.Problems)
+	}
+	return n
+}
+
+// target is an AWS component of the configuration with the settings the checks need.
+type target struct {
+	id string
+	// service is the endpoint prefix of the AWS service, e.g. logs
+	service      string
+	region       string
+	endpoint     string
+	roleARN      string
+	stsRegion    string
+	proxyAddress string
+	localMode    bool
+}
+
+// Run checks the AWS components of the resolved configuration.
+func Run(ctx context.Context, conf map[string]any, settings Settings) Report {
+	if settings.Timeout <= 0 {
+		settings.Timeout = defaultTimeout
+	}
+	if settings.LookupEnv == nil {
+		settings.LookupEnv = os.LookupEnv
+	}
+	if settings.Now == nil {
+		settings.Now = time.Now
+	}
+	d := &diagnoser{settings: settings}
+
+	report := Report{IMDS: d.checkIMDS(ctx), Components: []ComponentResult{}}
+	for _, t := range targets(conf) {
+		report.Components = append(report.Components, d.checkTarget(ctx, t, report.IMDS))
+	}
+	return report
+}
+
+// targets returns the AWS exporters and extensions of the configuration sorted by id. The
+// prometheusremotewrite exporters are only AWS components when they authenticate with sigv4auth.
+func targets(conf map[string]any) []target {
+	var result []target
+	exporters := mapValue(conf, "exporters")
+	extensions := mapValue(conf, "extensions")
+	for id := range exporters {
+		cfg := mapValue(exporters, id)
+		t := target{
+			id:           "exporters::" + id,
+			region:       stringValue(cfg, "region"),
+			endpoint:     stringValue(cfg, "endpoint"),
+			roleARN:      stringValue(cfg, "role_arn"),
+			proxyAddress: stringValue(cfg, "proxy_address"),
+			localMode:    boolValue(cfg, "local_mode"),
+		}
+		switch componentType(id) {
+		case "awsemf", "awscloudwatchlogs":
+			t.service = "logs"
+		case "awsxray":
+			t.service = "xray"
+		case "prometheusremotewrite":
+			authenticator := stringValue(mapValue(cfg, "auth"), "authenticator")
+			if componentType(authenticator) != "sigv4auth" {
+				continue
+			}
+			auth := mapValue(extensions, authenticator)
+			assumeRole := mapValue(auth, "assume_role")
+			t = target{
+				id:        t.id,
+				service:   "aps",
+				region:    stringValue(auth, "region"),
+				endpoint:  t.endpoint,
+				roleARN:   stringValue(assumeRole, "arn"),
+				stsRegion: stringValue(assumeRole, "sts_region"),
+			}
+		default:
+			continue
+		}
+		result = append(result, t)
+	}
+	for id := range extensions {
+		if componentType(id) != "awsproxy" {
+			continue
+		}
+		cfg := mapValue(extensions, id)
+		service := stringValue(cfg, "service_name")
+		if service == "" {
+			service = "xray"
+		}
+		result = append(result, target{
+			id:           "extensions::" + id,
+			service:      service,
+			region:       stringValue(cfg, "region"),
+			endpoint:     stringValue(cfg, "aws_endpoint"),
+			roleARN:      stringValue(cfg, "role_arn"),
+			proxyAddress: stringValue(cfg, "proxy_address"),
+			localMode:    boolValue(cfg, "local_mode"),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].id < result[j].id
+	})
+	return result
+}
+
+// regionFromEndpoint returns the region of an Amazon Managed Service for Prometheus endpoint.
+func regionFromEndpoint(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	matches := apsEndpointRegexp.FindStringSubmatch(u.Host)
+	if matches == nil {
+		return ""
+	}
+	return matches[1]
+}
+
+// componentType returns the type of a component id, e.g. awsemf for awsemf/app.
+func componentType(id string) string {
+	typ, _, _ := strings.Cut(id, "/")
+	return typ
+}
+
+func mapValue(m map[string]any, key string) map[string]any {
+	v, _ := m[key].(map[string]any)
+	return v
+}
+
+func stringValue(m map[string]any, key string) string {
+	v, _ := m[key].(string)
+	return v
+}
+
+func boolValue(m map[string]any, key string) bool {
+	v, _ := m[key].(bool)
+	return v
+}
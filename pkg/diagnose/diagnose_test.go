@@ -0,0 +1,392 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package diagnose
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	userARN = "arn:aws:iam::123456789012:user/collector"
+	roleARN = "arn:aws:iam::123456789012:role/collector"
+)
+
+const (
+	// imdsV2 answers the token requests
+	imdsV2 = "v2"
+	// imdsV1 never answers the token requests, the way they are dropped when the hop limit is too low,
+	// and answers the requests without a token
+	imdsV1 = "v1"
+	// imdsV2HopLimit never answers the token requests and requires a token, as an instance with
+	// IMDSv1 disabled and a hop limit too low
+	imdsV2HopLimit = "v2-hop-limit"
+)
+
+// newIMDS returns a stand-in of the instance metadata service, see imdsV2, imdsV1 and imdsV2HopLimit.
+func newIMDS(t *testing.T, mode string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == http.MethodPut && req.URL.Path == imdsTokenPath:
+			if mode != imdsV2 {
+				// the client gives up on the request
+				<-req.Context().Done()
+				return
+			}
+			_, _ = rw.Write([]byte("token"))
+		case req.URL.Path == imdsRegionPath:
+			if mode != imdsV1 && req.Header.Get(imdsTokenHeader) != "token" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = rw.Write([]byte("eu-west-1"))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newSTS returns a stand-in of STS answering AssumeRole and GetCallerIdentity, the identity is the
+// one of the access key the request is signed with.
+func newSTS(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		require.NoError(t, req.ParseForm())
+		auth := req.Header.Get("Authorization")
+		switch req.Form.Get("Action") {
+		case "AssumeRole":
+			fmt.Fprintf(rw, `<AssumeRoleResponse><AssumeRoleResult><Credentials><AccessKeyId>ASIAROLE</AccessKeyId>`+
+				`<SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken><Expiration>%s</Expiration></Credentials>`+
+				`<AssumedRoleUser><Arn>%s</Arn><AssumedRoleId>AROA:collector</AssumedRoleId></AssumedRoleUser></AssumeRoleResult></AssumeRoleResponse>`,
+				time.Now().Add(time.Hour).UTC().Format(time.RFC3339), req.Form.Get("RoleArn"))
+		case "GetCallerIdentity":
+			arn := userARN
+			switch {
+			case strings.Contains(auth, "Credential=ASIAROLE/"):
+				arn = "arn:aws:sts::123456789012:assumed-role/collector/session"
+			case strings.Contains(auth, "Credential=AKIAREVOKED/"):
+				rw.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(rw, `<ErrorResponse><Error><Type>Sender</Type><Code>InvalidClientTokenId</Code>`+
+					`<Message>The security token included in the request is invalid.</Message></Error><RequestId>1</RequestId></ErrorResponse>`)
+				return
+			}
+			fmt.Fprintf(rw, `<GetCallerIdentityResponse><GetCallerIdentityResult><Arn>%s</Arn><UserId>AIDA</UserId>`+
+				`<Account>123456789012</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`, arn)
+		default:
+			rw.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newService returns a stand-in of a service endpoint, rejecting the unsigned requests with a clock
+// off by skew.
+func newService(t *testing.T, skew time.Duration) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+		rw.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// setCredentials isolates the credential chain from the host, with the given access key in the
+// environment or no credentials at all when it is empty.
+func setCredentials(t *testing.T, accessKey string) {
+	t.Setenv("AWS_ACCESS_KEY_ID", accessKey)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv(envKeyIMDSDisabled, "true")
+}
+
+func TestTargets(t *testing.T) {
+	conf := map[string]any{
+		"exporters": map[string]any{
+			"awsemf/app": map[string]any{"region": "us-west-2", "role_arn": roleARN},
+			"awsxray":    map[string]any{"endpoint": "https://xray.example.com", "local_mode": true},
+			"awscloudwatchlogs": map[string]any{
+				"proxy_address": "http://proxy:3128",
+			},
+			"prometheusremotewrite/amp": map[string]any{
+				"endpoint": "https://aps-workspaces.eu-west-1.amazonaws.com/workspaces/ws-1/api/v1/remote_write",
+				"auth":     map[string]any{"authenticator": "sigv4auth/amp"},
+			},
+			"prometheusremotewrite/other": map[string]any{"endpoint": "https://prometheus.example.com"},
+			"logging":                     map[string]any{},
+		},
+		"extensions": map[string]any{
+			"sigv4auth/amp": map[string]any{
+				"assume_role": map[string]any{"arn": roleARN, "sts_region": "us-east-1"},
+			},
+			"awsproxy": map[string]any{"aws_endpoint": "https://xray.example.com", "endpoint": "0.0.0.0:2000"},
+			"pprof":    nil,
+		},
+	}
+	assert.Equal(t, []target{
+		{id: "exporters::awscloudwatchlogs", service: "logs", proxyAddress: "http://proxy:3128"},
+		{id: "exporters::awsemf/app", service: "logs", region: "us-west-2", roleARN: roleARN},
+		{id: "exporters::awsxray", service: "xray", endpoint: "https://xray.example.com", localMode: true},
+		{
+			id:        "exporters::prometheusremotewrite/amp",
+			service:   "aps",
+			endpoint:  "https://aps-workspaces.eu-west-1.amazonaws.com/workspaces/ws-1/api/v1/remote_write",
+			roleARN:   roleARN,
+			stsRegion: "us-east-1",
+		},
+		{id: "extensions::awsproxy", service: "xray", endpoint: "https://xray.example.com"},
+	}, targets(conf))
+}
+
+func TestRun(t *testing.T) {
+	sts := newSTS(t)
+	service := newService(t, 0)
+
+	tests := []struct {
+		name         string
+		accessKey    string
+		imds         string
+		env          map[string]string
+		exporter     map[string]any
+		expectedIMDS IMDSResult
+		expected     ComponentResult
+		problems     []string
+	}{
+		{
+			name:      "region from the config and credentials from the environment",
+			accessKey: "AKIAUSER",
+			exporter:  map[string]any{"region": "us-west-2", "endpoint": service.URL},
+			expected: ComponentResult{
+				Region:       "us-west-2",
+				RegionSource: "config",
+				Credentials:  "EnvConfigCredentials",
+				Identity:     userARN,
+				Endpoint:     service.URL,
+				Reachable:    true,
+				ClockSkew:    "0s",
+			},
+		},
+		{
+			name:      "region from IMDSv2",
+			accessKey: "AKIAUSER",
+			imds:      imdsV2,
+			exporter:  map[string]any{"endpoint": service.URL},
+			expected: ComponentResult{
+				Region:       "eu-west-1",
+				RegionSource: "imds",
+				Credentials:  "EnvConfigCredentials",
+				Identity:     userARN,
+				Endpoint:     service.URL,
+				Reachable:    true,
+				ClockSkew:    "0s",
+			},
+		},
+		{
+			name:      "region from the environment before IMDS",
+			accessKey: "AKIAUSER",
+			imds:      imdsV2,
+			env:       map[string]string{envKeyRegion: "ap-south-1"},
+			exporter:  map[string]any{"endpoint": service.URL},
+			expected: ComponentResult{
+				Region:       "ap-south-1",
+				RegionSource: "env:" + envKeyRegion,
+				Credentials:  "EnvConfigCredentials",
+				Identity:     userARN,
+				Endpoint:     service.URL,
+				Reachable:    true,
+				ClockSkew:    "0s",
+			},
+		},
+		{
+			name:      "hop limit too low",
+			accessKey: "AKIAUSER",
+			imds:      imdsV1,
+			exporter:  map[string]any{"endpoint": service.URL},
+			expected: ComponentResult{
+				Region:       "eu-west-1",
+				RegionSource: "imds",
+				Credentials:  "EnvConfigCredentials",
+				Identity:     userARN,
+				Endpoint:     service.URL,
+				Reachable:    true,
+				ClockSkew:    "0s",
+			},
+			problems: []string{"the hop limit of the instance is likely too low"},
+		},
+		{
+			name:      "hop limit too low without IMDSv1",
+			accessKey: "AKIAUSER",
+			imds:      imdsV2HopLimit,
+			exporter:  map[string]any{"endpoint": service.URL},
+			problems:  []string{"IMDSv1 is disabled and the hop limit of the instance is likely too low", "no region"},
+		},
+		{
+			name:      "no region in local mode",
+			accessKey: "AKIAUSER",
+			imds:      imdsV2,
+			exporter:  map[string]any{"endpoint": service.URL, "local_mode": true},
+			problems:  []string{"no region"},
+		},
+		{
+			name:      "assumed role",
+			accessKey: "AKIAUSER",
+			exporter:  map[string]any{"region": "us-west-2", "endpoint": service.URL, "role_arn": roleARN},
+			expected: ComponentResult{
+				Region:       "us-west-2",
+				RegionSource: "config",
+				Credentials:  "AssumeRoleProvider",
+				Identity:     "arn:aws:sts::123456789012:assumed-role/collector/session",
+				Endpoint:     service.URL,
+				Reachable:    true,
+				ClockSkew:    "0s",
+			},
+		},
+		{
+			name:     "no credentials",
+			exporter: map[string]any{"region": "us-west-2", "endpoint": service.URL},
+			expected: ComponentResult{
+				Region:       "us-west-2",
+				RegionSource: "config",
+				Endpoint:     service.URL,
+				Reachable:    true,
+				ClockSkew:    "0s",
+			},
+			problems: []string{"no credentials found"},
+		},
+		{
+			name:      "credentials rejected",
+			accessKey: "AKIAREVOKED",
+			exporter:  map[string]any{"region": "us-west-2", "endpoint": service.URL},
+			expected: ComponentResult{
+				Region:       "us-west-2",
+				RegionSource: "config",
+				Credentials:  "EnvConfigCredentials",
+				Endpoint:     service.URL,
+				Reachable:    true,
+				ClockSkew:    "0s",
+			},
+			problems: []string{"STS rejected the credentials of EnvConfigCredentials"},
+		},
+		{
+			name:      "unreachable endpoint",
+			accessKey: "AKIAUSER",
+			exporter:  map[string]any{"region": "us-west-2", "endpoint": "http://127.0.0.1:1"},
+			expected: ComponentResult{
+				Region:       "us-west-2",
+				RegionSource: "config",
+				Credentials:  "EnvConfigCredentials",
+				Identity:     userARN,
+				Endpoint:     "http://127.0.0.1:1",
+			},
+			problems: []string{"endpoint http://127.0.0.1:1 is not reachable"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setCredentials(t, tt.accessKey)
+			settings := Settings{
+				STSEndpoint: sts.URL,
+				Timeout:     time.Second,
+				LookupEnv: func(key string) (string, bool) {
+					value, ok := tt.env[key]
+					return value, ok
+				},
+			}
+			if tt.imds != "" {
+				settings.IMDSEndpoint = newIMDS(t, tt.imds).URL
+			}
+			conf := map[string]any{"exporters": map[string]any{"awsemf": tt.exporter}}
+
+			report := Run(context.Background(), conf, settings)
+			require.Len(t, report.Components, 1)
+			result := report.Components[0]
+			problems := append(report.IMDS.Problems, result.Problems...)
+			require.Len(t, problems, len(tt.problems), "problems: %v", problems)
+			for i, problem := range tt.problems {
+				assert.Contains(t, problems[i], problem)
+			}
+			assert.Equal(t, len(tt.problems), report.Problems())
+			if tt.imds != "" {
+				assert.Equal(t, tt.imds != imdsV2HopLimit, report.IMDS.Available)
+				assert.Equal(t, tt.imds == imdsV2, report.IMDS.IMDSv2)
+			}
+
+			result.Problems = nil
+			tt.expected.ID = "exporters::awsemf"
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestRunClockSkew(t *testing.T) {
+	setCredentials(t, "AKIAUSER")
+	service := newService(t, -10*time.Minute)
+	conf := map[string]any{"exporters": map[string]any{
+		"awsxray": map[string]any{"region": "us-west-2", "endpoint": service.URL},
+	}}
+
+	report := Run(context.Background(), conf, Settings{STSEndpoint: newSTS(t).URL, Timeout: time.Second})
+	require.Len(t, report.Components, 1)
+	assert.Equal(t, "-10m0s", report.Components[0].ClockSkew)
+	require.Len(t, report.Components[0].Problems, 1)
+	assert.Contains(t, report.Components[0].Problems[0], "the clock is off by -10m0s")
+}
+
+func TestServiceEndpoint(t *testing.T) {
+	endpoint, err := serviceEndpoint(target{service: "xray"}, "cn-north-1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://xray.cn-north-1.amazonaws.com.cn", endpoint)
+
+	endpoint, err = serviceEndpoint(target{service: "logs", endpoint: "https://logs.example.com"}, "us-west-2")
+	require.NoError(t, err)
+	assert.Equal(t, "https://logs.example.com", endpoint)
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	d := &diagnoser{settings: Settings{LookupEnv: func(key string) (string, bool) {
+		return "http://env-proxy:3128", key == "HTTPS_PROXY"
+	}}}
+	_, proxy, err := d.httpClient("http://proxy:3128")
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy:3128", proxy)
+
+	_, proxy, err = d.httpClient("")
+	require.NoError(t, err)
+	assert.Equal(t, "http://env-proxy:3128", proxy)
+
+	_, _, err = d.httpClient("http://[::1")
+	assert.Error(t, err)
+}
+
+func TestRegionFromEndpoint(t *testing.T) {
+	assert.Equal(t, "eu-west-1", regionFromEndpoint("https://aps-workspaces.eu-west-1.amazonaws.com/workspaces/ws-1/api/v1/remote_write"))
+	assert.Equal(t, "", regionFromEndpoint("https://prometheus.example.com/api/v1/write"))
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package exportedenv exports the environment variables of settings which change over time, e.g. on
+// a configuration reload, and restores the variables they no longer export.
+package exportedenv
+
+import (
+	"os"
+
+	"go.uber.org/multierr"
+)
+
+// Env is the set of variables exported by the last call to Export. The zero value is ready to use,
+// it is not safe for concurrent use.
+type Env struct {
+	// saved holds the values the exported variables had before, nil when they were not set
+	saved map[string]*string
+}
+
+// Export exports the variables of env. The variables exported by the previous call and not by this one
+// are restored to the values they had before.
+func (e *Env) Export(env map[string]string) error {
+	if e.saved == nil {
+		e.saved = map[string]*string{}
+	}
+	var errs error
+	for key, saved := range e.saved {
+		if _, ok := env[key]; ok {
+			continue
+		}
+		if saved == nil {
+			errs = multierr.Append(errs, os.Unsetenv(key))
+		} else {
+			errs = multierr.Append(errs, os.Setenv(key, *saved))
+		}
+		delete(e.saved, key)
+	}
+	for key, value := range env {
+		if _, ok := e.saved[key]; !ok {
+			if previous, set := os.LookupEnv(key); set {
+				e.saved[key] = &previous
+			} else {
+				e.saved[key] = nil
+			}
+		}
+		errs = multierr.Append(errs, os.Setenv(key, value))
+	}
+	return errs
+}
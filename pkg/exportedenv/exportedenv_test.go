@@ -0,0 +1,46 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package exportedenv
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	t.Setenv("EXPORTEDENV_SET", "before")
+	t.Setenv("EXPORTEDENV_UNSET", "")
+	require.NoError(t, os.Unsetenv("EXPORTEDENV_UNSET"))
+
+	var env Env
+	require.NoError(t, env.Export(map[string]string{"EXPORTEDENV_SET": "first", "EXPORTEDENV_UNSET": "first"}))
+	assert.Equal(t, "first", os.Getenv("EXPORTEDENV_SET"))
+	assert.Equal(t, "first", os.Getenv("EXPORTEDENV_UNSET"))
+
+	// the values saved are the ones from before the first export
+	require.NoError(t, env.Export(map[string]string{"EXPORTEDENV_SET": "second"}))
+	assert.Equal(t, "second", os.Getenv("EXPORTEDENV_SET"))
+	_, set := os.LookupEnv("EXPORTEDENV_UNSET")
+	assert.False(t, set)
+
+	require.NoError(t, env.Export(nil))
+	assert.Equal(t, "before", os.Getenv("EXPORTEDENV_SET"))
+	_, set = os.LookupEnv("EXPORTEDENV_UNSET")
+	assert.False(t, set)
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package pipelinehealthextension // import "github.com/aws-observability/aws-otel-collector/pkg/extension/pipelinehealthextension"
+
+import (
+	"errors"
+	"time"
+)
+
+// Config configures the pipeline_health extension.
+type Config struct {
+	// Endpoint the health status is served on.
+	Endpoint string `mapstructure:"endpoint"`
+	// MetricsEndpoint is the Prometheus endpoint of the collector's own telemetry, it is scraped to
+	// compute the send failure ratio of the exporters. The ratios are not computed when it is empty.
+	MetricsEndpoint string `mapstructure:"metrics_endpoint"`
+	// ScrapeInterval is the interval at which the metrics endpoint is scraped.
+	ScrapeInterval time.Duration `mapstructure:"scrape_interval"`
+	// Window is the sliding window over which the send failure ratio of the exporters is computed.
+	Window time.Duration `mapstructure:"window"`
+	// DegradedRatio is the send failure ratio from which the pipelines of an exporter are degraded.
+	DegradedRatio float64 `mapstructure:"degraded_ratio"`
+	// UnhealthyRatio is the send failure ratio from which the pipelines of an exporter are unhealthy.
+	UnhealthyRatio float64 `mapstructure:"unhealthy_ratio"`
+	// MinItems is the number of items an exporter must have tried to send within the window for its
+	// failure ratio to be taken into account.
+	MinItems int64 `mapstructure:"min_items"`
+}
+
+// Validate checks the intervals and the ratios.
+func (cfg *Config) Validate() error {
+	if cfg.Endpoint == "" {
+		return errors.New("endpoint must be set")
+	}
+	if cfg.MetricsEndpoint != "" {
+		if cfg.ScrapeInterval <= 0 {
+			return errors.New("scrape_interval must be positive")
+		}
+		if cfg.Window < cfg.ScrapeInterval {
+			return errors.New("window must not be shorter than scrape_interval")
+		}
+	}
+	if cfg.DegradedRatio <= 0 || cfg.DegradedRatio > 1 || cfg.UnhealthyRatio <= 0 || cfg.UnhealthyRatio > 1 {
+		return errors.New("degraded_ratio and unhealthy_ratio must be in (0, 1]")
+	}
+	if cfg.DegradedRatio > cfg.UnhealthyRatio {
+		return errors.New("degraded_ratio must not be greater than unhealthy_ratio")
+	}
+	if cfg.MinItems < 0 {
+		return errors.New("min_items must not be negative")
+	}
+	return nil
+}
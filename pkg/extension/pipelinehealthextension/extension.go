@@ -0,0 +1,186 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package pipelinehealthextension // import "github.com/aws-observability/aws-otel-collector/pkg/extension/pipelinehealthextension"
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+	"go.uber.org/zap"
+)
+
+const (
+	livenessPath  = "/liveness"
+	readinessPath = "/readiness"
+	pipelinesPath = "/pipelines"
+
+	readHeaderTimeout = 10 * time.Second
+)
+
+type pipelineHealthExtension struct {
+	cfg    *Config
+	logger *zap.Logger
+	health *aggregator
+	client *http.Client
+
+	server *http.Server
+	stop   context.CancelFunc
+	wg     sync.WaitGroup
+	// states are the last logged states of the pipelines
+	states map[string]healthState
+}
+
+var _ extension.PipelineWatcher = (*pipelineHealthExtension)(nil)
+var _ extension.StatusWatcher = (*pipelineHealthExtension)(nil)
+
+func newExtension(cfg *Config, logger *zap.Logger) *pipelineHealthExtension {
+	return &pipelineHealthExtension{
+		cfg:    cfg,
+		logger: logger,
+		health: newAggregator(cfg),
+		client: &http.Client{Timeout: cfg.ScrapeInterval},
+		stop:   func() {},
+		states: map[string]healthState{},
+	}
+}
+
+func (e *pipelineHealthExtension) Start(_ context.Context, _ component.Host) error {
+	listener, err := net.Listen("tcp", e.cfg.Endpoint)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(livenessPath, reportHandler(e.health.live))
+	mux.Handle(readinessPath, reportHandler(e.health.readiness))
+	mux.Handle(pipelinesPath, reportHandler(e.health.pipelines))
+	e.server = &http.Server{Handler: mux, ReadHeaderTimeout: readHeaderTimeout}
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		if err := e.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.logger.Error("pipeline health server stopped", zap.Error(err))
+		}
+	}()
+
+	if e.cfg.MetricsEndpoint != "" {
+		ctx, cancel := context.WithCancel(context.Background())
+		e.stop = cancel
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			e.scrapeLoop(ctx)
+		}()
+	}
+	return nil
+}
+
+func (e *pipelineHealthExtension) Shutdown(ctx context.Context) error {
+	e.stop()
+	var err error
+	if e.server != nil {
+		err = e.server.Shutdown(ctx)
+	}
+	e.wg.Wait()
+	return err
+}
+
+func (e *pipelineHealthExtension) Ready() error {
+	e.health.setReady(true)
+	return nil
+}
+
+func (e *pipelineHealthExtension) NotReady() error {
+	e.health.setReady(false)
+	return nil
+}
+
+func (e *pipelineHealthExtension) ComponentStatusChanged(source *component.InstanceID, event *component.StatusEvent) {
+	e.health.statusChanged(source, event)
+}
+
+func (e *pipelineHealthExtension) scrapeLoop(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.ScrapeInterval)
+	defer ticker.Stop()
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		counters, err := scrapeExporterCounters(ctx, e.client, e.cfg.MetricsEndpoint)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// the endpoint is not available when the collector telemetry is disabled, warn only once
+			if !failing {
+				e.logger.Warn("Failed to scrape the collector metrics, the send failures of the exporters are not taken into account",
+					zap.String("endpoint", e.cfg.MetricsEndpoint), zap.Error(err))
+			}
+			failing = true
+			continue
+		}
+		failing = false
+		e.health.record(time.Now(), counters)
+		e.logStateChanges()
+	}
+}
+
+// logStateChanges logs the pipelines whose state changed since the last scrape.
+func (e *pipelineHealthExtension) logStateChanges() {
+	report := e.health.pipelines()
+	for _, name := range sortedPipelines(report) {
+		state := report.Pipelines[name].Status
+		previous, ok := e.states[name]
+		e.states[name] = state
+		if state == previous || (!ok && state == stateHealthy) {
+			continue
+		}
+		if state == stateHealthy {
+			e.logger.Info("Pipeline is healthy again", zap.String("pipeline", name))
+		} else {
+			e.logger.Warn("Pipeline health changed", zap.String("pipeline", name), zap.String("status", string(state)))
+		}
+	}
+}
+
+// reportHandler serves the report as JSON, with a 503 status when it is unhealthy.
+func reportHandler(report func() healthReport) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		rep := report()
+		w.Header().Set("Content-Type", "application/json")
+		if rep.Status == stateUnhealthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		_ = json.NewEncoder(w).Encode(rep)
+	})
+}
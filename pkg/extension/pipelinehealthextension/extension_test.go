@@ -0,0 +1,167 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package pipelinehealthextension
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/extension/extensiontest"
+)
+
+func TestConfigValidate(t *testing.T) {
+	testCases := []struct {
+		name           string
+		modify         func(cfg *Config)
+		errorAssertion assert.ErrorAssertionFunc
+	}{
+		{
+			name:           "Default",
+			modify:         func(*Config) {},
+			errorAssertion: assert.NoError,
+		},
+		{
+			name:           "NoEndpoint",
+			modify:         func(cfg *Config) { cfg.Endpoint = "" },
+			errorAssertion: assert.Error,
+		},
+		{
+			name:           "WindowShorterThanInterval",
+			modify:         func(cfg *Config) { cfg.Window = time.Second },
+			errorAssertion: assert.Error,
+		},
+		{
+			name: "NoMetricsEndpoint",
+			modify: func(cfg *Config) {
+				cfg.MetricsEndpoint = ""
+				cfg.ScrapeInterval = 0
+			},
+			errorAssertion: assert.NoError,
+		},
+		{
+			name:           "RatioOutOfRange",
+			modify:         func(cfg *Config) { cfg.UnhealthyRatio = 1.5 },
+			errorAssertion: assert.Error,
+		},
+		{
+			name:           "DegradedAboveUnhealthy",
+			modify:         func(cfg *Config) { cfg.DegradedRatio = 0.95 },
+			errorAssertion: assert.Error,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			tc.modify(cfg)
+			tc.errorAssertion(t, cfg.Validate())
+		})
+	}
+}
+
+func TestExporterCounters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `# TYPE otelcol_exporter_sent_spans counter
+otelcol_exporter_sent_spans{exporter="awsxray",service_instance_id="a"} 90
+# TYPE otelcol_exporter_send_failed_spans counter
+otelcol_exporter_send_failed_spans{exporter="awsxray",service_instance_id="a"} 10
+# TYPE otelcol_exporter_sent_metric_points_total counter
+otelcol_exporter_sent_metric_points_total{exporter="awsemf/app"} 5
+# TYPE otelcol_receiver_accepted_spans counter
+otelcol_receiver_accepted_spans{receiver="otlp",transport="grpc"} 100
+`)
+	}))
+	defer server.Close()
+
+	counters, err := scrapeExporterCounters(context.Background(), server.Client(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, map[exporterKey]sample{
+		{exporter: "awsxray", signal: component.DataTypeTraces}:     {sent: 90, failed: 10},
+		{exporter: "awsemf/app", signal: component.DataTypeMetrics}: {sent: 5},
+	}, counters)
+}
+
+func TestExtension(t *testing.T) {
+	var failed atomic.Int64
+	metrics := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, "otelcol_exporter_sent_spans{exporter=\"awsxray\"} 0\notelcol_exporter_send_failed_spans{exporter=\"awsxray\"} %d\n", failed.Add(10))
+	}))
+	defer metrics.Close()
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = freeEndpoint(t)
+	cfg.MetricsEndpoint = metrics.URL
+	cfg.ScrapeInterval = 10 * time.Millisecond
+	cfg.Window = time.Minute
+
+	ext, err := NewFactory().CreateExtension(context.Background(), extensiontest.NewNopCreateSettings(), cfg)
+	require.NoError(t, err)
+	require.NoError(t, ext.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() { assert.NoError(t, ext.Shutdown(context.Background())) }()
+
+	e := ext.(*pipelineHealthExtension)
+	e.ComponentStatusChanged(instanceID(component.KindReceiver, "otlp", "traces"), component.NewStatusEvent(component.StatusOK))
+	e.ComponentStatusChanged(instanceID(component.KindExporter, "awsxray", "traces"), component.NewStatusEvent(component.StatusOK))
+
+	get := func(path string) (int, healthReport) {
+		resp, err := http.Get("http://" + cfg.Endpoint + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var report healthReport
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		return resp.StatusCode, report
+	}
+
+	code, _ := get(livenessPath)
+	assert.Equal(t, http.StatusOK, code)
+	code, report := get(readinessPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, stateUnhealthy, report.Status)
+
+	require.NoError(t, e.Ready())
+	code, _ = get(readinessPath)
+	assert.Equal(t, http.StatusOK, code)
+
+	// every span the exporter tries to send fails
+	assert.Eventually(t, func() bool {
+		code, report = get(pipelinesPath)
+		return code == http.StatusServiceUnavailable
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, stateUnhealthy, report.Pipelines["traces"].Status)
+	assert.Equal(t, "StatusOK", report.Pipelines["traces"].Components["receiver/otlp"].Status)
+
+	resp, err := http.Post("http://"+cfg.Endpoint+pipelinesPath, "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func freeEndpoint(t *testing.T) string {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
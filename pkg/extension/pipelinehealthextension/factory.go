@@ -0,0 +1,53 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package pipelinehealthextension // import "github.com/aws-observability/aws-otel-collector/pkg/extension/pipelinehealthextension"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+)
+
+const (
+	typeStr = "pipeline_health"
+
+	defaultEndpoint        = "localhost:13135"
+	defaultMetricsEndpoint = "http://localhost:8888/metrics"
+)
+
+// NewFactory creates a factory for the pipeline_health extension, which reports the health of the
+// pipelines based on the status of their components and the send failures of their exporters.
+func NewFactory() extension.Factory {
+	return extension.NewFactory(component.Type(typeStr), createDefaultConfig, createExtension, component.StabilityLevelAlpha)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		Endpoint:        defaultEndpoint,
+		MetricsEndpoint: defaultMetricsEndpoint,
+		ScrapeInterval:  30 * time.Second,
+		Window:          10 * time.Minute,
+		DegradedRatio:   0.1,
+		UnhealthyRatio:  0.9,
+		MinItems:        1,
+	}
+}
+
+func createExtension(_ context.Context, set extension.CreateSettings, cfg component.Config) (extension.Extension, error) {
+	return newExtension(cfg.(*Config), set.Logger), nil
+}
@@ -0,0 +1,293 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package pipelinehealthextension // import "github.com/aws-observability/aws-otel-collector/pkg/extension/pipelinehealthextension"
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// healthState is the health of a pipeline, or of the collector as the worst of its pipelines.
+type healthState string
+
+const (
+	stateHealthy   healthState = "healthy"
+	stateDegraded  healthState = "degraded"
+	stateUnhealthy healthState = "unhealthy"
+)
+
+func (s healthState) severity() int {
+	switch s {
+	case stateDegraded:
+		return 1
+	case stateUnhealthy:
+		return 2
+	}
+	return 0
+}
+
+func worst(a, b healthState) healthState {
+	if b.severity() > a.severity() {
+		return b
+	}
+	return a
+}
+
+// healthReport is the body of the responses of the extension.
+type healthReport struct {
+	Status    healthState               `json:"status"`
+	UpSince   time.Time                 `json:"upSince"`
+	Uptime    string                    `json:"uptime"`
+	Error     string                    `json:"error,omitempty"`
+	Pipelines map[string]pipelineReport `json:"pipelines,omitempty"`
+}
+
+type pipelineReport struct {
+	Status     healthState                `json:"status"`
+	Components map[string]componentReport `json:"components"`
+}
+
+type componentReport struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// FailureRatio is the ratio of items the exporter failed to send within the window.
+	FailureRatio *float64 `json:"failureRatio,omitempty"`
+}
+
+// exporterKey identifies the counters of an exporter for a signal, an exporter used in pipelines of
+// several signals is a separate instance for each of them.
+type exporterKey struct {
+	exporter string
+	signal   component.DataType
+}
+
+// sample holds the cumulative counters of an exporter at a point in time.
+type sample struct {
+	at     time.Time
+	sent   float64
+	failed float64
+}
+
+// aggregator keeps the last status event of every component and the send counters of the exporters
+// over the window, and aggregates them into the health of each pipeline.
+type aggregator struct {
+	cfg *Config
+	now func() time.Time
+
+	mu         sync.Mutex
+	started    time.Time
+	ready      bool
+	components map[*component.InstanceID]*component.StatusEvent
+	samples    map[exporterKey][]sample
+}
+
+func newAggregator(cfg *Config) *aggregator {
+	a := &aggregator{
+		cfg:        cfg,
+		now:        time.Now,
+		components: map[*component.InstanceID]*component.StatusEvent{},
+		samples:    map[exporterKey][]sample{},
+	}
+	a.started = a.now()
+	return a
+}
+
+func (a *aggregator) statusChanged(source *component.InstanceID, event *component.StatusEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.components[source] = event
+}
+
+func (a *aggregator) setReady(ready bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ready = ready
+}
+
+// record adds the counters scraped at the given time, the samples which left the window are dropped
+// except the most recent of them, used as the baseline of the window.
+func (a *aggregator) record(at time.Time, counters map[exporterKey]sample) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key, c := range counters {
+		c.at = at
+		samples := a.samples[key]
+		if n := len(samples); n > 0 && (c.sent < samples[n-1].sent || c.failed < samples[n-1].failed) {
+			// the counters were reset, e.g. by a configuration reload
+			samples = nil
+		}
+		samples = append(samples, c)
+		for len(samples) > 1 && !samples[1].at.After(at.Add(-a.cfg.Window)) {
+			samples = samples[1:]
+		}
+		a.samples[key] = samples
+	}
+}
+
+// failureRatio returns the ratio of failed items within the window, ok is false when the exporter
+// did not try to send enough items for the ratio to be meaningful.
+func (a *aggregator) failureRatio(key exporterKey) (ratio float64, ok bool) {
+	samples := a.samples[key]
+	if len(samples) < 2 {
+		return 0, false
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	sent, failed := last.sent-first.sent, last.failed-first.failed
+	total := sent + failed
+	if total <= 0 || total < float64(a.cfg.MinItems) {
+		return 0, false
+	}
+	return failed / total, true
+}
+
+// live reports whether the collector is running, it is not once a component reported a fatal error.
+func (a *aggregator) live() healthReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r := a.newReport(stateHealthy)
+	for source, event := range a.components {
+		if event.Status() == component.StatusFatalError {
+			r.Status = stateUnhealthy
+			r.Error = componentName(source) + ": " + errorString(event)
+		}
+	}
+	return r
+}
+
+// readiness reports whether the pipelines are ready to receive data, i.e. they were all started and
+// none of their components failed permanently.
+func (a *aggregator) readiness() healthReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r := a.newReport(stateHealthy)
+	if !a.ready {
+		r.Status = stateUnhealthy
+		r.Error = "pipelines not ready"
+		return r
+	}
+	for source, event := range a.components {
+		switch event.Status() {
+		case component.StatusStarting, component.StatusPermanentError, component.StatusFatalError:
+			r.Status = stateUnhealthy
+			r.Error = componentName(source) + ": " + event.Status().String()
+			if event.Err() != nil {
+				r.Error += ": " + event.Err().Error()
+			}
+			return r
+		}
+	}
+	return r
+}
+
+// pipelines reports the health of every pipeline and of the collector as the worst of them.
+func (a *aggregator) pipelines() healthReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r := a.newReport(stateHealthy)
+	r.Pipelines = map[string]pipelineReport{}
+	for source, event := range a.components {
+		state := componentState(event.Status())
+		report := componentReport{Status: event.Status().String(), Error: errorString(event)}
+		if source.Kind == component.KindExporter {
+			for pipeline := range source.PipelineIDs {
+				p := pipelineEntry(r, pipeline)
+				exporterState, exporterReport := state, report
+				if ratio, ok := a.failureRatio(exporterKey{exporter: source.ID.String(), signal: pipeline.Type()}); ok {
+					exporterReport.FailureRatio = &ratio
+					exporterState = worst(exporterState, a.ratioState(ratio))
+				}
+				p.Components[componentName(source)] = exporterReport
+				p.Status = worst(p.Status, exporterState)
+				r.Pipelines[pipeline.String()] = p
+			}
+			continue
+		}
+		for pipeline := range source.PipelineIDs {
+			p := pipelineEntry(r, pipeline)
+			p.Components[componentName(source)] = report
+			p.Status = worst(p.Status, state)
+			r.Pipelines[pipeline.String()] = p
+		}
+	}
+	for _, p := range r.Pipelines {
+		r.Status = worst(r.Status, p.Status)
+	}
+	return r
+}
+
+func pipelineEntry(r healthReport, pipeline component.ID) pipelineReport {
+	p, ok := r.Pipelines[pipeline.String()]
+	if !ok {
+		p = pipelineReport{Status: stateHealthy, Components: map[string]componentReport{}}
+	}
+	return p
+}
+
+func (a *aggregator) ratioState(ratio float64) healthState {
+	switch {
+	case ratio >= a.cfg.UnhealthyRatio:
+		return stateUnhealthy
+	case ratio >= a.cfg.DegradedRatio:
+		return stateDegraded
+	}
+	return stateHealthy
+}
+
+func (a *aggregator) newReport(state healthState) healthReport {
+	return healthReport{
+		Status:  state,
+		UpSince: a.started,
+		Uptime:  a.now().Sub(a.started).Round(time.Second).String(),
+	}
+}
+
+// componentState maps the status of a component to the health of its pipelines.
+func componentState(status component.Status) healthState {
+	switch status {
+	case component.StatusOK:
+		return stateHealthy
+	case component.StatusPermanentError, component.StatusFatalError, component.StatusStopped:
+		return stateUnhealthy
+	}
+	// starting, stopping, or failing to process some data
+	return stateDegraded
+}
+
+// componentName returns the name of a component within a pipeline, e.g. exporter/awsemf.
+func componentName(source *component.InstanceID) string {
+	return strings.ToLower(source.Kind.String()) + "/" + source.ID.String()
+}
+
+func errorString(event *component.StatusEvent) string {
+	if event.Err() == nil {
+		return ""
+	}
+	return event.Err().Error()
+}
+
+// sortedPipelines returns the names of the pipelines of the report in a stable order.
+func sortedPipelines(r healthReport) []string {
+	names := make([]string, 0, len(r.Pipelines))
+	for name := range r.Pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package pipelinehealthextension
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+)
+
+func mustID(s string) component.ID {
+	var id component.ID
+	if err := id.UnmarshalText([]byte(s)); err != nil {
+		panic(err)
+	}
+	return id
+}
+
+func instanceID(kind component.Kind, id string, pipelines ...string) *component.InstanceID {
+	source := &component.InstanceID{
+		ID:          mustID(id),
+		Kind:        kind,
+		PipelineIDs: map[component.ID]struct{}{},
+	}
+	for _, p := range pipelines {
+		source.PipelineIDs[mustID(p)] = struct{}{}
+	}
+	return source
+}
+
+func newTestAggregator(t *testing.T) (*aggregator, *time.Time) {
+	cfg := createDefaultConfig().(*Config)
+	require.NoError(t, cfg.Validate())
+	a := newAggregator(cfg)
+	now := a.started
+	a.now = func() time.Time { return now }
+	return a, &now
+}
+
+func TestPipelinesComponentStatus(t *testing.T) {
+	a, _ := newTestAggregator(t)
+	receiver := instanceID(component.KindReceiver, "otlp", "traces", "metrics")
+	traces := instanceID(component.KindExporter, "awsxray", "traces")
+	metrics := instanceID(component.KindExporter, "awsemf", "metrics")
+
+	for _, source := range []*component.InstanceID{receiver, traces, metrics} {
+		a.statusChanged(source, component.NewStatusEvent(component.StatusOK))
+	}
+	r := a.pipelines()
+	assert.Equal(t, stateHealthy, r.Status)
+	require.Len(t, r.Pipelines, 2)
+	assert.Equal(t, stateHealthy, r.Pipelines["traces"].Status)
+	assert.Equal(t, componentReport{Status: "StatusOK"}, r.Pipelines["metrics"].Components["exporter/awsemf"])
+	assert.Contains(t, r.Pipelines["metrics"].Components, "receiver/otlp")
+
+	a.statusChanged(traces, component.NewRecoverableErrorEvent(errors.New("throttled")))
+	r = a.pipelines()
+	assert.Equal(t, stateDegraded, r.Status)
+	assert.Equal(t, stateDegraded, r.Pipelines["traces"].Status)
+	assert.Equal(t, "throttled", r.Pipelines["traces"].Components["exporter/awsxray"].Error)
+	assert.Equal(t, stateHealthy, r.Pipelines["metrics"].Status)
+
+	a.statusChanged(metrics, component.NewPermanentErrorEvent(errors.New("invalid credentials")))
+	r = a.pipelines()
+	assert.Equal(t, stateUnhealthy, r.Status)
+	assert.Equal(t, stateUnhealthy, r.Pipelines["metrics"].Status)
+}
+
+func TestPipelinesFailureRatio(t *testing.T) {
+	a, now := newTestAggregator(t)
+	exporter := instanceID(component.KindExporter, "awsemf", "metrics", "metrics/app")
+	a.statusChanged(exporter, component.NewStatusEvent(component.StatusOK))
+	key := exporterKey{exporter: "awsemf", signal: component.DataTypeMetrics}
+
+	record := func(after time.Duration, sent, failed float64) {
+		*now = now.Add(after)
+		a.record(*now, map[exporterKey]sample{key: {sent: sent, failed: failed}})
+	}
+
+	// a single sample does not tell anything about the window
+	record(0, 100, 0)
+	r := a.pipelines()
+	assert.Equal(t, stateHealthy, r.Status)
+	assert.Nil(t, r.Pipelines["metrics"].Components["exporter/awsemf"].FailureRatio)
+
+	record(time.Minute, 180, 20)
+	r = a.pipelines()
+	assert.Equal(t, stateDegraded, r.Status)
+	require.NotNil(t, r.Pipelines["metrics/app"].Components["exporter/awsemf"].FailureRatio)
+	assert.InDelta(t, 0.2, *r.Pipelines["metrics/app"].Components["exporter/awsemf"].FailureRatio, 1e-9)
+
+	// every request fails for the whole window
+	record(5*time.Minute, 180, 500)
+	record(5*time.Minute, 180, 1000)
+	r = a.pipelines()
+	assert.Equal(t, stateUnhealthy, r.Status)
+	assert.Equal(t, stateUnhealthy, r.Pipelines["metrics"].Status)
+	assert.InDelta(t, 1, *r.Pipelines["metrics"].Components["exporter/awsemf"].FailureRatio, 1e-9)
+
+	// the failures leave the window once the exporter recovers
+	record(5*time.Minute, 1000, 1000)
+	record(5*time.Minute, 2000, 1000)
+	assert.Equal(t, stateHealthy, a.pipelines().Status)
+
+	// counters reset by a reload start a new window
+	record(time.Minute, 10, 10)
+	assert.Equal(t, stateHealthy, a.pipelines().Status)
+	assert.Len(t, a.samples[key], 1)
+}
+
+func TestPipelinesMinItems(t *testing.T) {
+	a, now := newTestAggregator(t)
+	a.cfg.MinItems = 100
+	exporter := instanceID(component.KindExporter, "awsxray", "traces")
+	a.statusChanged(exporter, component.NewStatusEvent(component.StatusOK))
+	key := exporterKey{exporter: "awsxray", signal: component.DataTypeTraces}
+
+	a.record(*now, map[exporterKey]sample{key: {}})
+	a.record(now.Add(time.Minute), map[exporterKey]sample{key: {failed: 10}})
+	assert.Equal(t, stateHealthy, a.pipelines().Status)
+	a.record(now.Add(2*time.Minute), map[exporterKey]sample{key: {failed: 100}})
+	assert.Equal(t, stateUnhealthy, a.pipelines().Status)
+}
+
+func TestReadiness(t *testing.T) {
+	a, _ := newTestAggregator(t)
+	receiver := instanceID(component.KindReceiver, "otlp", "traces")
+	a.statusChanged(receiver, component.NewStatusEvent(component.StatusStarting))
+	r := a.readiness()
+	assert.Equal(t, stateUnhealthy, r.Status)
+	assert.Equal(t, "pipelines not ready", r.Error)
+
+	a.setReady(true)
+	r = a.readiness()
+	assert.Equal(t, stateUnhealthy, r.Status)
+	assert.Equal(t, "receiver/otlp: StatusStarting", r.Error)
+
+	a.statusChanged(receiver, component.NewStatusEvent(component.StatusOK))
+	assert.Equal(t, stateHealthy, a.readiness().Status)
+
+	// recoverable errors do not make the collector unready
+	a.statusChanged(receiver, component.NewRecoverableErrorEvent(errors.New("queue full")))
+	assert.Equal(t, stateHealthy, a.readiness().Status)
+
+	a.setReady(false)
+	assert.Equal(t, stateUnhealthy, a.readiness().Status)
+}
+
+func TestLiveness(t *testing.T) {
+	a, now := newTestAggregator(t)
+	*now = now.Add(90 * time.Second)
+	r := a.live()
+	assert.Equal(t, stateHealthy, r.Status)
+	assert.Equal(t, "1m30s", r.Uptime)
+
+	a.statusChanged(instanceID(component.KindExporter, "awsemf", "metrics"), component.NewPermanentErrorEvent(errors.New("denied")))
+	assert.Equal(t, stateHealthy, a.live().Status)
+
+	a.statusChanged(instanceID(component.KindReceiver, "otlp", "traces"), component.NewFatalErrorEvent(errors.New("port in use")))
+	r = a.live()
+	assert.Equal(t, stateUnhealthy, r.Status)
+	assert.Equal(t, "receiver/otlp: port in use", r.Error)
+}
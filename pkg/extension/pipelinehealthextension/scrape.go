@@ -0,0 +1,108 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package pipelinehealthextension // import "github.com/aws-observability/aws-otel-collector/pkg/extension/pipelinehealthextension"
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"go.opentelemetry.io/collector/component"
+)
+
+const exporterLabel = "exporter"
+
+// sentMetrics and failedMetrics map the send counters of the exporters, as exposed by the collector
+// telemetry, to the signal they count.
+var (
+	sentMetrics = map[string]component.DataType{
+		"otelcol_exporter_sent_spans":         component.DataTypeTraces,
+		"otelcol_exporter_sent_metric_points": component.DataTypeMetrics,
+		"otelcol_exporter_sent_log_records":   component.DataTypeLogs,
+	}
+	failedMetrics = map[string]component.DataType{
+		"otelcol_exporter_send_failed_spans":         component.DataTypeTraces,
+		"otelcol_exporter_send_failed_metric_points": component.DataTypeMetrics,
+		"otelcol_exporter_send_failed_log_records":   component.DataTypeLogs,
+	}
+)
+
+// scrapeExporterCounters returns the cumulative send counters of every exporter, read from the
+// Prometheus endpoint of the collector telemetry.
+func scrapeExporterCounters(ctx context.Context, client *http.Client, endpoint string) (map[exporterKey]sample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+	return exporterCounters(families), nil
+}
+
+func exporterCounters(families map[string]*dto.MetricFamily) map[exporterKey]sample {
+	counters := map[exporterKey]sample{}
+	for name, family := range families {
+		// the counters have a _total suffix when exposed through the OpenTelemetry SDK
+		name = strings.TrimSuffix(name, "_total")
+		signal, sent := sentMetrics[name]
+		if !sent {
+			var failed bool
+			if signal, failed = failedMetrics[name]; !failed {
+				continue
+			}
+		}
+		for _, m := range family.GetMetric() {
+			exporter := labelValue(m, exporterLabel)
+			if exporter == "" {
+				continue
+			}
+			value := m.GetCounter().GetValue() + m.GetUntyped().GetValue()
+			key := exporterKey{exporter: exporter, signal: signal}
+			c := counters[key]
+			if sent {
+				c.sent += value
+			} else {
+				c.failed += value
+			}
+			counters[key] = c
+		}
+	}
+	return counters
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, label := range m.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package extraconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const cgroupRoot = "sys/fs/cgroup"
+
+// cgroup v1 uses a huge number when the memory is not limited
+const cgroupV1Unlimited = 1 << 62
+
+// CgroupLimits are the resource limits of the cgroup of the process, zero means unlimited.
+type CgroupLimits struct {
+	// Memory is the memory limit in bytes
+	Memory int64
+	// CPU is the CPU quota in cores, e.g. 0.5 for half a core
+	CPU float64
+	// Version is the cgroup version the limits were read from, 1 or 2, 0 when the process is not in a cgroup
+	Version int
+}
+
+// GetCgroupLimits reads the memory limit and the CPU quota of the cgroup of the process, with
+// cgroup v1 or v2.
+func GetCgroupLimits() (CgroupLimits, error) {
+	return detector{lookupEnv: os.LookupEnv, root: "/"}.cgroupLimits()
+}
+
+func (d detector) cgroupLimits() (CgroupLimits, error) {
+	content, err := os.ReadFile(filepath.Join(d.root, cgroupFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return CgroupLimits{}, nil
+		}
+		return CgroupLimits{}, err
+	}
+	paths := cgroupPaths(string(content))
+	if d.exists(filepath.Join(cgroupRoot, "cgroup.controllers")) {
+		return d.cgroupV2Limits(paths[""])
+	}
+	return d.cgroupV1Limits(paths)
+}
+
+// cgroupV2Limits reads memory.max and cpu.max, "max" meaning unlimited.
+func (d detector) cgroupV2Limits(path string) (CgroupLimits, error) {
+	limits := CgroupLimits{Version: 2}
+	if value, ok := d.readCgroupFile("", path, "memory.max"); ok && value != "max" {
+		memory, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return limits, fmt.Errorf("invalid memory.max %q: %w", value, err)
+		}
+		limits.Memory = memory
+	}
+	if value, ok := d.readCgroupFile("", path, "cpu.max"); ok {
+		// the quota and the period in microseconds
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			return limits, fmt.Errorf("invalid cpu.max %q", value)
+		}
+		if fields[0] != "max" {
+			cpu, err := cpuQuota(fields[0], fields[1])
+			if err != nil {
+				return limits, fmt.Errorf("invalid cpu.max %q: %w", value, err)
+			}
+			limits.CPU = cpu
+		}
+	}
+	return limits, nil
+}
+
+// cgroupV1Limits reads the memory and cpu controllers, a negative quota meaning unlimited.
+func (d detector) cgroupV1Limits(paths map[string]string) (CgroupLimits, error) {
+	limits := CgroupLimits{Version: 1}
+	memoryPath, ok := paths["memory"]
+	if !ok {
+		return CgroupLimits{}, nil
+	}
+	if value, ok := d.readCgroupFile("memory", memoryPath, "memory.limit_in_bytes"); ok {
+		memory, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return limits, fmt.Errorf("invalid memory.limit_in_bytes %q: %w", value, err)
+		}
+		if memory < cgroupV1Unlimited {
+			limits.Memory = memory
+		}
+	}
+	cpuPath, ok := paths["cpu"]
+	if !ok {
+		return limits, nil
+	}
+	// the cpu controller is often mounted together with cpuacct
+	for _, controller := range []string{"cpu", "cpu,cpuacct", "cpuacct,cpu"} {
+		quota, ok := d.readCgroupFile(controller, cpuPath, "cpu.cfs_quota_us")
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(quota, "-") {
+			break
+		}
+		period, _ := d.readCgroupFile(controller, cpuPath, "cpu.cfs_period_us")
+		cpu, err := cpuQuota(quota, period)
+		if err != nil {
+			return limits, fmt.Errorf("invalid cpu quota %q/%q: %w", quota, period, err)
+		}
+		limits.CPU = cpu
+		break
+	}
+	return limits, nil
+}
+
+// readCgroupFile reads a file of the cgroup of the process in the hierarchy of the controller, the
+// cgroup v2 one having no controller. The cgroup is usually the root of the hierarchy mounted in a
+// container, the file is looked up there when it is not found at the path of the cgroup.
+func (d detector) readCgroupFile(controller, path, file string) (string, bool) {
+	for _, candidate := range []string{
+		filepath.Join(d.root, cgroupRoot, controller, path, file),
+		filepath.Join(d.root, cgroupRoot, controller, file),
+	} {
+		if content, err := os.ReadFile(candidate); err == nil {
+			return strings.TrimSpace(string(content)), true
+		}
+	}
+	return "", false
+}
+
+// cgroupPaths returns the cgroup paths of the process by controllers, the cgroup v2 path has no
+// controller.
+func cgroupPaths(content string) map[string]string {
+	paths := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		// hierarchy-id:controllers:path
+		fields := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[1] == "" {
+			paths[""] = fields[2]
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			paths[controller] = fields[2]
+		}
+	}
+	return paths
+}
+
+func cpuQuota(quota, period string) (float64, error) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, err
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil {
+		return 0, err
+	}
+	if p <= 0 {
+		return 0, fmt.Errorf("invalid period %v", p)
+	}
+	return q / p, nil
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package extraconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgroupLimits(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		expected CgroupLimits
+		err      bool
+	}{
+		{
+			name:     "NoCgroup",
+			expected: CgroupLimits{},
+		},
+		{
+			name: "V2",
+			files: map[string]string{
+				cgroupFile:                         "0::/\n",
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory pids\n",
+				"sys/fs/cgroup/memory.max":         "536870912\n",
+				"sys/fs/cgroup/cpu.max":            "25000 100000\n",
+			},
+			expected: CgroupLimits{Memory: 512 << 20, CPU: 0.25, Version: 2},
+		},
+		{
+			name: "V2Unlimited",
+			files: map[string]string{
+				cgroupFile:                         "0::/\n",
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory pids\n",
+				"sys/fs/cgroup/memory.max":         "max\n",
+				"sys/fs/cgroup/cpu.max":            "max 100000\n",
+			},
+			expected: CgroupLimits{Version: 2},
+		},
+		{
+			name: "V2Nested",
+			files: map[string]string{
+				cgroupFile:                         "0::/system.slice/aws-otel-collector.service\n",
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory pids\n",
+				"sys/fs/cgroup/system.slice/aws-otel-collector.service/memory.max": "1073741824\n",
+				"sys/fs/cgroup/system.slice/aws-otel-collector.service/cpu.max":    "200000 100000\n",
+			},
+			expected: CgroupLimits{Memory: 1 << 30, CPU: 2, Version: 2},
+		},
+		{
+			name: "V1",
+			files: map[string]string{
+				cgroupFile: "9:memory:/ecs/task/abc\n4:cpu,cpuacct:/ecs/task/abc\n",
+				"sys/fs/cgroup/memory/memory.limit_in_bytes":  "268435456\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_quota_us":  "150000\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+			},
+			expected: CgroupLimits{Memory: 256 << 20, CPU: 1.5, Version: 1},
+		},
+		{
+			name: "V1Unlimited",
+			files: map[string]string{
+				cgroupFile: "9:memory:/\n4:cpu:/\n",
+				"sys/fs/cgroup/memory/memory.limit_in_bytes": "9223372036854771712\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         "-1\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us":        "100000\n",
+			},
+			expected: CgroupLimits{Version: 1},
+		},
+		{
+			name: "InvalidMemoryMax",
+			files: map[string]string{
+				cgroupFile:                         "0::/\n",
+				"sys/fs/cgroup/cgroup.controllers": "memory\n",
+				"sys/fs/cgroup/memory.max":         "lots\n",
+			},
+			err: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tc.files {
+				path := filepath.Join(root, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+			}
+			limits, err := detector{root: root}.cgroupLimits()
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, limits)
+		})
+	}
+}
@@ -17,9 +17,22 @@ package extraconfig
 
 import (
 	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/outbound"
 )
 
 var (
@@ -27,38 +40,371 @@ var (
 	WindowsExtraConfigPath = "C:\\ProgramData\\Amazon\\AWSOTelCollector\\Configs\\extracfg.txt"
 )
 
+// ExtraConfig holds the settings of the extracfg.txt file. The file uses a key=value format with one
+// setting per line, lines starting with # are comments.
 type ExtraConfig struct {
-	LoggingLevel      string
-	AwsProfile        string
-	AwsCredentialFile string
+	LoggingLevel string
+	// ComponentLogLevels are the log levels of components, e.g. awsemf:debug,otlp/app:warn.
+	ComponentLogLevels map[string]string
+	AwsProfile         string
+	AwsCredentialFile  string
+	AwsRegion          string
+	RoleArn            string
+	// ExternalID, RoleSessionName, WebIdentityTokenFile and CredentialDuration set how RoleArn is
+	// assumed, they are shared by the AWS components with STSRegionalEndpoints.
+	ExternalID           string
+	RoleSessionName      string
+	WebIdentityTokenFile string
+	CredentialDuration   time.Duration
+	// STSRegionalEndpoints is regional or legacy.
+	STSRegionalEndpoints string
+	Proxy                ProxyConfig
+	LogFile              LogFileConfig
+	CloudWatchLogs       CloudWatchLogsConfig
+	// FeatureGates are applied like the --feature-gates flag, e.g. +gate to enable and -gate to disable it.
+	FeatureGates []string
+	// AdminEndpoint is the endpoint of the local admin HTTP server, e.g. localhost:13134.
+	AdminEndpoint string
+	// ReloadOnSIGHUP reloads the file when the collector reloads its configuration on SIGHUP.
+	ReloadOnSIGHUP bool
+	// StrictEnv fails the configuration when it references undefined environment variables.
+	StrictEnv bool
+
+	// Env holds the keys which are not part of the schema. For backward compatibility they are
+	// exported as environment variables.
+	Env map[string]string
+	// Warnings are the problems found in the file which did not prevent it from being parsed.
+	Warnings []string
+}
+
+// ProxyConfig holds the proxy and CA bundle settings of the outbound connections.
+type ProxyConfig struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+	// CABundle is a PEM file of CA certificates trusted by the collector, e.g. the one of the proxy.
+	CABundle string
+}
+
+// LogFileConfig holds the log file settings, unset values keep the logger defaults.
+type LogFileConfig struct {
+	Path string
+	// MaxSize is the size in megabytes at which the file is rotated.
+	MaxSize *int
+	// MaxBackups is the number of rotated files kept.
+	MaxBackups *int
+	// MaxAge is the number of days rotated files are kept.
+	MaxAge   *int
+	Compress *bool
+	// Encoding of the log file, json or console.
+	Encoding string
+	// RotateAt additionally rotates the file every day at the given local time, e.g. 00:00.
+	RotateAt string
+}
+
+// CloudWatchLogsConfig holds the settings shipping the collector logs to CloudWatch Logs, which is
+// enabled by setting the log group.
+type CloudWatchLogsConfig struct {
+	LogGroup  string
+	LogStream string
+	Region    string
+	Endpoint  string
+	// FlushInterval is the maximum time log entries are buffered before being sent.
+	FlushInterval time.Duration
+	// MaxBufferSize caps the bytes of the buffered log entries.
+	MaxBufferSize int
+}
+
+// ParseError is the error of a single line of the file.
+type ParseError struct {
+	Line int
+	Key  string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: invalid value for %q: %v", e.Line, e.Key, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// roleSessionNameRegexp matches the names STS accepts for the role sessions.
+var roleSessionNameRegexp = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+
+// keyParsers sets the typed settings, every other key ends up in ExtraConfig.Env.
+var keyParsers = map[string]func(cfg *ExtraConfig, value string) error{
+	"loggingLevel": func(cfg *ExtraConfig, value string) error {
+		if _, err := zapcore.ParseLevel(strings.ToLower(value)); err != nil {
+			return err
+		}
+		cfg.LoggingLevel = value
+		return nil
+	},
+	"componentLogLevels": func(cfg *ExtraConfig, value string) error {
+		levels := map[string]string{}
+		for _, pair := range strings.Split(value, ",") {
+			component, level, found := strings.Cut(strings.TrimSpace(pair), ":")
+			component, level = strings.TrimSpace(component), strings.TrimSpace(level)
+			if !found || component == "" {
+				return fmt.Errorf("expected component:level, got %q", pair)
+			}
+			if _, err := zapcore.ParseLevel(strings.ToLower(level)); err != nil {
+				return err
+			}
+			levels[component] = level
+		}
+		cfg.ComponentLogLevels = levels
+		return nil
+	},
+	"awsProfile": func(cfg *ExtraConfig, value string) error {
+		cfg.AwsProfile = value
+		return nil
+	},
+	"awsCredentialFile": func(cfg *ExtraConfig, value string) error {
+		cfg.AwsCredentialFile = value
+		return nil
+	},
+	"awsRegion": func(cfg *ExtraConfig, value string) error {
+		cfg.AwsRegion = value
+		return nil
+	},
+	"roleArn": func(cfg *ExtraConfig, value string) error {
+		parsed, err := arn.Parse(value)
+		if err != nil {
+			return err
+		}
+		if parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+			return fmt.Errorf("%q is not an IAM role ARN", value)
+		}
+		cfg.RoleArn = value
+		return nil
+	},
+	"externalId": func(cfg *ExtraConfig, value string) error {
+		cfg.ExternalID = value
+		return nil
+	},
+	"roleSessionName": func(cfg *ExtraConfig, value string) error {
+		if !roleSessionNameRegexp.MatchString(value) {
+			return fmt.Errorf("must be 2 to 64 characters among letters, digits and +=,.@_-")
+		}
+		cfg.RoleSessionName = value
+		return nil
+	},
+	"webIdentityTokenFile": func(cfg *ExtraConfig, value string) error {
+		cfg.WebIdentityTokenFile = value
+		return nil
+	},
+	"credentialDuration": func(cfg *ExtraConfig, value string) error {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if duration < 15*time.Minute || duration > 12*time.Hour {
+			return fmt.Errorf("must be between 15m and 12h")
+		}
+		cfg.CredentialDuration = duration
+		return nil
+	},
+	"stsRegionalEndpoints": func(cfg *ExtraConfig, value string) error {
+		if value != "regional" && value != "legacy" {
+			return fmt.Errorf("must be regional or legacy")
+		}
+		cfg.STSRegionalEndpoints = value
+		return nil
+	},
+	"httpProxy": func(cfg *ExtraConfig, value string) error {
+		if err := outbound.ValidateProxyURL(value); err != nil {
+			return err
+		}
+		cfg.Proxy.HTTPProxy = value
+		return nil
+	},
+	"httpsProxy": func(cfg *ExtraConfig, value string) error {
+		if err := outbound.ValidateProxyURL(value); err != nil {
+			return err
+		}
+		cfg.Proxy.HTTPSProxy = value
+		return nil
+	},
+	"noProxy": func(cfg *ExtraConfig, value string) error {
+		cfg.Proxy.NoProxy = value
+		return nil
+	},
+	"caBundle": func(cfg *ExtraConfig, value string) error {
+		cfg.Proxy.CABundle = value
+		return nil
+	},
+	"logFile": func(cfg *ExtraConfig, value string) error {
+		cfg.LogFile.Path = value
+		return nil
+	},
+	"logMaxSize": func(cfg *ExtraConfig, value string) (err error) {
+		cfg.LogFile.MaxSize, err = parseNonNegativeInt(value)
+		return err
+	},
+	"logMaxBackups": func(cfg *ExtraConfig, value string) (err error) {
+		cfg.LogFile.MaxBackups, err = parseNonNegativeInt(value)
+		return err
+	},
+	"logMaxAge": func(cfg *ExtraConfig, value string) (err error) {
+		cfg.LogFile.MaxAge, err = parseNonNegativeInt(value)
+		return err
+	},
+	"logCompress": func(cfg *ExtraConfig, value string) error {
+		compress, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		cfg.LogFile.Compress = &compress
+		return nil
+	},
+	"logEncoding": func(cfg *ExtraConfig, value string) error {
+		if value != "json" && value != "console" {
+			return fmt.Errorf("must be json or console")
+		}
+		cfg.LogFile.Encoding = value
+		return nil
+	},
+	"logRotateAt": func(cfg *ExtraConfig, value string) error {
+		if _, err := time.Parse("15:04", value); err != nil {
+			return fmt.Errorf("must be a time of the day formatted as HH:MM")
+		}
+		cfg.LogFile.RotateAt = value
+		return nil
+	},
+	"cloudWatchLogGroup": func(cfg *ExtraConfig, value string) error {
+		cfg.CloudWatchLogs.LogGroup = value
+		return nil
+	},
+	"cloudWatchLogStream": func(cfg *ExtraConfig, value string) error {
+		cfg.CloudWatchLogs.LogStream = value
+		return nil
+	},
+	"cloudWatchRegion": func(cfg *ExtraConfig, value string) error {
+		cfg.CloudWatchLogs.Region = value
+		return nil
+	},
+	"cloudWatchEndpoint": func(cfg *ExtraConfig, value string) error {
+		if _, err := url.ParseRequestURI(value); err != nil {
+			return err
+		}
+		cfg.CloudWatchLogs.Endpoint = value
+		return nil
+	},
+	"cloudWatchFlushInterval": func(cfg *ExtraConfig, value string) error {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if interval <= 0 {
+			return fmt.Errorf("must be positive")
+		}
+		cfg.CloudWatchLogs.FlushInterval = interval
+		return nil
+	},
+	"cloudWatchMaxBufferSize": func(cfg *ExtraConfig, value string) error {
+		size, err := parseNonNegativeInt(value)
+		if err != nil {
+			return err
+		}
+		cfg.CloudWatchLogs.MaxBufferSize = *size
+		return nil
+	},
+	"featureGates": func(cfg *ExtraConfig, value string) error {
+		var gates []string
+		for _, gate := range strings.Split(value, ",") {
+			gate = strings.TrimSpace(gate)
+			if id := strings.TrimLeft(gate, "+-"); id == "" {
+				return fmt.Errorf("empty feature gate in %q", value)
+			}
+			gates = append(gates, gate)
+		}
+		cfg.FeatureGates = gates
+		return nil
+	},
+	"adminEndpoint": func(cfg *ExtraConfig, value string) error {
+		if err := ValidateAdminEndpoint(value); err != nil {
+			return err
+		}
+		cfg.AdminEndpoint = value
+		return nil
+	},
+	"reloadOnSIGHUP": func(cfg *ExtraConfig, value string) (err error) {
+		cfg.ReloadOnSIGHUP, err = strconv.ParseBool(value)
+		return err
+	},
+	"strictEnv": func(cfg *ExtraConfig, value string) (err error) {
+		cfg.StrictEnv, err = strconv.ParseBool(value)
+		return err
+	},
 }
 
-// GetExtraConfig returns the extra configs.
+// GetExtraConfig returns the extra configs. The keys which are not part of the schema are exported
+// as environment variables. When some lines are invalid, the settings of the valid lines are
+// returned along with the error.
 func GetExtraConfig() (*ExtraConfig, error) {
 	// read .env from os
-	file, err := os.Open(getConfigFilePath())
+	file, err := os.Open(FilePath())
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
+	extraConfig, err := Parse(file)
+	if extraConfig == nil {
+		return nil, err
+	}
+	for key, val := range extraConfig.Env {
+		if setErr := os.Setenv(key, val); setErr != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to set env var %s: %w", key, setErr))
+		}
+	}
+	return extraConfig, err
+}
+
+// Parse reads the settings from r. Invalid lines are reported as a ParseError and skipped.
+func Parse(r io.Reader) (*ExtraConfig, error) {
+	extraConfig := &ExtraConfig{Env: map[string]string{}}
+	seen := map[string]int{}
+	var errs error
+
 	// read its content line by line and handle it as keyvalue pairs
-	extraConfigMap := map[string]string{}
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		equal := strings.Index(line, "=")
+		if equal < 0 {
+			errs = multierr.Append(errs, &ParseError{Line: lineNum, Err: fmt.Errorf("expected key=value, got %q", line)})
 			continue
 		}
-		if equal := strings.Index(line, "="); equal >= 0 {
-			if key := strings.TrimSpace(line[:equal]); len(key) > 0 {
-				value := ""
-				if len(line) > equal {
-					value = strings.TrimSpace(line[equal+1:])
-				}
-				extraConfigMap[key] = value
+		key, value := strings.TrimSpace(line[:equal]), strings.TrimSpace(line[equal+1:])
+		if key == "" {
+			errs = multierr.Append(errs, &ParseError{Line: lineNum, Err: fmt.Errorf("missing key before '='")})
+			continue
+		}
+		if previous, ok := seen[key]; ok {
+			extraConfig.Warnings = append(extraConfig.Warnings,
+				fmt.Sprintf("line %d: %q is already set on line %d, the last value is used", lineNum, key, previous))
+		}
+		seen[key] = lineNum
 
-			}
+		parse, ok := keyParsers[key]
+		if !ok {
+			extraConfig.Warnings = append(extraConfig.Warnings,
+				fmt.Sprintf("line %d: unknown key %q, it is exported as an environment variable", lineNum, key))
+			extraConfig.Env[key] = value
+			continue
+		}
+		if err := parse(extraConfig, value); err != nil {
+			errs = multierr.Append(errs, &ParseError{Line: lineNum, Key: key, Err: err})
 		}
 	}
 
@@ -66,27 +412,39 @@ func GetExtraConfig() (*ExtraConfig, error) {
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	return extraConfig, errs
+}
 
-	// build extraconfig object
-	extraConfig := ExtraConfig{}
-	for key, val := range extraConfigMap {
-		switch key {
-		case "loggingLevel":
-			extraConfig.LoggingLevel = val
-		case "awsProfile":
-			extraConfig.AwsProfile = val
-		case "awsCredentialFile":
-			extraConfig.AwsCredentialFile = val
-		default:
-			os.Setenv(key, val)
-		}
+func parseNonNegativeInt(value string) (*int, error) {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
 	}
+	if i < 0 {
+		return nil, fmt.Errorf("must not be negative")
+	}
+	return &i, nil
+}
 
-	return &extraConfig, nil
+// ValidateAdminEndpoint returns an error when the endpoint of the admin server is not on a loopback
+// address, e.g. localhost:13134. The server has no authentication, so it must not be reachable from
+// other hosts.
+func ValidateAdminEndpoint(endpoint string) error {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(host, "localhost") {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("%q is not a loopback address, the admin server has no authentication", host)
 }
 
-// getConfigFilePath return the path base on os
-func getConfigFilePath() string {
+// FilePath returns the path of the extracfg file, which depends on the os.
+func FilePath() string {
 	if runtime.GOOS == "windows" {
 		return WindowsExtraConfigPath
 	}
@@ -16,11 +16,15 @@
 package extraconfig
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
 )
 
 func TestExtraConfigBasic(t *testing.T) {
@@ -109,8 +113,138 @@ func TestExtraConfigBasic(t *testing.T) {
 	}
 }
 
+func TestExtraConfigTyped(t *testing.T) {
+	setFilePathHelper("extraconfigtyped.txt")
+	extraConfig, err := GetExtraConfig()
+	assert.NoError(t, err)
+
+	maxSize, maxBackups, compress := 50, 0, false
+	assert.Equal(t, &ExtraConfig{
+		AwsRegion:            "us-west-2",
+		RoleArn:              "arn:aws:iam::123456789012:role/adot-collector",
+		ExternalID:           "adot",
+		RoleSessionName:      "adot-collector",
+		CredentialDuration:   time.Hour,
+		STSRegionalEndpoints: "regional",
+		Proxy: ProxyConfig{
+			HTTPProxy:  "http://proxy.example.com:3128",
+			HTTPSProxy: "http://proxy.example.com:3128",
+			NoProxy:    "169.254.169.254,localhost",
+			CABundle:   "/etc/pki/proxy-ca.pem",
+		},
+		LogFile: LogFileConfig{
+			Path:       "/var/log/aws-otel-collector.log",
+			MaxSize:    &maxSize,
+			MaxBackups: &maxBackups,
+			Compress:   &compress,
+			Encoding:   "console",
+			RotateAt:   "00:00",
+		},
+		CloudWatchLogs: CloudWatchLogsConfig{
+			LogGroup:      "/aws/otel/collector",
+			FlushInterval: 10 * time.Second,
+			MaxBufferSize: 1048576,
+		},
+		ComponentLogLevels: map[string]string{"awsemf": "debug", "otlp/app": "WARN"},
+		AdminEndpoint:      "localhost:13134",
+		FeatureGates:       []string{"+exporter.awsemf.a", "-receiver.b"},
+		ReloadOnSIGHUP:     true,
+		StrictEnv:          true,
+		Env:                map[string]string{},
+	}, extraConfig)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          string
+		expectedErrors   []string
+		expectedWarnings []string
+		checkFunc        func(t *testing.T, config *ExtraConfig)
+	}{
+		{
+			name:    "backward compatible",
+			content: "loggingLevel=DEBUG\n  # comment\n\nAny_Var = any=val\n",
+			expectedWarnings: []string{
+				`line 4: unknown key "Any_Var", it is exported as an environment variable`,
+			},
+			checkFunc: func(t *testing.T, config *ExtraConfig) {
+				assert.Equal(t, "DEBUG", config.LoggingLevel)
+				assert.Equal(t, map[string]string{"Any_Var": "any=val"}, config.Env)
+			},
+		},
+		{
+			name:    "invalid lines",
+			content: "awsRegion=us-east-1\nnot a setting\n=value\nlogMaxAge=-1\nlogCompress=maybe\nloggingLevel=verbose\nroleArn=arn:aws:s3:::bucket\nhttpProxy=proxy:3128\nfeatureGates=a,,b\nlogEncoding=text\nlogRotateAt=midnight\ncomponentLogLevels=awsemf\nadminEndpoint=0.0.0.0:13134\nroleSessionName=adot collector\ncredentialDuration=5m\nstsRegionalEndpoints=global\nhttpsProxy=ftp://proxy:21",
+			expectedErrors: []string{
+				`line 2: expected key=value, got "not a setting"`,
+				`line 3: missing key before '='`,
+				`line 4: invalid value for "logMaxAge": must not be negative`,
+				`line 5: invalid value for "logCompress"`,
+				`line 6: invalid value for "loggingLevel"`,
+				`line 7: invalid value for "roleArn"`,
+				`line 8: invalid value for "httpProxy"`,
+				`line 9: invalid value for "featureGates"`,
+				`line 10: invalid value for "logEncoding": must be json or console`,
+				`line 11: invalid value for "logRotateAt": must be a time of the day formatted as HH:MM`,
+				`line 12: invalid value for "componentLogLevels": expected component:level, got "awsemf"`,
+				`line 13: invalid value for "adminEndpoint": "0.0.0.0" is not a loopback address`,
+				`line 14: invalid value for "roleSessionName"`,
+				`line 15: invalid value for "credentialDuration": must be between 15m and 12h`,
+				`line 16: invalid value for "stsRegionalEndpoints": must be regional or legacy`,
+				`line 17: invalid value for "httpsProxy": unsupported scheme "ftp"`,
+			},
+			checkFunc: func(t *testing.T, config *ExtraConfig) {
+				// the valid lines are kept
+				assert.Equal(t, "us-east-1", config.AwsRegion)
+				assert.Nil(t, config.LogFile.MaxAge)
+				assert.Empty(t, config.LoggingLevel)
+			},
+		},
+		{
+			name:    "duplicate key",
+			content: "awsProfile=a\nawsProfile=b",
+			expectedWarnings: []string{
+				`line 2: "awsProfile" is already set on line 1, the last value is used`,
+			},
+			checkFunc: func(t *testing.T, config *ExtraConfig) {
+				assert.Equal(t, "b", config.AwsProfile)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := Parse(strings.NewReader(test.content))
+			if len(test.expectedErrors) == 0 {
+				assert.NoError(t, err)
+			} else {
+				errs := multierr.Errors(err)
+				if assert.Len(t, errs, len(test.expectedErrors)) {
+					for i, expected := range test.expectedErrors {
+						assert.Contains(t, errs[i].Error(), expected)
+						var parseErr *ParseError
+						assert.True(t, errors.As(errs[i], &parseErr))
+					}
+				}
+			}
+			assert.Equal(t, test.expectedWarnings, config.Warnings)
+			test.checkFunc(t, config)
+		})
+	}
+}
+
 func setFilePathHelper(filename string) {
 	fp := filepath.Join(".", "testdata", filename)
 	UnixExtraConfigPath = fp
 	WindowsExtraConfigPath = fp
 }
+
+func TestValidateAdminEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"localhost:13134", "LOCALHOST:13134", "127.0.0.1:13134", "127.0.0.2:13134", "[::1]:13134"} {
+		assert.NoError(t, ValidateAdminEndpoint(endpoint), endpoint)
+	}
+	for _, endpoint := range []string{"0.0.0.0:13134", ":13134", "[::]:13134", "10.0.0.1:13134", "example.com:13134", "13134"} {
+		assert.Error(t, ValidateAdminEndpoint(endpoint), endpoint)
+	}
+}
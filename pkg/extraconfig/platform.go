@@ -0,0 +1,256 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package extraconfig
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Platform is the platform the collector runs on.
+type Platform string
+
+const (
+	// PlatformEC2 is an EC2 instance, without a container
+	PlatformEC2 Platform = "ec2"
+	// PlatformECS is an ECS task on an EC2 container instance
+	PlatformECS Platform = "ecs"
+	// PlatformECSFargate is an ECS task on Fargate
+	PlatformECSFargate Platform = "ecs-fargate"
+	// PlatformEKS is a Kubernetes pod, EKS or not
+	PlatformEKS       Platform = "eks"
+	PlatformAppRunner Platform = "apprunner"
+	PlatformLambda    Platform = "lambda"
+	// PlatformContainer is a container outside of the orchestrators above, e.g. Docker on a host
+	PlatformContainer Platform = "container"
+	// PlatformHost is a host which is not an EC2 instance, e.g. on premises
+	PlatformHost Platform = "host"
+
+	// EnvKeyPlatform overrides the detected platform
+	EnvKeyPlatform = "AOT_PLATFORM"
+
+	envKeyECSMetadataURIV4  = "ECS_CONTAINER_METADATA_URI_V4"
+	envKeyECSMetadataURI    = "ECS_CONTAINER_METADATA_URI"
+	envKeyExecutionEnv      = "AWS_EXECUTION_ENV"
+	envKeyLambdaFunction    = "AWS_LAMBDA_FUNCTION_NAME"
+	envKeyKubernetesService = "KUBERNETES_SERVICE_HOST"
+
+	executionEnvFargate = "AWS_ECS_FARGATE"
+	executionEnvLambda  = "AWS_Lambda_"
+
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+	RuntimePodman     = "podman"
+)
+
+var platforms = []Platform{
+	PlatformEC2, PlatformECS, PlatformECSFargate, PlatformEKS, PlatformAppRunner, PlatformLambda, PlatformContainer, PlatformHost,
+}
+
+// The files the detection looks at, relative to the root of the file system.
+const (
+	dockerEnvFile      = ".dockerenv"
+	podmanEnvFile      = "run/.containerenv"
+	cgroupFile         = "proc/self/cgroup"
+	mountInfoFile      = "proc/self/mountinfo"
+	serviceAccountDir  = "var/run/secrets/kubernetes.io/serviceaccount"
+	boardVendorFile    = "sys/devices/virtual/dmi/id/board_vendor"
+	hypervisorUUIDFile = "sys/hypervisor/uuid"
+)
+
+// PlatformInfo describes where the collector runs.
+type PlatformInfo struct {
+	Platform Platform
+	// Container is whether the collector runs in a container, EnvKeyRunInContainer overrides it
+	Container bool
+	// Runtime is the container runtime, e.g. docker or containerd, empty when unknown
+	Runtime string
+	// Evidence lists what the detection is based on, e.g. env:ECS_CONTAINER_METADATA_URI_V4
+	Evidence []string
+}
+
+func (p PlatformInfo) String() string {
+	s := string(p.Platform)
+	if p.Container {
+		s += ", in a container"
+		if p.Runtime != "" {
+			s += " (" + p.Runtime + ")"
+		}
+	}
+	if len(p.Evidence) > 0 {
+		s += ", based on " + strings.Join(p.Evidence, ", ")
+	}
+	return s
+}
+
+// DetectPlatform returns the platform the collector runs on, see Detect.
+func DetectPlatform() Platform {
+	return Detect().Platform
+}
+
+// Detect detects the platform from the environment variables set by ECS, Lambda and Kubernetes,
+// the Kubernetes service account mount, the container marker files, the cgroups of the process and
+// the DMI data of EC2 instances. EnvKeyPlatform (i.e. AOT_PLATFORM) overrides the platform and
+// EnvKeyRunInContainer (i.e. RUN_IN_CONTAINER) whether it runs in a container.
+//
+// App Runner sets nothing which tells it apart from other containers, it is only used when set
+// with EnvKeyPlatform.
+func Detect() PlatformInfo {
+	return detector{lookupEnv: os.LookupEnv, root: "/"}.detect()
+}
+
+type detector struct {
+	lookupEnv func(string) (string, bool)
+	// root is the root of the file system, it is only changed by tests
+	root string
+}
+
+func (d detector) detect() PlatformInfo {
+	var info PlatformInfo
+	info.Runtime, info.Evidence = d.containerRuntime()
+	info.Container = info.Runtime != "" || len(info.Evidence) > 0
+
+	switch {
+	case strings.HasPrefix(d.getenv(envKeyExecutionEnv), executionEnvLambda):
+		info.Platform = PlatformLambda
+		info.Evidence = append(info.Evidence, "env:"+envKeyExecutionEnv)
+	case d.getenv(envKeyLambdaFunction) != "":
+		info.Platform = PlatformLambda
+		info.Evidence = append(info.Evidence, "env:"+envKeyLambdaFunction)
+	case d.firstSet(envKeyECSMetadataURIV4, envKeyECSMetadataURI) != "":
+		info.Platform = PlatformECS
+		info.Evidence = append(info.Evidence, "env:"+d.firstSet(envKeyECSMetadataURIV4, envKeyECSMetadataURI))
+		if d.getenv(envKeyExecutionEnv) == executionEnvFargate {
+			info.Platform = PlatformECSFargate
+			info.Evidence = append(info.Evidence, "env:"+envKeyExecutionEnv)
+		}
+	case d.getenv(envKeyKubernetesService) != "":
+		info.Platform = PlatformEKS
+		info.Evidence = append(info.Evidence, "env:"+envKeyKubernetesService)
+	case d.exists(serviceAccountDir):
+		info.Platform = PlatformEKS
+		info.Evidence = append(info.Evidence, "file:/"+serviceAccountDir)
+	case info.Container:
+		info.Platform = PlatformContainer
+	case d.isEC2():
+		info.Platform = PlatformEC2
+		info.Evidence = append(info.Evidence, "dmi")
+	default:
+		info.Platform = PlatformHost
+	}
+	if info.Platform != PlatformEC2 && info.Platform != PlatformHost {
+		// the orchestrators always run the collector in a container, even without a trace of it
+		info.Container = true
+	}
+
+	if value, ok := d.lookupEnv(EnvKeyPlatform); ok && value != "" {
+		platform := Platform(strings.ToLower(strings.TrimSpace(value)))
+		if isPlatform(platform) {
+			info.Platform = platform
+			info.Evidence = append(info.Evidence, "env:"+EnvKeyPlatform)
+		} else {
+			log.Printf("W! ignoring invalid %s %q, must be one of %v\n", EnvKeyPlatform, value, platforms)
+		}
+	}
+	if value, ok := d.lookupEnv(EnvKeyRunInContainer); ok && value != "" {
+		// exact match, as before the detection, any other value means it does not run in a container
+		info.Container = value == EnvValTrue
+		info.Evidence = append(info.Evidence, "env:"+EnvKeyRunInContainer)
+	}
+	return info
+}
+
+// containerRuntime looks for the marker files of the runtimes and for the container ids in the
+// cgroups of the process. With cgroup v2, the cgroups are hidden from the container, the runtimes
+// are then told apart by the /etc/hostname file they mount into the container.
+func (d detector) containerRuntime() (string, []string) {
+	if d.exists(dockerEnvFile) {
+		return RuntimeDocker, []string{"file:/" + dockerEnvFile}
+	}
+	if d.exists(podmanEnvFile) {
+		return RuntimePodman, []string{"file:/" + podmanEnvFile}
+	}
+	if content, err := os.ReadFile(filepath.Join(d.root, cgroupFile)); err == nil {
+		if runtime, ok := runtimeFromPath(string(content)); ok {
+			return runtime, []string{"file:/" + cgroupFile}
+		}
+	}
+	if content, err := os.ReadFile(filepath.Join(d.root, mountInfoFile)); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			// the 4th field is the root of the mount within its file system, the 5th the mount point
+			fields := strings.Fields(line)
+			if len(fields) >= 5 && fields[4] == "/etc/hostname" {
+				runtime, _ := runtimeFromPath(fields[3])
+				return runtime, []string{"file:/" + mountInfoFile}
+			}
+		}
+	}
+	return "", nil
+}
+
+// runtimeFromPath returns the runtime named in a cgroup or mount path, ok is false when the path
+// is not one of a container.
+func runtimeFromPath(path string) (runtime string, ok bool) {
+	switch {
+	case strings.Contains(path, "containerd"):
+		return RuntimeContainerd, true
+	case strings.Contains(path, "/docker/") || strings.Contains(path, "/docker-"):
+		return RuntimeDocker, true
+	case strings.Contains(path, "/kubepods") || strings.Contains(path, "/ecs/"):
+		return "", true
+	}
+	return "", false
+}
+
+func (d detector) isEC2() bool {
+	if vendor, err := os.ReadFile(filepath.Join(d.root, boardVendorFile)); err == nil && strings.TrimSpace(string(vendor)) == "Amazon EC2" {
+		return true
+	}
+	// older Xen based instances
+	uuid, err := os.ReadFile(filepath.Join(d.root, hypervisorUUIDFile))
+	return err == nil && strings.HasPrefix(strings.ToLower(string(uuid)), "ec2")
+}
+
+func (d detector) getenv(key string) string {
+	value, _ := d.lookupEnv(key)
+	return value
+}
+
+// firstSet returns the first of the variables which is set.
+func (d detector) firstSet(keys ...string) string {
+	for _, key := range keys {
+		if d.getenv(key) != "" {
+			return key
+		}
+	}
+	return ""
+}
+
+func (d detector) exists(file string) bool {
+	_, err := os.Stat(filepath.Join(d.root, file))
+	return err == nil
+}
+
+func isPlatform(platform Platform) bool {
+	for _, p := range platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
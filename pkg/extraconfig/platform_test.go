@@ -0,0 +1,218 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package extraconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		name     string
+		env      map[string]string
+		files    map[string]string
+		expected PlatformInfo
+	}{
+		{
+			name:     "Host",
+			expected: PlatformInfo{Platform: PlatformHost},
+		},
+		{
+			name:  "EC2",
+			files: map[string]string{boardVendorFile: "Amazon EC2\n", cgroupFile: "0::/system.slice/aws-otel-collector.service\n"},
+			expected: PlatformInfo{
+				Platform: PlatformEC2,
+				Evidence: []string{"dmi"},
+			},
+		},
+		{
+			name:  "XenEC2",
+			files: map[string]string{hypervisorUUIDFile: "ec2e1916-9099-7caf-fd21-012345abcdef\n"},
+			expected: PlatformInfo{
+				Platform: PlatformEC2,
+				Evidence: []string{"dmi"},
+			},
+		},
+		{
+			name:  "DockerOnEC2",
+			files: map[string]string{dockerEnvFile: "", boardVendorFile: "Amazon EC2\n"},
+			expected: PlatformInfo{
+				Platform:  PlatformContainer,
+				Container: true,
+				Runtime:   RuntimeDocker,
+				Evidence:  []string{"file:/.dockerenv"},
+			},
+		},
+		{
+			name: "DockerCgroupV1",
+			files: map[string]string{
+				cgroupFile: "12:memory:/docker/0123456789abcdef\n11:cpu,cpuacct:/docker/0123456789abcdef\n",
+			},
+			expected: PlatformInfo{
+				Platform:  PlatformContainer,
+				Container: true,
+				Runtime:   RuntimeDocker,
+				Evidence:  []string{"file:/proc/self/cgroup"},
+			},
+		},
+		{
+			name: "ContainerdCgroupV2",
+			files: map[string]string{
+				cgroupFile:    "0::/\n",
+				mountInfoFile: "1 0 0:1 / / rw - overlay overlay rw\n2 1 259:1 /var/lib/containerd/io.containerd.grpc.v1.cri/sandboxes/abc/hostname /etc/hostname rw - ext4 /dev/root rw\n",
+			},
+			expected: PlatformInfo{
+				Platform:  PlatformContainer,
+				Container: true,
+				Runtime:   RuntimeContainerd,
+				Evidence:  []string{"file:/proc/self/mountinfo"},
+			},
+		},
+		{
+			name: "DockerHostMountsAreIgnored",
+			files: map[string]string{
+				cgroupFile:    "0::/system.slice/aws-otel-collector.service\n",
+				mountInfoFile: "1 0 0:1 / / rw - ext4 /dev/root rw\n2 1 0:2 / /var/lib/docker/overlay2/abc/merged rw - overlay overlay rw\n",
+			},
+			expected: PlatformInfo{Platform: PlatformHost},
+		},
+		{
+			name:  "ECSOnEC2",
+			env:   map[string]string{envKeyECSMetadataURIV4: "http://169.254.170.2/v4/abc", envKeyExecutionEnv: "AWS_ECS_EC2"},
+			files: map[string]string{cgroupFile: "9:memory:/ecs/task/0123456789abcdef\n"},
+			expected: PlatformInfo{
+				Platform:  PlatformECS,
+				Container: true,
+				Evidence:  []string{"file:/proc/self/cgroup", "env:ECS_CONTAINER_METADATA_URI_V4"},
+			},
+		},
+		{
+			name: "ECSFargate",
+			env:  map[string]string{envKeyECSMetadataURI: "http://169.254.170.2/v3/abc", envKeyExecutionEnv: executionEnvFargate},
+			expected: PlatformInfo{
+				Platform:  PlatformECSFargate,
+				Container: true,
+				Evidence:  []string{"env:ECS_CONTAINER_METADATA_URI", "env:AWS_EXECUTION_ENV"},
+			},
+		},
+		{
+			name:  "EKS",
+			files: map[string]string{serviceAccountDir + "/token": "token"},
+			expected: PlatformInfo{
+				Platform:  PlatformEKS,
+				Container: true,
+				Evidence:  []string{"file:/var/run/secrets/kubernetes.io/serviceaccount"},
+			},
+		},
+		{
+			name: "EKSFromEnv",
+			env:  map[string]string{envKeyKubernetesService: "10.100.0.1"},
+			expected: PlatformInfo{
+				Platform:  PlatformEKS,
+				Container: true,
+				Evidence:  []string{"env:KUBERNETES_SERVICE_HOST"},
+			},
+		},
+		{
+			name: "Lambda",
+			env:  map[string]string{envKeyExecutionEnv: "AWS_Lambda_python3.12"},
+			expected: PlatformInfo{
+				Platform:  PlatformLambda,
+				Container: true,
+				Evidence:  []string{"env:AWS_EXECUTION_ENV"},
+			},
+		},
+		{
+			name: "PlatformOverride",
+			env:  map[string]string{EnvKeyPlatform: "AppRunner"},
+			expected: PlatformInfo{
+				Platform: PlatformAppRunner,
+				Evidence: []string{"env:AOT_PLATFORM"},
+			},
+		},
+		{
+			name: "InvalidPlatformOverride",
+			env:  map[string]string{EnvKeyPlatform: "beanstalk", envKeyKubernetesService: "10.100.0.1"},
+			expected: PlatformInfo{
+				Platform:  PlatformEKS,
+				Container: true,
+				Evidence:  []string{"env:KUBERNETES_SERVICE_HOST"},
+			},
+		},
+		{
+			name: "RunInContainer",
+			env:  map[string]string{EnvKeyRunInContainer: EnvValTrue},
+			expected: PlatformInfo{
+				Platform:  PlatformHost,
+				Container: true,
+				Evidence:  []string{"env:RUN_IN_CONTAINER"},
+			},
+		},
+		{
+			name:  "NotRunInContainer",
+			env:   map[string]string{EnvKeyRunInContainer: "False"},
+			files: map[string]string{dockerEnvFile: ""},
+			expected: PlatformInfo{
+				Platform: PlatformContainer,
+				Runtime:  RuntimeDocker,
+				Evidence: []string{"file:/.dockerenv", "env:RUN_IN_CONTAINER"},
+			},
+		},
+		{
+			name:  "RunInContainerIsCaseSensitive",
+			env:   map[string]string{EnvKeyRunInContainer: "true"},
+			files: map[string]string{dockerEnvFile: ""},
+			expected: PlatformInfo{
+				Platform: PlatformContainer,
+				Runtime:  RuntimeDocker,
+				Evidence: []string{"file:/.dockerenv", "env:RUN_IN_CONTAINER"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tc.files {
+				path := filepath.Join(root, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+			}
+			d := detector{
+				lookupEnv: func(key string) (string, bool) {
+					value, ok := tc.env[key]
+					return value, ok
+				},
+				root: root,
+			}
+			assert.Equal(t, tc.expected, d.detect())
+		})
+	}
+}
+
+func TestPlatformInfoString(t *testing.T) {
+	info := PlatformInfo{
+		Platform:  PlatformECSFargate,
+		Container: true,
+		Runtime:   RuntimeContainerd,
+		Evidence:  []string{"env:ECS_CONTAINER_METADATA_URI_V4", "env:AWS_EXECUTION_ENV"},
+	}
+	assert.Equal(t, "ecs-fargate, in a container (containerd), based on env:ECS_CONTAINER_METADATA_URI_V4, env:AWS_EXECUTION_ENV", info.String())
+}
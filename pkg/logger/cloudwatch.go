@@ -0,0 +1,389 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/awssession"
+)
+
+// The limits of the PutLogEvents API.
+const (
+	maxEventsPerBatch = 10000
+	maxBatchBytes     = 1048576
+	// eventOverhead is added to the size of every message by CloudWatch Logs
+	eventOverhead   = 26
+	maxMessageBytes = 262144 - eventOverhead
+	// maxBatchSpan is the longest time span of the events of a single batch
+	maxBatchSpan = 24 * time.Hour
+)
+
+// CloudWatchSettings configures the shipping of the collector logs to CloudWatch Logs.
+type CloudWatchSettings struct {
+	LogGroup string
+	// LogStream defaults to the hostname.
+	LogStream string
+	// Region and Endpoint default to the ones of the environment.
+	Region   string
+	Endpoint string
+	// FlushInterval is the maximum time log entries are buffered before being sent.
+	FlushInterval time.Duration
+	// MaxBufferSize caps the bytes of the buffered log entries, the oldest entries are dropped when
+	// CloudWatch Logs cannot keep up or is unavailable.
+	MaxBufferSize int
+	// MaxRetries is the number of times a failed request is retried before the next flush.
+	MaxRetries int
+}
+
+// DefaultCloudWatchSettings returns the settings used for the values left unset.
+func DefaultCloudWatchSettings() CloudWatchSettings {
+	return CloudWatchSettings{
+		FlushInterval: 5 * time.Second,
+		MaxBufferSize: 4 * 1024 * 1024,
+		MaxRetries:    3,
+	}
+}
+
+// cloudWatchWriter buffers log events and sends them in batches to a log stream. A failed batch is
+// kept in the buffer and sent again on the next flush, until the buffer size cap drops it.
+type cloudWatchWriter struct {
+	client   cloudwatchlogsiface.CloudWatchLogsAPI
+	settings CloudWatchSettings
+
+	mu      sync.Mutex
+	events  []*cloudwatchlogs.InputLogEvent
+	size    int
+	dropped int
+
+	// sendMu serializes the requests of the flush loop and of Sync
+	sendMu  sync.Mutex
+	created bool
+
+	flush chan struct{}
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newCloudWatchWriter(settings CloudWatchSettings) (*cloudWatchWriter, error) {
+	if settings.LogGroup == "" {
+		return nil, errors.New("the CloudWatch Logs log group is required")
+	}
+	defaults := DefaultCloudWatchSettings()
+	if settings.LogStream == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the hostname for the log stream name: %w", err)
+		}
+		settings.LogStream = hostname
+	}
+	if settings.FlushInterval <= 0 {
+		settings.FlushInterval = defaults.FlushInterval
+	}
+	if settings.MaxBufferSize <= 0 {
+		settings.MaxBufferSize = defaults.MaxBufferSize
+	}
+	if settings.MaxRetries < 0 {
+		settings.MaxRetries = defaults.MaxRetries
+	}
+
+	sess, err := awssession.New(settings.Region, settings.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	client := cloudwatchlogs.New(sess, aws.NewConfig().WithMaxRetries(settings.MaxRetries))
+	return startCloudWatchWriter(client, settings), nil
+}
+
+func startCloudWatchWriter(client cloudwatchlogsiface.CloudWatchLogsAPI, settings CloudWatchSettings) *cloudWatchWriter {
+	w := &cloudWatchWriter{
+		client:   client,
+		settings: settings,
+		flush:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+func (w *cloudWatchWriter) run() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.settings.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		case <-w.flush:
+		}
+		w.send()
+	}
+}
+
+// write buffers a log event, it never blocks on CloudWatch Logs.
+func (w *cloudWatchWriter) write(timestamp time.Time, message string) {
+	if len(message) > maxMessageBytes {
+		message = truncate(message, maxMessageBytes)
+	}
+	event := &cloudwatchlogs.InputLogEvent{
+		Message:   aws.String(message),
+		Timestamp: aws.Int64(timestamp.UnixMilli()),
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.events = append(w.events, event)
+	w.size += eventSize(event)
+	for w.size > w.settings.MaxBufferSize && len(w.events) > 0 {
+		w.size -= eventSize(w.events[0])
+		w.events[0] = nil
+		w.events = w.events[1:]
+		w.dropped++
+	}
+	if w.size >= maxBatchBytes || len(w.events) >= maxEventsPerBatch {
+		select {
+		case w.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// send sends the buffered events in as many batches as needed, it stops at the first failure.
+func (w *cloudWatchWriter) send() {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	for {
+		batch := w.nextBatch()
+		if len(batch) == 0 {
+			return
+		}
+		err := w.putLogEvents(batch)
+		if err != nil && !isRetryable(err) {
+			// the batch would be rejected again, e.g. an event is too old
+			log.Printf("E! dropping %d log events rejected by CloudWatch Logs: %v\n", len(batch), err)
+			w.remove(batch)
+			continue
+		}
+		if err != nil {
+			log.Printf("W! failed to send log events to CloudWatch Logs, retrying on the next flush: %v\n", err)
+			return
+		}
+		w.remove(batch)
+	}
+}
+
+// nextBatch returns the oldest buffered events which fit in a single request.
+func (w *cloudWatchWriter) nextBatch() []*cloudwatchlogs.InputLogEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.dropped > 0 {
+		log.Printf("W! dropped %d log events, the CloudWatch Logs buffer is full\n", w.dropped)
+		w.dropped = 0
+	}
+	size := 0
+	n := 0
+	for ; n < len(w.events) && n < maxEventsPerBatch; n++ {
+		size += eventSize(w.events[n])
+		if size > maxBatchBytes {
+			break
+		}
+		if time.Duration(aws.Int64Value(w.events[n].Timestamp)-aws.Int64Value(w.events[0].Timestamp))*time.Millisecond > maxBatchSpan {
+			break
+		}
+	}
+	batch := make([]*cloudwatchlogs.InputLogEvent, n)
+	copy(batch, w.events[:n])
+	return batch
+}
+
+// remove removes the sent batch from the buffer, unless it was already dropped to make room.
+func (w *cloudWatchWriter) remove(batch []*cloudwatchlogs.InputLogEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, event := range batch {
+		if len(w.events) == 0 || w.events[0] != event {
+			continue
+		}
+		w.size -= eventSize(event)
+		w.events[0] = nil
+		w.events = w.events[1:]
+	}
+}
+
+func (w *cloudWatchWriter) putLogEvents(batch []*cloudwatchlogs.InputLogEvent) error {
+	if !w.created {
+		if err := w.createLogStream(); err != nil {
+			return err
+		}
+		w.created = true
+	}
+
+	// PutLogEvents requires the events in chronological order
+	events := make([]*cloudwatchlogs.InputLogEvent, len(batch))
+	copy(events, batch)
+	sort.SliceStable(events, func(i, j int) bool {
+		return aws.Int64Value(events[i].Timestamp) < aws.Int64Value(events[j].Timestamp)
+	})
+	input := &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(w.settings.LogGroup),
+		LogStreamName: aws.String(w.settings.LogStream),
+		LogEvents:     events,
+	}
+
+	_, err := w.client.PutLogEvents(input)
+	if hasErrorCode(err, cloudwatchlogs.ErrCodeResourceNotFoundException) {
+		// the group or stream was deleted while the collector was running
+		if err = w.createLogStream(); err != nil {
+			return err
+		}
+		_, err = w.client.PutLogEvents(input)
+	}
+	return err
+}
+
+// createLogStream creates the log group and stream if they do not exist yet.
+func (w *cloudWatchWriter) createLogStream() error {
+	input := &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(w.settings.LogGroup),
+		LogStreamName: aws.String(w.settings.LogStream),
+	}
+	_, err := w.client.CreateLogStream(input)
+	if !hasErrorCode(err, cloudwatchlogs.ErrCodeResourceNotFoundException) {
+		return ignoreAlreadyExists(err)
+	}
+	_, err = w.client.CreateLogGroup(&cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(w.settings.LogGroup),
+	})
+	if err = ignoreAlreadyExists(err); err != nil {
+		return err
+	}
+	_, err = w.client.CreateLogStream(input)
+	return ignoreAlreadyExists(err)
+}
+
+// hasErrorCode reports whether err is an error of the AWS API with the given code.
+func hasErrorCode(err error, code string) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == code
+}
+
+// ignoreAlreadyExists returns err, or nil when the resource it failed to create already exists.
+func ignoreAlreadyExists(err error) error {
+	if hasErrorCode(err, cloudwatchlogs.ErrCodeResourceAlreadyExistsException) {
+		return nil
+	}
+	return err
+}
+
+// sync sends the buffered events.
+func (w *cloudWatchWriter) sync() {
+	w.send()
+}
+
+// close sends the buffered events and stops the flush loop.
+func (w *cloudWatchWriter) close() {
+	close(w.done)
+	w.wg.Wait()
+	w.send()
+}
+
+// isRetryable returns whether sending the same batch again may succeed.
+func isRetryable(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return true
+	}
+	switch awsErr.Code() {
+	case cloudwatchlogs.ErrCodeInvalidParameterException, cloudwatchlogs.ErrCodeDataAlreadyAcceptedException:
+		return false
+	}
+	return true
+}
+
+func eventSize(event *cloudwatchlogs.InputLogEvent) int {
+	return len(aws.StringValue(event.Message)) + eventOverhead
+}
+
+// truncate cuts the message to at most n bytes without splitting a rune.
+func truncate(message string, n int) string {
+	for n > 0 && !utf8.RuneStart(message[n]) {
+		n--
+	}
+	return message[:n]
+}
+
+// cloudWatchCore is a zapcore.Core writing the encoded entries to a cloudWatchWriter.
+type cloudWatchCore struct {
+	zapcore.LevelEnabler
+	encoder zapcore.Encoder
+	writer  *cloudWatchWriter
+}
+
+func newCloudWatchCore(encoder zapcore.Encoder, writer *cloudWatchWriter, enab zapcore.LevelEnabler) zapcore.Core {
+	return &cloudWatchCore{LevelEnabler: enab, encoder: encoder, writer: writer}
+}
+
+func (c *cloudWatchCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &cloudWatchCore{LevelEnabler: c.LevelEnabler, encoder: c.encoder.Clone(), writer: c.writer}
+	for _, field := range fields {
+		field.AddTo(clone.encoder)
+	}
+	return clone
+}
+
+func (c *cloudWatchCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+func (c *cloudWatchCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	// the encoder ends every entry with a line ending, which is not needed for a log event
+	message := buf.String()
+	buf.Free()
+	for len(message) > 0 && (message[len(message)-1] == '\n' || message[len(message)-1] == '\r') {
+		message = message[:len(message)-1]
+	}
+	c.writer.write(entry.Time, message)
+	return nil
+}
+
+func (c *cloudWatchCore) Sync() error {
+	c.writer.sync()
+	return nil
+}
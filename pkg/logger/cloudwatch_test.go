@@ -0,0 +1,265 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeCloudWatchLogs is a local stand-in of the CloudWatch Logs API.
+type fakeCloudWatchLogs struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	groups   map[string]bool
+	streams  map[string]bool
+	messages []string
+	// failures is the list of error codes returned by the next PutLogEvents calls
+	failures []string
+}
+
+func newFakeCloudWatchLogs(t *testing.T) *fakeCloudWatchLogs {
+	fake := &fakeCloudWatchLogs{groups: map[string]bool{}, streams: map[string]bool{}}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.Close)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+	return fake
+}
+
+func (f *fakeCloudWatchLogs) handle(rw http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var body struct {
+		LogGroupName  string
+		LogStreamName string
+		LogEvents     []struct {
+			Message   string
+			Timestamp int64
+		}
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fail := func(status int, code string) {
+		rw.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		rw.WriteHeader(status)
+		fmt.Fprintf(rw, `{"__type":%q,"message":"fake error"}`, code)
+	}
+	stream := body.LogGroupName + "/" + body.LogStreamName
+
+	switch req.Header.Get("X-Amz-Target") {
+	case "Logs_20140328.CreateLogGroup":
+		if f.groups[body.LogGroupName] {
+			fail(http.StatusBadRequest, "ResourceAlreadyExistsException")
+			return
+		}
+		f.groups[body.LogGroupName] = true
+	case "Logs_20140328.CreateLogStream":
+		if !f.groups[body.LogGroupName] {
+			fail(http.StatusBadRequest, "ResourceNotFoundException")
+			return
+		}
+		if f.streams[stream] {
+			fail(http.StatusBadRequest, "ResourceAlreadyExistsException")
+			return
+		}
+		f.streams[stream] = true
+	case "Logs_20140328.PutLogEvents":
+		if len(f.failures) > 0 {
+			code := f.failures[0]
+			f.failures = f.failures[1:]
+			if code == "InternalFailure" {
+				fail(http.StatusInternalServerError, code)
+			} else {
+				fail(http.StatusBadRequest, code)
+			}
+			return
+		}
+		if !f.streams[stream] {
+			fail(http.StatusBadRequest, "ResourceNotFoundException")
+			return
+		}
+		for _, event := range body.LogEvents {
+			f.messages = append(f.messages, event.Message)
+		}
+	default:
+		http.Error(rw, "unexpected target", http.StatusBadRequest)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_, _ = rw.Write([]byte("{}"))
+}
+
+func (f *fakeCloudWatchLogs) received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.messages...)
+}
+
+func (f *fakeCloudWatchLogs) fail(codes ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, codes...)
+}
+
+func newTestCloudWatchWriter(t *testing.T, fake *fakeCloudWatchLogs, maxBufferSize int) *cloudWatchWriter {
+	writer, err := newCloudWatchWriter(CloudWatchSettings{
+		LogGroup:      "/aws/otel/collector",
+		LogStream:     "test",
+		Endpoint:      fake.URL,
+		FlushInterval: time.Hour,
+		MaxBufferSize: maxBufferSize,
+	})
+	require.NoError(t, err)
+	t.Cleanup(writer.close)
+	return writer
+}
+
+func TestCloudWatchCore(t *testing.T) {
+	fake := newFakeCloudWatchLogs(t)
+	setupLogEnv()
+	logfile = ""
+	_ = logWriter.swap(nil)
+	require.NoError(t, SetCloudWatchSettings(CloudWatchSettings{
+		LogGroup:      "/aws/otel/collector",
+		LogStream:     "test",
+		Endpoint:      fake.URL,
+		FlushInterval: 10 * time.Millisecond,
+	}))
+	t.Cleanup(Close)
+
+	observed := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&strings.Builder{}), zapcore.InfoLevel)
+	logger := zap.New(observed, WrapCoreOpt()).With(zap.String("kind", "exporter"))
+	logger.Debug("filtered")
+	logger.Info("first")
+	logger.Warn("second", zap.Int("count", 2))
+
+	assert.Eventually(t, func() bool { return len(fake.received()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	messages := fake.received()
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(messages[0]), &entry))
+	assert.Equal(t, "first", entry["message"])
+	assert.Equal(t, "exporter", entry["kind"])
+	require.NoError(t, json.Unmarshal([]byte(messages[1]), &entry))
+	assert.Equal(t, "second", entry["message"])
+	assert.Equal(t, float64(2), entry["count"])
+	assert.Equal(t, "warn", entry["level"])
+}
+
+func TestCloudWatchWriterRetry(t *testing.T) {
+	fake := newFakeCloudWatchLogs(t)
+	// the test writer does not retry within a flush
+	writer := newTestCloudWatchWriter(t, fake, 0)
+
+	fake.fail("InternalFailure")
+	writer.write(time.Now(), "kept")
+	writer.sync()
+	assert.Empty(t, fake.received())
+
+	// the failed batch is sent again on the next flush
+	writer.sync()
+	assert.Equal(t, []string{"kept"}, fake.received())
+}
+
+func TestCloudWatchWriterDropsRejectedBatch(t *testing.T) {
+	fake := newFakeCloudWatchLogs(t)
+	writer := newTestCloudWatchWriter(t, fake, 0)
+
+	fake.fail("InvalidParameterException")
+	writer.write(time.Now(), "rejected")
+	writer.sync()
+	writer.write(time.Now(), "accepted")
+	writer.sync()
+	assert.Equal(t, []string{"accepted"}, fake.received())
+}
+
+func TestCloudWatchWriterBufferCap(t *testing.T) {
+	fake := newFakeCloudWatchLogs(t)
+	// room for two events of 4 bytes
+	writer := newTestCloudWatchWriter(t, fake, 2*(4+eventOverhead))
+
+	for i := 0; i < 5; i++ {
+		writer.write(time.Now(), fmt.Sprintf("msg%d", i))
+	}
+	writer.sync()
+	assert.Equal(t, []string{"msg3", "msg4"}, fake.received())
+}
+
+func TestCloudWatchWriterBatches(t *testing.T) {
+	fake := newFakeCloudWatchLogs(t)
+	writer := newTestCloudWatchWriter(t, fake, 0)
+
+	now := time.Now()
+	writer.write(now.Add(-25*time.Hour), "old")
+	writer.write(now.Add(time.Second), "later")
+	writer.write(now, "earlier")
+	batch := writer.nextBatch()
+	// events more than 24 hours apart cannot be in the same batch
+	assert.Len(t, batch, 1)
+
+	writer.sync()
+	// each batch is sorted by time
+	assert.Equal(t, []string{"old", "earlier", "later"}, fake.received())
+}
+
+// failingGroupClient fails to create the log group with an error which is not one of the API, e.g.
+// a timeout.
+type failingGroupClient struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+	streamCalls int
+}
+
+func (c *failingGroupClient) CreateLogStream(*cloudwatchlogs.CreateLogStreamInput) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	c.streamCalls++
+	return nil, awserr.New(cloudwatchlogs.ErrCodeResourceNotFoundException, "log group not found", nil)
+}
+
+func (c *failingGroupClient) CreateLogGroup(*cloudwatchlogs.CreateLogGroupInput) (*cloudwatchlogs.CreateLogGroupOutput, error) {
+	return nil, errors.New("dial tcp: i/o timeout")
+}
+
+func TestCreateLogStreamGroupError(t *testing.T) {
+	client := &failingGroupClient{}
+	writer := &cloudWatchWriter{client: client, settings: CloudWatchSettings{LogGroup: "/aws/otel/collector", LogStream: "test"}}
+	assert.EqualError(t, writer.createLogStream(), "dial tcp: i/o timeout")
+	assert.Equal(t, 1, client.streamCalls)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "ab", truncate("abc", 2))
+	assert.Equal(t, "a", truncate("aé", 2))
+}
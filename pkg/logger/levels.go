@@ -0,0 +1,201 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// componentNameKey is the field the collector adds to the logger of every component, holding its ID.
+const componentNameKey = "name"
+
+var (
+	levelsMu sync.Mutex
+	// defaultComponentLevels are the levels of the extracfg file
+	defaultComponentLevels = map[string]zapcore.Level{}
+	// componentLevels are the levels in use, read by every log entry of a component
+	componentLevels atomic.Pointer[map[string]zapcore.Level]
+)
+
+func init() {
+	componentLevels.Store(&map[string]zapcore.Level{})
+}
+
+// ParseComponentLevels parses the levels of components, e.g. map[awsemf:debug otlp/app:warn].
+func ParseComponentLevels(levels map[string]string) (map[string]zapcore.Level, error) {
+	parsed := make(map[string]zapcore.Level, len(levels))
+	for component, level := range levels {
+		if component == "" {
+			return nil, fmt.Errorf("empty component name for level %q", level)
+		}
+		var l zapcore.Level
+		if err := l.UnmarshalText([]byte(strings.ToLower(level))); err != nil {
+			return nil, fmt.Errorf("invalid log level %q for component %q", level, component)
+		}
+		parsed[component] = l
+	}
+	return parsed, nil
+}
+
+// GetDefaultComponentLevels returns the component levels used unless the service telemetry config
+// overrides them.
+func GetDefaultComponentLevels() map[string]zapcore.Level {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	return copyLevels(defaultComponentLevels)
+}
+
+// SetDefaultComponentLevels replaces the default component levels, e.g. with the ones of the extracfg
+// file, and applies them.
+func SetDefaultComponentLevels(levels map[string]zapcore.Level) {
+	levelsMu.Lock()
+	defaultComponentLevels = copyLevels(levels)
+	levelsMu.Unlock()
+	SetComponentLevels(levels)
+}
+
+// GetComponentLevels returns the component levels in use.
+func GetComponentLevels() map[string]zapcore.Level {
+	return copyLevels(*componentLevels.Load())
+}
+
+// SetComponentLevels replaces the component levels in use, they apply to the running components
+// right away.
+func SetComponentLevels(levels map[string]zapcore.Level) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	levels = copyLevels(levels)
+	componentLevels.Store(&levels)
+}
+
+// SetComponentLevel sets the level of a single component.
+func SetComponentLevel(component string, level zapcore.Level) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	levels := copyLevels(*componentLevels.Load())
+	levels[component] = level
+	componentLevels.Store(&levels)
+}
+
+// UnsetComponentLevel removes the level of a single component, which then uses the level of the
+// collector again.
+func UnsetComponentLevel(component string) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	levels := copyLevels(*componentLevels.Load())
+	delete(levels, component)
+	componentLevels.Store(&levels)
+}
+
+// FormatComponentLevels returns the levels sorted by component, e.g. for logging.
+func FormatComponentLevels(levels map[string]zapcore.Level) string {
+	components := make([]string, 0, len(levels))
+	for component := range levels {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	pairs := make([]string, 0, len(components))
+	for _, component := range components {
+		pairs = append(pairs, component+":"+levels[component].String())
+	}
+	return strings.Join(pairs, ",")
+}
+
+func copyLevels(levels map[string]zapcore.Level) map[string]zapcore.Level {
+	c := make(map[string]zapcore.Level, len(levels))
+	for component, level := range levels {
+		c[component] = level
+	}
+	return c
+}
+
+// componentLevel returns the level of the component, an exact ID match, e.g. otlp/app, takes
+// precedence over a type match, e.g. otlp.
+func componentLevel(name string) (zapcore.Level, bool) {
+	if name == "" {
+		return zapcore.InvalidLevel, false
+	}
+	levels := *componentLevels.Load()
+	if len(levels) == 0 {
+		return zapcore.InvalidLevel, false
+	}
+	if level, ok := levels[name]; ok {
+		return level, true
+	}
+	if typ, _, found := strings.Cut(name, "/"); found {
+		if level, ok := levels[typ]; ok {
+			return level, true
+		}
+	}
+	return zapcore.InvalidLevel, false
+}
+
+// levelCore applies the level of the component of the logger, which may be lower or higher than the
+// level of the collector. Entries below the level of the collector are written without going through
+// the wrapped core checks, e.g. sampling.
+type levelCore struct {
+	core zapcore.Core
+	name string
+}
+
+func newLevelCore(core zapcore.Core) zapcore.Core {
+	return &levelCore{core: core}
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	if l, ok := componentLevel(c.name); ok {
+		return level >= l
+	}
+	return c.core.Enabled(level)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	name := c.name
+	for _, field := range fields {
+		if field.Key == componentNameKey && field.Type == zapcore.StringType {
+			name = field.String
+		}
+	}
+	return &levelCore{core: c.core.With(fields), name: name}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	l, ok := componentLevel(c.name)
+	if !ok {
+		return c.core.Check(entry, ce)
+	}
+	if entry.Level < l {
+		return ce
+	}
+	if c.core.Enabled(entry.Level) {
+		return c.core.Check(entry, ce)
+	}
+	return ce.AddCore(entry, c)
+}
+
+func (c *levelCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.core.Write(entry, fields)
+}
+
+func (c *levelCore) Sync() error {
+	return c.core.Sync()
+}
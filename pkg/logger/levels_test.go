@@ -0,0 +1,86 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseComponentLevels(t *testing.T) {
+	levels, err := ParseComponentLevels(map[string]string{"awsemf": "DEBUG", "otlp/app": "warn"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]zapcore.Level{"awsemf": zapcore.DebugLevel, "otlp/app": zapcore.WarnLevel}, levels)
+	assert.Equal(t, "awsemf:debug,otlp/app:warn", FormatComponentLevels(levels))
+
+	_, err = ParseComponentLevels(map[string]string{"awsemf": "verbose"})
+	assert.Error(t, err)
+	_, err = ParseComponentLevels(map[string]string{"": "info"})
+	assert.Error(t, err)
+}
+
+func TestLevelCore(t *testing.T) {
+	defaults := GetDefaultComponentLevels()
+	t.Cleanup(func() { SetDefaultComponentLevels(defaults) })
+	SetDefaultComponentLevels(map[string]zapcore.Level{
+		"awsemf":   zapcore.DebugLevel,
+		"otlp/app": zapcore.WarnLevel,
+	})
+
+	// the collector logs at info
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(newLevelCore(core))
+	component := func(kind, name string) *zap.Logger {
+		return logger.With(zap.String("kind", kind), zap.String(componentNameKey, name))
+	}
+	emf := component("exporter", "awsemf/app")
+	otlpApp := component("receiver", "otlp/app")
+	otlp := component("receiver", "otlp")
+
+	logger.Debug("collector debug")
+	logger.Info("collector info")
+	emf.Debug("emf debug")
+	otlpApp.Info("otlp/app info")
+	otlpApp.Warn("otlp/app warn")
+	otlp.Debug("otlp debug")
+	otlp.Info("otlp info")
+
+	var messages []string
+	for _, entry := range logs.TakeAll() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"collector info", "emf debug", "otlp/app warn", "otlp info"}, messages)
+	// the fields of the component are kept
+	emf.Debug("with fields", zap.Int("count", 1))
+	entries := logs.TakeAll()
+	require.Len(t, entries, 1)
+	assert.Equal(t, map[string]any{"kind": "exporter", "name": "awsemf/app", "count": int64(1)}, entries[0].ContextMap())
+
+	// the levels are changed at runtime
+	SetComponentLevel("otlp", zapcore.DebugLevel)
+	UnsetComponentLevel("awsemf")
+	otlp.Debug("otlp debug")
+	emf.Debug("emf debug")
+	entries = logs.TakeAll()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "otlp debug", entries[0].Message)
+	assert.Equal(t, map[string]zapcore.Level{"otlp": zapcore.DebugLevel, "otlp/app": zapcore.WarnLevel}, GetComponentLevels())
+}
@@ -22,6 +22,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -31,32 +33,238 @@ import (
 )
 
 var (
-	UnixLogPath      = "/opt/aws/aws-otel-collector/logs/aws-otel-collector.log"
-	WindowsLogPath   = "C:\\ProgramData\\Amazon\\AWSOTelCollector\\Logs\\aws-otel-collector.log"
-	logfile          = getLogFilePath()
-	lumberjackLogger = tryNewLumberJackLogger()
+	UnixLogPath    = "/opt/aws/aws-otel-collector/logs/aws-otel-collector.log"
+	WindowsLogPath = "C:\\ProgramData\\Amazon\\AWSOTelCollector\\Logs\\aws-otel-collector.log"
+	logfile        = getLogFilePath()
+	// logWriter is the writer of the log file of the cores and of the go logger
+	logWriter = &fileWriter{logger: tryNewLumberJackLogger()}
+)
+
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+)
+
+// FileSettings configures the log file and its rotation.
+type FileSettings struct {
+	// Path of the log file, the default depends on the OS.
+	Path string `mapstructure:"path"`
+	// MaxSize is the size in megabytes at which the file is rotated.
+	MaxSize int `mapstructure:"max_size"`
+	// MaxBackups is the number of rotated files kept.
+	MaxBackups int `mapstructure:"max_backups"`
+	// MaxAge is the number of days rotated files are kept.
+	MaxAge   int  `mapstructure:"max_age"`
+	Compress bool `mapstructure:"compress"`
+	// Encoding of the collector logs written to the file, json or console.
+	Encoding string `mapstructure:"encoding"`
+	// RotateAt additionally rotates the file every day at the given local time, e.g. 00:00.
+	RotateAt string `mapstructure:"rotate_at"`
+}
+
+// Validate checks the encoding and the rotation time.
+func (s FileSettings) Validate() error {
+	if s.Encoding != EncodingJSON && s.Encoding != EncodingConsole {
+		return fmt.Errorf("invalid log encoding %q, must be %q or %q", s.Encoding, EncodingJSON, EncodingConsole)
+	}
+	if s.MaxSize < 0 || s.MaxBackups < 0 || s.MaxAge < 0 {
+		return fmt.Errorf("log rotation sizes and counts must not be negative")
+	}
+	if s.RotateAt != "" {
+		if _, err := time.Parse(rotateAtLayout, s.RotateAt); err != nil {
+			return fmt.Errorf("invalid log rotation time %q, must be HH:MM: %w", s.RotateAt, err)
+		}
+	}
+	return nil
+}
+
+const rotateAtLayout = "15:04"
+
+var (
+	// The default values below should not change, because the retention information has already been published to public doc.
+	defaultFileSettings = FileSettings{
+		MaxSize:    100, //MB
+		MaxBackups: 5,   //backup files
+		MaxAge:     7,   //days
+		Compress:   true,
+		Encoding:   EncodingJSON,
+	}
+	fileSettings = defaultFileSettings
+
+	mu               sync.Mutex
+	logLevel         string
+	errorLoggerSetUp bool
+	stopRotation     = func() {}
+	cloudWatch       *cloudWatchWriter
 )
 
 func tryNewLumberJackLogger() *lumberjack.Logger {
 	if logfile != "" && !extraconfig.IsRunningInContainer() {
 		return &lumberjack.Logger{
 			Filename:   logfile,
-			MaxSize:    100, //MB
-			MaxBackups: 5,   //backup files
-			MaxAge:     7,   //days
-			Compress:   true,
+			MaxSize:    fileSettings.MaxSize,
+			MaxBackups: fileSettings.MaxBackups,
+			MaxAge:     fileSettings.MaxAge,
+			Compress:   fileSettings.Compress,
 		}
 	}
 	return nil
 }
 
-// WrapCoreOpt returns a zap.Option that wraps the provided core, teeing the output to the lumberjack writer.
-// It uses a JSON encoder and the same level as the provided core.
-// If the lumberjack logger is not configured returns the provided core unmodified.
+// fileWriter writes to the lumberjack logger of the current file settings, nil when there is no log
+// file. The cores keep the same writer when the settings change, so that a single lumberjack logger
+// writes to, and rotates, the file.
+type fileWriter struct {
+	mu     sync.Mutex
+	logger *lumberjack.Logger
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.logger == nil {
+		return len(p), nil
+	}
+	return w.logger.Write(p)
+}
+
+// current returns the lumberjack logger written to.
+func (w *fileWriter) current() *lumberjack.Logger {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.logger
+}
+
+// swap writes to logger from now on and closes the previous one, which is no longer written to.
+func (w *fileWriter) swap(logger *lumberjack.Logger) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	previous := w.logger
+	w.logger = logger
+	if previous == nil {
+		return nil
+	}
+	return previous.Close()
+}
+
+// rotate rotates the file of the lumberjack logger written to.
+func (w *fileWriter) rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.logger == nil {
+		return nil
+	}
+	return w.logger.Rotate()
+}
+
+// GetDefaultFileSettings returns the log file settings used unless the service telemetry config
+// overrides them, i.e. the published defaults or the ones set by SetDefaultFileSettings.
+func GetDefaultFileSettings() FileSettings {
+	mu.Lock()
+	defer mu.Unlock()
+	settings := defaultFileSettings
+	if settings.Path == "" {
+		settings.Path = getLogFilePath()
+	}
+	return settings
+}
+
+// SetDefaultFileSettings replaces the default log file settings, e.g. with the ones of the extracfg
+// file, and applies them.
+func SetDefaultFileSettings(settings FileSettings) {
+	mu.Lock()
+	defaultFileSettings = settings
+	mu.Unlock()
+	SetFileSettings(settings)
+}
+
+// GetFileSettings returns the current log file settings.
+func GetFileSettings() FileSettings {
+	mu.Lock()
+	defer mu.Unlock()
+	settings := fileSettings
+	settings.Path = logfile
+	return settings
+}
+
+// SetFileSettings applies the log file settings. The file is reopened only when the settings changed,
+// the cores write to the new file right away, the collector logger picks up a new encoding when it is
+// rebuilt, e.g. on a configuration reload.
+func SetFileSettings(settings FileSettings) {
+	mu.Lock()
+	current := fileSettings
+	current.Path = logfile
+	if settings == current && logWriter.current() != nil {
+		mu.Unlock()
+		return
+	}
+	fileSettings = settings
+	logfile = settings.Path
+	logger := tryNewLumberJackLogger()
+	stopRotation()
+	if err := logWriter.swap(logger); err != nil {
+		log.Printf("W! failed to close the previous log file: %v\n", err)
+	}
+	stopRotation = startDailyRotation(logger != nil, settings.RotateAt)
+	setUp := errorLoggerSetUp
+	mu.Unlock()
+
+	if setUp {
+		SetupErrorLogger()
+	}
+}
+
+// startDailyRotation rotates the log file every day at the given time, it returns the function
+// stopping it.
+func startDailyRotation(enabled bool, rotateAt string) func() {
+	if !enabled || rotateAt == "" {
+		return func() {}
+	}
+	at, err := time.Parse(rotateAtLayout, rotateAt)
+	if err != nil {
+		log.Printf("W! ignoring invalid log rotation time %q: %v\n", rotateAt, err)
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(nextRotation(time.Now(), at)))
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case <-timer.C:
+				if err := logWriter.rotate(); err != nil {
+					log.Printf("E! failed to rotate log file: %v\n", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// nextRotation returns the next time of the day matching at, strictly after now.
+func nextRotation(now time.Time, at time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// WrapCoreOpt returns a zap.Option that wraps the provided core, teeing the output to the lumberjack writer
+// and to CloudWatch Logs when configured with SetCloudWatchSettings.
+// The file uses the encoding of the file settings, JSON by default, CloudWatch Logs always uses JSON. Both use
+// the same level as the provided core.
+// The resulting core applies the component levels set with SetComponentLevels.
 func WrapCoreOpt() zap.Option {
 	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
-		if lumberjackLogger == nil {
-			return core
+		mu.Lock()
+		hasFile, encoding, cw := logWriter.current() != nil, fileSettings.Encoding, cloudWatch
+		mu.Unlock()
+		if !hasFile && cw == nil {
+			return newLevelCore(core)
 		}
 
 		encoderConfig := zapcore.EncoderConfig{
@@ -70,21 +278,65 @@ func WrapCoreOpt() zap.Option {
 			EncodeDuration: zapcore.MillisDurationEncoder,
 			EncodeCaller:   zapcore.ShortCallerEncoder,
 		}
-		return zapcore.NewTee(core, zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(lumberjackLogger), core.(zapcore.LevelEnabler)))
+		cores := []zapcore.Core{core}
+		if hasFile {
+			encoder := zapcore.NewJSONEncoder(encoderConfig)
+			if encoding == EncodingConsole {
+				consoleConfig := encoderConfig
+				consoleConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+				encoder = zapcore.NewConsoleEncoder(consoleConfig)
+			}
+			cores = append(cores, zapcore.NewCore(encoder, zapcore.AddSync(logWriter), core.(zapcore.LevelEnabler)))
+		}
+		if cw != nil {
+			cores = append(cores, newCloudWatchCore(zapcore.NewJSONEncoder(encoderConfig), cw, core.(zapcore.LevelEnabler)))
+		}
+		return newLevelCore(zapcore.NewTee(cores...))
 	})
 }
 
-// SetupErrorLogger setup lumberjackLogger for go logger
+// SetCloudWatchSettings ships the collector logs to CloudWatch Logs, in addition to the log file or
+// stderr. The collector logger picks up the change when it is rebuilt, e.g. on a configuration reload.
+func SetCloudWatchSettings(settings CloudWatchSettings) error {
+	writer, err := newCloudWatchWriter(settings)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	previous := cloudWatch
+	cloudWatch = writer
+	mu.Unlock()
+	if previous != nil {
+		previous.close()
+	}
+	return nil
+}
+
+// Close sends the log entries buffered for CloudWatch Logs, it is called before the collector exits.
+func Close() {
+	mu.Lock()
+	cw := cloudWatch
+	cloudWatch = nil
+	mu.Unlock()
+	if cw != nil {
+		cw.close()
+	}
+}
+
+// SetupErrorLogger setup the log file writer for go logger
 func SetupErrorLogger() {
-	var writer io.WriteCloser
+	mu.Lock()
+	defer mu.Unlock()
+	errorLoggerSetUp = true
+
+	var writer io.Writer
 	// When running in container, always log to stderr, it makes debugging easier.
-	if lumberjackLogger != nil {
+	if logWriter.current() != nil {
 		err := os.MkdirAll(filepath.Dir(logfile), 0755)
 		if err != nil {
 			log.Printf("D! fail to chmod on log file due to : %v \n", err)
 		}
-		// The codes below should not change, because the retention information has already been published to public doc.
-		writer = lumberjackLogger
+		writer = logWriter
 	} else {
 		writer = os.Stderr
 	}
@@ -101,8 +353,16 @@ func getLogFilePath() string {
 
 // SetLogLevel allows to set log level by parameter
 // possible values (DEBUG, INFO, WARN, ERROR, DPANIC, PANIC, FATAL)
+// The level is layered on top of the configuration files by the config provider, see GetLogLevel.
 func SetLogLevel(level string) {
-	if level != "" {
-		os.Args = append(os.Args, fmt.Sprintf("--config=yaml:service::telemetry::logs::level: %s", level))
-	}
+	mu.Lock()
+	defer mu.Unlock()
+	logLevel = level
+}
+
+// GetLogLevel returns the level set by SetLogLevel, empty when it is not set.
+func GetLogLevel() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return logLevel
 }
@@ -16,31 +16,43 @@
 package logger
 
 import (
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
-	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
 )
 
+func TestMain(m *testing.M) {
+	// the tests write to a log file, which is disabled when the collector runs in a container
+	os.Setenv(extraconfig.EnvKeyRunInContainer, "False")
+	os.Exit(m.Run())
+}
+
 func setupLogEnv() {
 	logfile = getLogFilePath()
-	lumberjackLogger = tryNewLumberJackLogger()
+	_ = logWriter.swap(tryNewLumberJackLogger())
 }
 
 func TestSetupErrorLogger(t *testing.T) {
 	setupLogEnv()
 	SetupErrorLogger()
-	_, ok := log.Writer().(*lumberjack.Logger)
-	assert.True(t, ok)
+	assert.Same(t, logWriter, log.Writer())
 }
 
 func TestSetupErrorLoggerWithNoFilePath(t *testing.T) {
 	logfile = ""
-	lumberjackLogger = tryNewLumberJackLogger()
+	_ = logWriter.swap(tryNewLumberJackLogger())
 
 	SetupErrorLogger()
 	_, ok := log.Writer().(*os.File)
@@ -58,7 +70,107 @@ func TestGetLogFilePath(t *testing.T) {
 
 func TestSetLogLevel(t *testing.T) {
 	setupLogEnv()
+	t.Cleanup(func() { SetLogLevel("") })
+	args := append([]string(nil), os.Args...)
 	SetLogLevel("DEBUG")
-	argStr := strings.Join(os.Args[:], "=")
-	assert.True(t, strings.Contains(argStr, "--config=yaml:service::telemetry::logs::level: DEBUG"))
+	assert.Equal(t, "DEBUG", GetLogLevel())
+	// the level is not passed as a flag, which the sub commands would reject
+	assert.Equal(t, args, os.Args)
+}
+
+func TestSetFileSettings(t *testing.T) {
+	setupLogEnv()
+	defaults := GetDefaultFileSettings()
+	t.Cleanup(func() { SetDefaultFileSettings(defaults) })
+
+	assert.Equal(t, FileSettings{Path: getLogFilePath(), MaxSize: 100, MaxBackups: 5, MaxAge: 7, Compress: true, Encoding: EncodingJSON}, defaults)
+
+	SetDefaultFileSettings(FileSettings{Path: "custom.log", MaxSize: 10, MaxBackups: 0, MaxAge: 1, Encoding: EncodingJSON})
+	assert.Equal(t, &lumberjack.Logger{Filename: "custom.log", MaxSize: 10, MaxBackups: 0, MaxAge: 1}, logWriter.current())
+	assert.Equal(t, "custom.log", GetDefaultFileSettings().Path)
+
+	// the same settings keep the same file
+	current := logWriter.current()
+	SetFileSettings(GetFileSettings())
+	assert.Same(t, current, logWriter.current())
+}
+
+// TestSetFileSettingsSwapsFile checks that a logger built before the settings change writes to the new
+// file, and no longer to the previous one.
+func TestSetFileSettingsSwapsFile(t *testing.T) {
+	setupLogEnv()
+	defaults := GetDefaultFileSettings()
+	t.Cleanup(func() { SetDefaultFileSettings(defaults) })
+
+	dir := t.TempDir()
+	settings := defaults
+	settings.Path = filepath.Join(dir, "before.log")
+	SetFileSettings(settings)
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zapcore.InfoLevel), WrapCoreOpt())
+	logger.Info("before")
+
+	settings.Path = filepath.Join(dir, "after.log")
+	SetFileSettings(settings)
+	logger.Info("after")
+	require.NoError(t, logger.Sync())
+
+	before, err := os.ReadFile(filepath.Join(dir, "before.log"))
+	require.NoError(t, err)
+	after, err := os.ReadFile(filepath.Join(dir, "after.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(before), `"message":"before"`)
+	assert.NotContains(t, string(before), `"message":"after"`)
+	assert.Contains(t, string(after), `"message":"after"`)
+}
+
+func TestFileSettingsValidate(t *testing.T) {
+	valid := FileSettings{Encoding: EncodingConsole, RotateAt: "23:30"}
+	assert.NoError(t, valid.Validate())
+
+	invalid := []FileSettings{
+		{Encoding: "xml"},
+		{Encoding: EncodingJSON, MaxSize: -1},
+		{Encoding: EncodingJSON, RotateAt: "25:00"},
+		{Encoding: EncodingJSON, RotateAt: "midnight"},
+	}
+	for _, settings := range invalid {
+		assert.Error(t, settings.Validate(), "%+v", settings)
+	}
+}
+
+func TestWrapCoreOptEncoding(t *testing.T) {
+	setupLogEnv()
+	defaults := GetDefaultFileSettings()
+	t.Cleanup(func() { SetDefaultFileSettings(defaults) })
+
+	for _, encoding := range []string{EncodingJSON, EncodingConsole} {
+		t.Run(encoding, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "collector.log")
+			settings := defaults
+			settings.Path, settings.Encoding = path, encoding
+			SetFileSettings(settings)
+
+			logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zapcore.InfoLevel), WrapCoreOpt())
+			logger.Info("hello", zap.String("component", "test"))
+			require.NoError(t, logger.Sync())
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			if encoding == EncodingJSON {
+				assert.Contains(t, string(content), `"message":"hello"`)
+			} else {
+				assert.Contains(t, string(content), "INFO\thello\t{\"component\": \"test\"}")
+			}
+		})
+	}
+}
+
+func TestNextRotation(t *testing.T) {
+	at, err := time.Parse(rotateAtLayout, "02:30")
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 31, 1, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 1, 31, 2, 30, 0, 0, time.UTC), nextRotation(now, at))
+	now = time.Date(2024, 1, 31, 2, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 2, 1, 2, 30, 0, 0, time.UTC), nextRotation(now, at))
 }
@@ -0,0 +1,319 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package outbound holds the proxy and CA bundle settings of the outbound connections of the
+// collector.
+package outbound // import "github.com/aws-observability/aws-otel-collector/pkg/outbound"
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"go.uber.org/multierr"
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/exportedenv"
+)
+
+const (
+	envKeyHTTPProxy  = "HTTP_PROXY"
+	envKeyHTTPSProxy = "HTTPS_PROXY"
+	envKeyNoProxy    = "NO_PROXY"
+	// envKeyCertFile is read as the CA bundle, it is not exported as Go loads it in place of the
+	// CA file of the system on Linux rather than with it
+	envKeyCertFile = "SSL_CERT_FILE"
+)
+
+// Settings are the settings of the outbound connections, set with the environment, the extracfg
+// file and the service::outbound block of the configuration.
+type Settings struct {
+	HTTPProxy  string `mapstructure:"http_proxy"`
+	HTTPSProxy string `mapstructure:"https_proxy"`
+	// NoProxy are the comma separated hosts, domains and CIDRs which bypass the proxy
+	NoProxy string `mapstructure:"no_proxy"`
+	// CABundle is a PEM file of CA certificates trusted by the collector, e.g. the one of a TLS
+	// intercepting proxy
+	CABundle string `mapstructure:"ca_bundle"`
+}
+
+// FromEnvironment returns the settings of the environment variables.
+func FromEnvironment() Settings {
+	return Settings{
+		HTTPProxy:  getEnvAny(envKeyHTTPProxy, "http_proxy"),
+		HTTPSProxy: getEnvAny(envKeyHTTPSProxy, "https_proxy"),
+		NoProxy:    getEnvAny(envKeyNoProxy, "no_proxy"),
+		CABundle:   os.Getenv(envKeyCertFile),
+	}
+}
+
+func getEnvAny(keys ...string) string {
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Validate checks the proxy URLs, the no_proxy entries and that the CA bundle has certificates.
+func (s Settings) Validate() error {
+	var errs error
+	for _, proxy := range []struct {
+		key   string
+		value string
+	}{
+		{"http_proxy", s.HTTPProxy},
+		{"https_proxy", s.HTTPSProxy},
+	} {
+		if proxy.value == "" {
+			continue
+		}
+		if err := ValidateProxyURL(proxy.value); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("invalid %s: %w", proxy.key, err))
+		}
+	}
+	if s.NoProxy != "" {
+		for _, entry := range strings.Split(s.NoProxy, ",") {
+			if err := validateNoProxyEntry(strings.TrimSpace(entry)); err != nil {
+				errs = multierr.Append(errs, fmt.Errorf("invalid no_proxy: %w", err))
+			}
+		}
+	}
+	if s.CABundle != "" {
+		if _, err := LoadCABundle(s.CABundle); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("invalid ca_bundle: %w", err))
+		}
+	}
+	return errs
+}
+
+// ValidateProxyURL checks that value is the absolute URL of an http, https or socks5 proxy.
+func ValidateProxyURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q must be an absolute URL, e.g. http://proxy.example.com:3128", value)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return nil
+	default:
+		return fmt.Errorf("unsupported scheme %q of %q, must be http, https or socks5", u.Scheme, u.Redacted())
+	}
+}
+
+func validateNoProxyEntry(entry string) error {
+	switch {
+	case entry == "":
+		return errors.New("empty entry")
+	case strings.ContainsAny(entry, " \t"):
+		return fmt.Errorf("%q contains spaces", entry)
+	case strings.Contains(entry, "/"):
+		if _, _, err := net.ParseCIDR(entry); err != nil {
+			return fmt.Errorf("%q is not a valid CIDR", entry)
+		}
+	}
+	return nil
+}
+
+// LoadCABundle returns the certificates of a PEM file, which must have at least one.
+func LoadCABundle(path string) ([]*x509.Certificate, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s has no PEM encoded certificate", path)
+	}
+	return certs, nil
+}
+
+// Override returns the settings with the ones set in other taking precedence.
+func (s Settings) Override(other Settings) Settings {
+	if other.HTTPProxy != "" {
+		s.HTTPProxy = other.HTTPProxy
+	}
+	if other.HTTPSProxy != "" {
+		s.HTTPSProxy = other.HTTPSProxy
+	}
+	if other.NoProxy != "" {
+		s.NoProxy = other.NoProxy
+	}
+	if other.CABundle != "" {
+		s.CABundle = other.CABundle
+	}
+	return s
+}
+
+// String returns the settings to log, without the passwords of the proxy URLs.
+func (s Settings) String() string {
+	return fmt.Sprintf("http_proxy=%s https_proxy=%s no_proxy=%s ca_bundle=%s",
+		orNone(redactURL(s.HTTPProxy)), orNone(redactURL(s.HTTPSProxy)), orNone(s.NoProxy), orNone(s.CABundle))
+}
+
+func redactURL(value string) string {
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return value
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// env returns the environment variables of the settings, read by the clients which do not use
+// http.DefaultTransport, e.g. gRPC.
+func (s Settings) env() map[string]string {
+	env := map[string]string{}
+	for key, value := range map[string]string{
+		envKeyHTTPProxy:  s.HTTPProxy,
+		envKeyHTTPSProxy: s.HTTPSProxy,
+		envKeyNoProxy:    s.NoProxy,
+	} {
+		if value != "" {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// systemCertPool returns the CAs of the system, replaced in tests.
+var systemCertPool = x509.SystemCertPool
+
+// rootCAs returns the CAs of the system along with the ones of the CA bundle.
+func rootCAs(bundle string) (*x509.CertPool, error) {
+	certs, err := LoadCABundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := systemCertPool()
+	if err != nil {
+		log.Printf("W! failed to load the CA certificates of the system, only the ones of %s are trusted: %v\n", bundle, err)
+		pool = x509.NewCertPool()
+	}
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+var (
+	mu      sync.Mutex
+	current Settings
+	applied bool
+	proxy   func(*url.URL) (*url.URL, error)
+	install sync.Once
+	// caBundle is the CA bundle trusted by http.DefaultTransport
+	caBundle string
+	exported exportedenv.Env
+)
+
+// Apply sets the settings of the outbound connections. They are exported as environment variables,
+// the variables exported by previous settings and not by these are restored, and the proxy of
+// http.DefaultTransport and its clones follows them. http.DefaultTransport trusts the CA bundle
+// along with the CAs of the system. The effective settings are logged when they change.
+func Apply(settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	// before the first request, so that http.DefaultTransport is not used concurrently
+	install.Do(func() {
+		http.DefaultTransport.(*http.Transport).Proxy = proxyFromSettings
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	errs := exported.Export(settings.env())
+
+	if settings.CABundle != caBundle {
+		if err := setRootCAs(settings.CABundle); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("invalid ca_bundle: %w", err))
+		} else {
+			caBundle = settings.CABundle
+		}
+	}
+
+	proxy = (&httpproxy.Config{
+		HTTPProxy:  settings.HTTPProxy,
+		HTTPSProxy: settings.HTTPSProxy,
+		NoProxy:    settings.NoProxy,
+	}).ProxyFunc()
+	if !applied || settings != current {
+		log.Printf("I! outbound connections: %s\n", settings)
+	}
+	current, applied = settings, true
+	return errs
+}
+
+// setRootCAs sets the CAs trusted by http.DefaultTransport, the ones of the system without a bundle.
+// The transport reads them when it opens a connection, the settings are applied before the
+// pipelines start.
+func setRootCAs(bundle string) error {
+	transport := http.DefaultTransport.(*http.Transport)
+	if bundle == "" {
+		transport.TLSClientConfig = nil
+		return nil
+	}
+	pool, err := rootCAs(bundle)
+	if err != nil {
+		return err
+	}
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return nil
+}
+
+// Current returns the settings last applied.
+func Current() Settings {
+	mu.Lock()
+	defer mu.Unlock()
+	return current
+}
+
+// proxyFromSettings is the proxy of http.DefaultTransport, like http.ProxyFromEnvironment with the
+// settings last applied rather than the environment variables of the first request.
+func proxyFromSettings(req *http.Request) (*url.URL, error) {
+	mu.Lock()
+	fn := proxy
+	mu.Unlock()
+	return fn(req.URL)
+}
@@ -0,0 +1,309 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package supportbundle collects what is needed to troubleshoot the collector into a single archive:
+// the end of the log file, the configuration and the extracfg file with their secrets redacted, the
+// build, the platform, the environment and the profiles of a running instance.
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/config"
+	"github.com/aws-observability/aws-otel-collector/pkg/extraconfig"
+	"github.com/aws-observability/aws-otel-collector/tools/version"
+)
+
+const (
+	// DefaultMaxLogSize is the size of the end of the log file kept by default, 10 MB.
+	DefaultMaxLogSize = 10 << 20
+
+	defaultPprofEndpoint = "localhost:1777"
+	profilesPath         = "/debug/pprof/"
+	profileTimeout       = 30 * time.Second
+)
+
+// Settings are the sources of the bundle.
+type Settings struct {
+	// LogFile is the path of the log file of the collector
+	LogFile string
+	// MaxLogSize is the number of bytes kept from the end of the log file
+	MaxLogSize int64
+	// ExtraConfigFile is the path of the extracfg file
+	ExtraConfigFile string
+	// Config returns the resolved configuration
+	Config    func(ctx context.Context) (map[string]any, error)
+	BuildInfo version.BuildInfo
+	Platform  extraconfig.PlatformInfo
+	// Cgroup is nil when the limits are unknown
+	Cgroup  *extraconfig.CgroupLimits
+	Environ []string
+	// AdminEndpoint is the endpoint of the admin server of the running instance, empty when not enabled.
+	// The endpoints of the pprof extensions of the configuration are tried after it.
+	AdminEndpoint string
+	Client        *http.Client
+	Now           func() time.Time
+}
+
+// bundle is the archive being written.
+type bundle struct {
+	tw  *tar.Writer
+	dir string
+	now time.Time
+	// problems are the sources which could not be collected
+	problems []string
+}
+
+// Write writes the bundle as a gzipped tar archive to w. The sources which cannot be collected are
+// skipped, they are listed in the errors.txt file of the archive and returned.
+func Write(ctx context.Context, w io.Writer, settings Settings) ([]string, error) {
+	if settings.Now == nil {
+		settings.Now = time.Now
+	}
+	if settings.Client == nil {
+		settings.Client = &http.Client{Timeout: profileTimeout}
+	}
+	if settings.MaxLogSize <= 0 {
+		settings.MaxLogSize = DefaultMaxLogSize
+	}
+	gw := gzip.NewWriter(w)
+	now := settings.Now()
+	b := &bundle{
+		tw:  tar.NewWriter(gw),
+		dir: "aws-otel-collector-support-" + now.UTC().Format("20060102T150405Z"),
+		now: now,
+	}
+
+	if err := b.collect(ctx, settings); err != nil {
+		return b.problems, err
+	}
+	if len(b.problems) > 0 {
+		if err := b.add("errors.txt", []byte(strings.Join(b.problems, "\n")+"\n")); err != nil {
+			return b.problems, err
+		}
+	}
+	if err := b.tw.Close(); err != nil {
+		return b.problems, err
+	}
+	return b.problems, gw.Close()
+}
+
+func (b *bundle) collect(ctx context.Context, settings Settings) error {
+	if err := b.addJSON("version.json", settings.BuildInfo); err != nil {
+		return err
+	}
+	platform := struct {
+		Platform extraconfig.PlatformInfo  `json:"platform"`
+		Cgroup   *extraconfig.CgroupLimits `json:"cgroup,omitempty"`
+	}{settings.Platform, settings.Cgroup}
+	if err := b.addJSON("platform.json", platform); err != nil {
+		return err
+	}
+	if err := b.add("env.txt", filterEnv(settings.Environ)); err != nil {
+		return err
+	}
+
+	if content, err := tailFile(settings.LogFile, settings.MaxLogSize); err != nil {
+		b.problem("log file", err)
+	} else if err = b.add(path.Base(strings.ReplaceAll(settings.LogFile, "\\", "/")), content); err != nil {
+		return err
+	}
+
+	if content, err := os.ReadFile(settings.ExtraConfigFile); err != nil {
+		b.problem("extracfg file", err)
+	} else if err = b.add("extracfg.txt", redactExtraConfig(content)); err != nil {
+		return err
+	}
+
+	var conf map[string]any
+	if settings.Config != nil {
+		var err error
+		if conf, err = settings.Config(ctx); err != nil {
+			b.problem("configuration", err)
+		} else if err = b.addYAML("config.yaml", config.Redact(conf)); err != nil {
+			return err
+		}
+	}
+
+	return b.collectProfiles(ctx, settings, profileEndpoints(settings.AdminEndpoint, conf))
+}
+
+// collectProfiles gets the goroutine dump and the heap profile from the first endpoint answering.
+func (b *bundle) collectProfiles(ctx context.Context, settings Settings, endpoints []string) error {
+	if len(endpoints) == 0 {
+		b.problem("profiles", fmt.Errorf("no running instance to get them from, enable the admin server or the pprof extension"))
+		return nil
+	}
+	var errs []string
+	for _, endpoint := range endpoints {
+		base := "http://" + endpoint + profilesPath
+		goroutines, err := get(ctx, settings.Client, base+"goroutine?debug=2")
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err = b.add("goroutines.txt", goroutines); err != nil {
+			return err
+		}
+		heap, err := get(ctx, settings.Client, base+"heap")
+		if err != nil {
+			b.problem("heap profile", err)
+			return nil
+		}
+		return b.add("heap.pprof", heap)
+	}
+	b.problem("profiles", fmt.Errorf("no running instance reachable: %s", strings.Join(errs, "; ")))
+	return nil
+}
+
+// profileEndpoints returns the admin endpoint followed by the endpoints of the pprof extensions of
+// the configuration.
+func profileEndpoints(adminEndpoint string, conf map[string]any) []string {
+	var endpoints []string
+	if adminEndpoint != "" {
+		endpoints = append(endpoints, adminEndpoint)
+	}
+	extensions, _ := conf["extensions"].(map[string]any)
+	var ids []string
+	for id := range extensions {
+		if typ, _, _ := strings.Cut(id, "/"); typ == "pprof" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		cfg, _ := extensions[id].(map[string]any)
+		endpoint, _ := cfg["endpoint"].(string)
+		if endpoint == "" {
+			endpoint = defaultPprofEndpoint
+		}
+		endpoints = append(endpoints, localEndpoint(endpoint))
+	}
+	return endpoints
+}
+
+// localEndpoint replaces the unspecified host of an endpoint, e.g. 0.0.0.0:1777 or [::]:1777, by
+// localhost.
+func localEndpoint(endpoint string) string {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		return net.JoinHostPort("localhost", port)
+	}
+	return endpoint
+}
+
+// tailFile returns at most maxSize bytes of the end of the file, starting at a line.
+func tailFile(file string, maxSize int64) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - maxSize
+	if offset <= 0 {
+		return io.ReadAll(f)
+	}
+	// the byte before the end tells whether it starts at a line
+	if _, err = f.Seek(offset-1, io.SeekStart); err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	// drop the first line, unless it is whole, a single line longer than maxSize is kept cut
+	if i := bytes.IndexByte(content, '\n'); i >= 0 {
+		return content[i+1:], nil
+	}
+	return content[1:], nil
+}
+
+func get(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return body, nil
+}
+
+func (b *bundle) problem(source string, err error) {
+	b.problems = append(b.problems, fmt.Sprintf("%s: %v", source, err))
+}
+
+func (b *bundle) addJSON(name string, v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	return b.add(name, append(content, '\n'))
+}
+
+func (b *bundle) addYAML(name string, v any) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	return b.add(name, buf.Bytes())
+}
+
+func (b *bundle) add(name string, content []byte) error {
+	err := b.tw.WriteHeader(&tar.Header{
+		Name:    b.dir + "/" + name,
+		Mode:    0600,
+		Size:    int64(len(content)),
+		ModTime: b.now,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = b.tw.Write(content)
+	return err
+}
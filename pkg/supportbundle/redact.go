@@ -0,0 +1,131 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package supportbundle
+
+import (
+	"bytes"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws-observability/aws-otel-collector/pkg/config"
+)
+
+// envAllowlist are the environment variables kept in the bundle, the ones ending with _ are prefixes.
+var envAllowlist = []string{
+	"AOT_",
+	"AWS_REGION",
+	"AWS_DEFAULT_REGION",
+	"AWS_PROFILE",
+	"AWS_ROLE_ARN",
+	"AWS_ROLE_SESSION_NAME",
+	"AWS_WEB_IDENTITY_TOKEN_FILE",
+	"AWS_CONFIG_FILE",
+	"AWS_SHARED_CREDENTIALS_FILE",
+	"AWS_STS_REGIONAL_ENDPOINTS",
+	"AWS_EC2_METADATA_",
+	"AWS_EXECUTION_ENV",
+	"AWS_LAMBDA_FUNCTION_NAME",
+	"ECS_CONTAINER_METADATA_URI",
+	"ECS_CONTAINER_METADATA_URI_V4",
+	"KUBERNETES_SERVICE_HOST",
+	"RUN_IN_CONTAINER",
+	"HTTP_PROXY",
+	"HTTPS_PROXY",
+	"NO_PROXY",
+	"SSL_CERT_FILE",
+	"GOMEMLIMIT",
+	"GOMAXPROCS",
+	"GOGC",
+	"GODEBUG",
+	"OTEL_SERVICE_NAME",
+	"OTEL_RESOURCE_ATTRIBUTES",
+	"OTEL_LOG_LEVEL",
+	"OTEL_PROPAGATORS",
+	"OTEL_TRACES_SAMPLER",
+	"OTEL_TRACES_SAMPLER_ARG",
+	"OTEL_EXPORTER_OTLP_ENDPOINT",
+	"OTEL_EXPORTER_OTLP_PROTOCOL",
+	"OTEL_EXPORTER_OTLP_TIMEOUT",
+}
+
+// envDenylist are left out even though they are allowed, the configuration content is in the
+// bundle already, with its secrets redacted.
+var envDenylist = []string{"AOT_CONFIG_CONTENT"}
+
+// filterEnv returns the allowed variables of the environment sorted by name, with the values of the
+// sensitive ones redacted and the passwords of the URLs masked.
+func filterEnv(environ []string) []byte {
+	var lines []string
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		if !isAllowed(strings.ToUpper(key)) {
+			continue
+		}
+		lines = append(lines, key+"="+redactValue(key, value))
+	}
+	sort.Strings(lines)
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func isAllowed(key string) bool {
+	for _, denied := range envDenylist {
+		if strings.HasPrefix(key, denied) {
+			return false
+		}
+	}
+	for _, allowed := range envAllowlist {
+		if key == allowed || strings.HasSuffix(allowed, "_") && strings.HasPrefix(key, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactExtraConfig redacts the values of the sensitive keys of the extracfg file, the comments and
+// the lines which are not settings are kept as they are.
+func redactExtraConfig(content []byte) []byte {
+	lines := bytes.Split(content, []byte("\n"))
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(string(line))
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		lines[i] = []byte(key + "=" + redactValue(strings.TrimSpace(key), value))
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// redactValue redacts the value of a sensitive key or of headers, e.g. OTEL_EXPORTER_OTLP_HEADERS
+// which holds the tokens of the exporters, and the password of a URL, e.g. of a proxy.
+func redactValue(key, value string) string {
+	if (config.IsSensitiveKey(key) || strings.Contains(strings.ToUpper(key), "HEADERS")) && value != "" {
+		return config.RedactedValue
+	}
+	if u, err := url.Parse(strings.TrimSpace(value)); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted()
+		}
+	}
+	return value
+}
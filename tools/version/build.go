@@ -17,7 +17,12 @@ package version
 import (
 	"bytes"
 	"fmt"
-	"log"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/collector/featuregate"
 )
 
 var (
@@ -28,8 +33,134 @@ var (
 	Date    string
 )
 
+// upstreamModules are the prefixes of the modules listed in the BuildInfo.
+var upstreamModules = []string{
+	"go.opentelemetry.io/collector",
+	"github.com/open-telemetry/opentelemetry-collector-contrib",
+}
+
 func init() {
-	log.Printf("ADOT Collector version: %v\n", Version)
+	// builds without the ldflags of the Makefile, e.g. go install, still know their version
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		Version, GitHash, Date = fromBuildInfo(bi, Version, GitHash, Date)
+	}
+}
+
+// fromBuildInfo fills the values which were not set at link time from the module version and the
+// vcs settings stamped by the go tool.
+func fromBuildInfo(bi *debug.BuildInfo, version, gitHash, date string) (string, string, string) {
+	if version == "latest" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		version = bi.Main.Version
+	}
+	settings := map[string]string{}
+	for _, s := range bi.Settings {
+		settings[s.Key] = s.Value
+	}
+	if gitHash == "" && settings["vcs.revision"] != "" {
+		gitHash = settings["vcs.revision"]
+		if settings["vcs.modified"] == "true" {
+			gitHash += "-dirty"
+		}
+	}
+	if date == "" {
+		date = settings["vcs.time"]
+	}
+	return version, gitHash, date
+}
+
+// Module is a module the collector is built with.
+type Module struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+// BuildInfo describes the build of the collector and the feature gates it runs with.
+type BuildInfo struct {
+	Version      string   `json:"version"`
+	GitHash      string   `json:"gitHash"`
+	Date         string   `json:"date"`
+	GoVersion    string   `json:"goVersion"`
+	OS           string   `json:"os"`
+	Arch         string   `json:"arch"`
+	FeatureGates []string `json:"featureGates"`
+	Modules      []Module `json:"modules"`
+}
+
+// NewBuildInfo returns the BuildInfo of the running binary, with the gates enabled in the registry.
+func NewBuildInfo(registry *featuregate.Registry) BuildInfo {
+	info := BuildInfo{
+		Version:      Version,
+		GitHash:      GitHash,
+		Date:         Date,
+		GoVersion:    runtime.Version(),
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		FeatureGates: []string{},
+		Modules:      []Module{},
+	}
+	if registry != nil {
+		registry.VisitAll(func(gate *featuregate.Gate) {
+			if gate.IsEnabled() {
+				info.FeatureGates = append(info.FeatureGates, gate.ID())
+			}
+		})
+		sort.Strings(info.FeatureGates)
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Modules = upstreamDeps(bi.Deps)
+	}
+	return info
+}
+
+// upstreamDeps returns the upstream collector modules of the dependencies sorted by path, with the
+// version of their replacement when they are replaced.
+func upstreamDeps(deps []*debug.Module) []Module {
+	modules := []Module{}
+	for _, dep := range deps {
+		if !isUpstream(dep.Path) {
+			continue
+		}
+		version := dep.Version
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			version = dep.Replace.Version
+		}
+		modules = append(modules, Module{Path: dep.Path, Version: version})
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Path < modules[j].Path
+	})
+	return modules
+}
+
+func isUpstream(path string) bool {
+	for _, prefix := range upstreamModules {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Info returns the build info as the properties printed by the version command, without the modules.
+func (b BuildInfo) Info() Info {
+	return Info{
+		{"Version", b.Version},
+		{"GitHash", b.GitHash},
+		{"Date", b.Date},
+		{"Goversion", b.GoVersion},
+		{"OS", b.OS},
+		{"Architecture", b.Arch},
+		{"FeatureGates", strings.Join(b.FeatureGates, ",")},
+	}
+}
+
+// ModulesInfo returns the upstream modules and their versions.
+func (b BuildInfo) ModulesInfo() Info {
+	info := make(Info, 0, len(b.Modules))
+	for _, module := range b.Modules {
+		info = append(info, [2]string{module.Path, module.Version})
+	}
+	return info
 }
 
 // Info has properties about the build and runtime.
@@ -16,6 +16,7 @@ package version
 
 import (
 	"runtime"
+	"runtime/debug"
 	"strings"
 	"testing"
 
@@ -38,3 +39,89 @@ func TestInfoString(t *testing.T) {
 		assert.True(t, strings.Contains(infoString, el[1]))
 	}
 }
+
+func TestFromBuildInfo(t *testing.T) {
+	settings := []debug.BuildSetting{
+		{Key: "vcs.revision", Value: "0123abcd"},
+		{Key: "vcs.time", Value: "2024-02-01T00:00:00Z"},
+		{Key: "vcs.modified", Value: "true"},
+	}
+	tests := []struct {
+		name            string
+		bi              *debug.BuildInfo
+		version         string
+		gitHash         string
+		date            string
+		expectedVersion string
+		expectedGitHash string
+		expectedDate    string
+	}{
+		{
+			name:            "not set at link time",
+			bi:              &debug.BuildInfo{Main: debug.Module{Version: "v0.37.0"}, Settings: settings},
+			version:         "latest",
+			expectedVersion: "v0.37.0",
+			expectedGitHash: "0123abcd-dirty",
+			expectedDate:    "2024-02-01T00:00:00Z",
+		},
+		{
+			name:            "set at link time",
+			bi:              &debug.BuildInfo{Main: debug.Module{Version: "v0.37.0"}, Settings: settings},
+			version:         "v0.38.0",
+			gitHash:         "fedc",
+			date:            "2024-03-01",
+			expectedVersion: "v0.38.0",
+			expectedGitHash: "fedc",
+			expectedDate:    "2024-03-01",
+		},
+		{
+			name:            "development build",
+			bi:              &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}},
+			version:         "latest",
+			expectedVersion: "latest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, gitHash, date := fromBuildInfo(tt.bi, tt.version, tt.gitHash, tt.date)
+			assert.Equal(t, tt.expectedVersion, version)
+			assert.Equal(t, tt.expectedGitHash, gitHash)
+			assert.Equal(t, tt.expectedDate, date)
+		})
+	}
+}
+
+func TestUpstreamDeps(t *testing.T) {
+	deps := []*debug.Module{
+		{Path: "go.opentelemetry.io/collector/receiver/otlpreceiver", Version: "v0.94.1"},
+		{Path: "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/awsxrayexporter", Version: "v0.94.0"},
+		{Path: "go.opentelemetry.io/collector", Version: "v0.94.1"},
+		{Path: "go.opentelemetry.io/collectorx", Version: "v1.0.0"},
+		{Path: "github.com/aws/aws-sdk-go", Version: "v1.50.0"},
+		{Path: "go.opentelemetry.io/collector/pdata", Version: "v1.1.0", Replace: &debug.Module{Path: "../pdata", Version: ""}},
+		{Path: "go.opentelemetry.io/collector/featuregate", Version: "v1.1.0", Replace: &debug.Module{Path: "go.opentelemetry.io/collector/featuregate", Version: "v1.2.0"}},
+	}
+	assert.Equal(t, []Module{
+		{Path: "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/awsxrayexporter", Version: "v0.94.0"},
+		{Path: "go.opentelemetry.io/collector", Version: "v0.94.1"},
+		{Path: "go.opentelemetry.io/collector/featuregate", Version: "v1.2.0"},
+		{Path: "go.opentelemetry.io/collector/pdata", Version: "v1.1.0"},
+		{Path: "go.opentelemetry.io/collector/receiver/otlpreceiver", Version: "v0.94.1"},
+	}, upstreamDeps(deps))
+}
+
+func TestBuildInfoInfo(t *testing.T) {
+	info := BuildInfo{
+		Version:      "v0.37.0",
+		GitHash:      "abc123",
+		Date:         "2024-02-01",
+		GoVersion:    "go1.21.6",
+		OS:           "linux",
+		Arch:         "amd64",
+		FeatureGates: []string{"a.gate", "b.gate"},
+		Modules:      []Module{{Path: "go.opentelemetry.io/collector", Version: "v0.94.1"}},
+	}
+	assert.Contains(t, info.Info(), [2]string{"FeatureGates", "a.gate,b.gate"})
+	assert.Equal(t, [2]string{"Version", "v0.37.0"}, info.Info()[0])
+	assert.Equal(t, Info{{"go.opentelemetry.io/collector", "v0.94.1"}}, info.ModulesInfo())
+}